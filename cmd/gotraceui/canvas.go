@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"maps"
 	"math"
 	rtrace "runtime/trace"
+	"slices"
 	"sort"
 	"time"
 
@@ -140,8 +142,14 @@ type Canvas struct {
 	scrollbar      widget.Scrollbar
 	axis           Axis
 
-	memoryGraph    Plot
-	goroutineGraph Plot
+	memoryGraph     Plot
+	goroutineGraph  Plot
+	allocationGraph Plot
+	// Plots the user created from runtime metrics, displayed below the built-in plots.
+	customPlots []Plot
+	// Scratch space used for laying out plots
+	scratchPlots      []*Plot
+	scratchPlotFlexes []layout.FlexChild
 
 	// State for dragging the canvas
 	drag struct {
@@ -175,6 +183,10 @@ type Canvas struct {
 		showTooltips showTooltips
 		// Should GC overlays be shown?
 		showGCOverlays showGCOverlays
+		// The critical path whose spans should be highlighted, if any.
+		criticalPath *ptrace.CriticalPath
+		// The search matches that should be highlighted, if any.
+		searchMatches *SearchMatches
 
 		hoveredTimeline *Timeline
 		hover           gesture.Hover
@@ -195,6 +207,8 @@ type Canvas struct {
 		hoveredTimeline    *Timeline
 		width              int
 		filter             Filter
+		criticalPath       *ptrace.CriticalPath
+		searchMatches      *SearchMatches
 	}
 
 	cachedCanvasHeight struct {
@@ -224,7 +238,7 @@ func NewCanvasInto(cv *Canvas, dwin *DebugWindow, t *Trace) {
 		trace:          t,
 		debugWindow:    dwin,
 		itemToTimeline: make(map[any]*Timeline),
-		timelines:      make([]*Timeline, 0, len(t.Goroutines)+len(t.Processors)+len(t.Machines)+2),
+		timelines:      make([]*Timeline, 0, len(t.Goroutines)+len(t.Processors)+len(t.Machines)+len(t.Ranges)+2),
 		textures: TextureManager{
 			rgbas:         mysync.NewMutex(&rbtree.Tree[comparableTimeDuration, *texture]{AllowDuplicates: true}),
 			realizedRGBAs: mysync.NewMutex(container.Set[*texture]{}),
@@ -234,13 +248,20 @@ func NewCanvasInto(cv *Canvas, dwin *DebugWindow, t *Trace) {
 		},
 	}
 	cv.timeline.displayAllLabels = true
+	cv.addGlobalTimelines(t)
+}
 
+// addGlobalTimelines adds the timelines that aren't tied to a goroutine, processor, machine or task.
+func (cv *Canvas) addGlobalTimelines(t *Trace) {
 	if len(t.GC) != 0 {
 		cv.timelines = append(cv.timelines, NewGCTimeline(cv, t, t.GC))
 	}
 	if len(t.STW) != 0 {
 		cv.timelines = append(cv.timelines, NewSTWTimeline(cv, t, t.STW))
 	}
+	for _, name := range slices.Sorted(maps.Keys(t.Ranges)) {
+		cv.timelines = append(cv.timelines, NewRangeTimeline(cv, t, name, t.Ranges[name]))
+	}
 }
 
 func (cv *Canvas) End() exptrace.Time {
@@ -372,6 +393,8 @@ func (cv *Canvas) unchanged(gtx layout.Context) bool {
 		cv.prevFrame.compact == cv.timeline.compact &&
 		cv.prevFrame.displayStackTracks == cv.timeline.displayStackTracks &&
 		cv.prevFrame.filter == cv.timeline.filter &&
+		cv.prevFrame.criticalPath == cv.timeline.criticalPath &&
+		cv.prevFrame.searchMatches == cv.timeline.searchMatches &&
 		cv.prevFrame.metric == gtx.Metric
 }
 
@@ -922,24 +945,28 @@ func (cv *Canvas) Layout(win *theme.Window, gtx layout.Context) layout.Dimension
 			func(gtx layout.Context) layout.Dimensions {
 				return theme.Resize(win.Theme, &cv.resizeMemoryTimelines).Layout(win, gtx,
 					func(win *theme.Window, gtx layout.Context) layout.Dimensions {
-						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-							layout.Flexed(0.5, func(gtx layout.Context) layout.Dimensions {
-								// Memory graph
+						graphs := append(cv.scratchPlots[:0], &cv.memoryGraph, &cv.goroutineGraph)
+						if len(cv.trace.Allocations.Events) != 0 {
+							// Only traces recorded with the AllocFree experiment have an allocation graph.
+							graphs = append(graphs, &cv.allocationGraph)
+						}
+						for i := range cv.customPlots {
+							graphs = append(graphs, &cv.customPlots[i])
+						}
+						n := len(graphs)
+						children := cv.scratchPlotFlexes[:0]
+						for _, pl := range graphs {
+							children = append(children, layout.Flexed(1/float32(n), func(gtx layout.Context) layout.Dimensions {
 								defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
 								cv.drag.drag.Add(gtx.Ops)
 
-								dims := cv.memoryGraph.Layout(win, gtx, cv)
+								dims := pl.Layout(win, gtx, cv)
 								return dims
-							}),
-							layout.Flexed(0.5, func(gtx layout.Context) layout.Dimensions {
-								// Goroutine graph
-								defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
-								cv.drag.drag.Add(gtx.Ops)
-
-								dims := cv.goroutineGraph.Layout(win, gtx, cv)
-								return dims
-							}),
-						)
+							}))
+						}
+						cv.scratchPlots = graphs[:0]
+						cv.scratchPlotFlexes = children[:0]
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
 					},
 
 					// Timelines and scrollbar
@@ -1127,6 +1154,8 @@ func (cv *Canvas) Layout(win *theme.Window, gtx layout.Context) layout.Dimension
 	cv.prevFrame.displayStackTracks = cv.timeline.displayStackTracks
 	cv.prevFrame.hoveredTimeline = cv.timeline.hoveredTimeline
 	cv.prevFrame.filter = cv.timeline.filter
+	cv.prevFrame.criticalPath = cv.timeline.criticalPath
+	cv.prevFrame.searchMatches = cv.timeline.searchMatches
 	cv.prevFrame.metric = gtx.Metric
 
 	cv.clickedSpans = cv.clickedSpans[:0]
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// The processes that group the threads of an exported Chrome trace.
+const (
+	chromePidGoroutines = 1 + iota
+	chromePidProcessors
+	chromePidUserRegions
+	chromePidTasks
+	chromePidRuntime
+)
+
+// The threads of chromePidRuntime.
+const (
+	chromeTidGC = 1 + iota
+	chromeTidSTW
+)
+
+// chromeEvent is an event in the Trace Event Format, as understood by Perfetto and chrome://tracing. Timestamps and
+// durations are in microseconds.
+type chromeEvent struct {
+	Name string         `json:"name,omitempty"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int64          `json:"tid"`
+	ID   uint64         `json:"id,omitempty"`
+	BP   string         `json:"bp,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type chromeExporter struct {
+	tr         *ptrace.Trace
+	start, end exptrace.Time
+	w          *bufio.Writer
+	enc        *json.Encoder
+	numEvents  int
+	nextFlowID uint64
+}
+
+// ExportChromeTrace writes the trace's goroutines, processors, user regions, tasks, GC and STW phases, and metrics
+// in the Trace Event Format, for viewing in Perfetto or chrome://tracing. Goroutine creation and unblocking are
+// exported as flow events. Only spans that overlap the time window between start and end are exported, truncated to
+// the window. If end is zero, the window extends to the end of the trace.
+func ExportChromeTrace(w io.Writer, tr *ptrace.Trace, start, end exptrace.Time) error {
+	if end == 0 {
+		end = tr.End()
+	}
+	bw := bufio.NewWriter(w)
+	ex := &chromeExporter{
+		tr:    tr,
+		start: max(start, tr.Start()),
+		end:   end,
+		w:     bw,
+		enc:   json.NewEncoder(bw),
+	}
+
+	bw.WriteString(`{"displayTimeUnit":"ns","traceEvents":[` + "\n")
+	if err := ex.export(); err != nil {
+		return err
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+func (ex *chromeExporter) emit(ev chromeEvent) error {
+	if ex.numEvents > 0 {
+		ex.w.WriteByte(',')
+	}
+	ex.numEvents++
+	return ex.enc.Encode(ev)
+}
+
+func (ex *chromeExporter) ts(t exptrace.Time) float64 {
+	return float64(t-ex.tr.Start()) / 1e3
+}
+
+// clip returns the part of the span that lies in the exported time window.
+func (ex *chromeExporter) clip(s *ptrace.Span) (start, end exptrace.Time, ok bool) {
+	if s.End < ex.start || s.Start > ex.end {
+		return 0, 0, false
+	}
+	return max(s.Start, ex.start), min(s.End, ex.end), true
+}
+
+func (ex *chromeExporter) span(pid int, tid int64, cat, name string, s *ptrace.Span, args map[string]any) error {
+	start, end, ok := ex.clip(s)
+	if !ok {
+		return nil
+	}
+	return ex.emit(chromeEvent{
+		Name: name,
+		Cat:  cat,
+		Ph:   "X",
+		Ts:   ex.ts(start),
+		Dur:  float64(end-start) / 1e3,
+		Pid:  pid,
+		Tid:  tid,
+		Args: args,
+	})
+}
+
+func (ex *chromeExporter) metadata(name string, pid int, tid int64, value string) error {
+	return ex.emit(chromeEvent{
+		Name: name,
+		Ph:   "M",
+		Pid:  pid,
+		Tid:  tid,
+		Args: map[string]any{"name": value},
+	})
+}
+
+// flow emits a flow event from one goroutine to another at time t.
+func (ex *chromeExporter) flow(name string, from, to exptrace.GoID, t exptrace.Time) error {
+	if t < ex.start || t > ex.end {
+		return nil
+	}
+	ex.nextFlowID++
+	if err := ex.emit(chromeEvent{Name: name, Cat: "flow", Ph: "s", Ts: ex.ts(t), Pid: chromePidGoroutines, Tid: int64(from), ID: ex.nextFlowID}); err != nil {
+		return err
+	}
+	return ex.emit(chromeEvent{Name: name, Cat: "flow", Ph: "f", BP: "e", Ts: ex.ts(t), Pid: chromePidGoroutines, Tid: int64(to), ID: ex.nextFlowID})
+}
+
+func (ex *chromeExporter) export() error {
+	tr := ex.tr
+
+	for pid, name := range map[int]string{
+		chromePidGoroutines:  "Goroutines",
+		chromePidProcessors:  "Processors",
+		chromePidUserRegions: "User regions",
+		chromePidTasks:       "Tasks",
+		chromePidRuntime:     "Runtime",
+	} {
+		if err := ex.metadata("process_name", pid, 0, name); err != nil {
+			return err
+		}
+	}
+	if err := ex.metadata("thread_name", chromePidRuntime, chromeTidGC, "GC"); err != nil {
+		return err
+	}
+	if err := ex.metadata("thread_name", chromePidRuntime, chromeTidSTW, "STW"); err != nil {
+		return err
+	}
+
+	for _, g := range tr.Goroutines {
+		tid := int64(g.ID)
+		label := local.Sprintf("Goroutine %d", g.ID)
+		if g.Function != nil && g.Function.Func != "" {
+			label += ": " + g.Function.Func
+		}
+		if err := ex.metadata("thread_name", chromePidGoroutines, tid, label); err != nil {
+			return err
+		}
+
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			if err := ex.span(chromePidGoroutines, tid, "goroutine", stateNames[s.State], s, nil); err != nil {
+				return err
+			}
+
+			// Link goroutines to the goroutines that created or unblocked them.
+			ev := tr.Event(s.StartEvent)
+			if ev.Kind() != exptrace.EventStateTransition {
+				continue
+			}
+			from, _ := ev.StateTransition().Goroutine()
+			other := ev.Goroutine()
+			if other == exptrace.NoGoroutine || other == g.ID {
+				continue
+			}
+			switch {
+			case s.State == ptrace.StateCreated:
+				if err := ex.flow("create", other, g.ID, s.Start); err != nil {
+					return err
+				}
+			case from == exptrace.GoWaiting:
+				if err := ex.flow("unblock", other, g.ID, s.Start); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(g.UserRegions) != 0 {
+			if err := ex.metadata("thread_name", chromePidUserRegions, tid, label); err != nil {
+				return err
+			}
+			for _, spans := range g.UserRegions {
+				for i := range spans {
+					s := &spans[i]
+					name := tr.Event(s.StartEvent).Region().Type
+					if err := ex.span(chromePidUserRegions, tid, "region", name, s, nil); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	for _, p := range tr.Processors {
+		tid := int64(p.ID)
+		if err := ex.metadata("thread_name", chromePidProcessors, tid, local.Sprintf("Processor %d", p.ID)); err != nil {
+			return err
+		}
+		for i := range p.Spans {
+			s := &p.Spans[i]
+			name := stateNames[s.State]
+			var args map[string]any
+			switch s.State {
+			case ptrace.StateProcRunningG, ptrace.StateProcRunningBlocked:
+				gid := tr.Event(s.StartEvent).StateTransition().Resource.Goroutine()
+				name = local.Sprintf("G%d", gid)
+				if g := tr.G(gid); g != nil && g.Function != nil {
+					args = map[string]any{"function": g.Function.Func}
+				}
+			}
+			if err := ex.span(chromePidProcessors, tid, "processor", name, s, args); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range tr.Tasks {
+		if len(t.Spans) == 0 {
+			continue
+		}
+		start, end, ok := ex.clip(&t.Spans[0])
+		if !ok {
+			continue
+		}
+		name := t.Name
+		if name == "" {
+			name = local.Sprintf("task %d", t.ID)
+		}
+		args := map[string]any{"id": uint64(t.ID)}
+		if t.Parent != exptrace.NoTask {
+			args["parent"] = uint64(t.Parent)
+		}
+		if err := ex.emit(chromeEvent{Name: name, Cat: "task", Ph: "b", Ts: ex.ts(start), Pid: chromePidTasks, ID: uint64(t.ID), Args: args}); err != nil {
+			return err
+		}
+		if err := ex.emit(chromeEvent{Name: name, Cat: "task", Ph: "e", Ts: ex.ts(end), Pid: chromePidTasks, ID: uint64(t.ID)}); err != nil {
+			return err
+		}
+	}
+
+	for i := range tr.GC {
+		if err := ex.span(chromePidRuntime, chromeTidGC, "gc", "GC", &tr.GC[i], nil); err != nil {
+			return err
+		}
+	}
+	for i := range tr.STW {
+		s := &tr.STW[i]
+		if err := ex.span(chromePidRuntime, chromeTidSTW, "stw", tr.Event(s.StartEvent).Range().Name, s, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(tr.Metrics)) {
+		m := tr.Metrics[name]
+		for i, t := range m.Timestamps {
+			if t > ex.end {
+				break
+			}
+			if t < ex.start {
+				// Only export the last value before the window, at the start of the window.
+				if i+1 < len(m.Timestamps) && m.Timestamps[i+1] <= ex.start {
+					continue
+				}
+				t = ex.start
+			}
+			if err := ex.emit(chromeEvent{Name: name, Ph: "C", Ts: ex.ts(t), Pid: chromePidRuntime, Args: map[string]any{"value": m.Values[i]}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func chromeUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "Usage: gotraceui chrome [flags] <trace file>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Chrome converts a trace to the JSON-based Trace Event Format, which can be viewed in Perfetto and chrome://tracing.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		printDefaults(fs)
+	}
+}
+
+// runChrome implements the chrome subcommand.
+func runChrome(args []string) error {
+	fs := flag.NewFlagSet("chrome", flag.ExitOnError)
+	fs.Usage = chromeUsage(fs)
+	output := fs.String("o", "", "Write JSON to this file instead of stdout")
+	start := fs.Duration("start", 0, "Only export the part of the trace starting at this offset from the start of the trace")
+	end := fs.Duration("end", 0, "Only export the part of the trace ending at this offset from the start of the trace")
+	patterns := fs.String("patterns", "", "Load span patterns from this file instead of the user's configuration directory")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := loadUserPatterns(*patterns); err != nil {
+		return fmt.Errorf("couldn't load patterns: %w", err)
+	}
+
+	tr, err := parseTraceFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	windowStart := tr.Start() + exptrace.Time(*start)
+	var windowEnd exptrace.Time
+	if *end != 0 {
+		windowEnd = tr.Start() + exptrace.Time(*end)
+	}
+	if *output == "" {
+		return ExportChromeTrace(os.Stdout, tr, windowStart, windowEnd)
+	}
+	of, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := ExportChromeTrace(of, tr, windowStart, windowEnd); err != nil {
+		of.Close()
+		return err
+	}
+	return of.Close()
+}
+
+// exportChromeTrace lets the user choose a file and exports the part of the current trace between start and end to
+// it.
+func (mwin *MainWindow) exportChromeTrace(start, end exptrace.Time) {
+	if mwin.following != nil {
+		// The trace is exported in the background, but the followed trace gets updated on the UI goroutine.
+		mwin.showNotification("Couldn't export the trace while following it")
+		return
+	}
+	tr := mwin.trace.Trace
+	mwin.showFileSaveDialog("trace.json", func(w io.Writer) error {
+		t := time.Now()
+		if err := ExportChromeTrace(w, tr, start, end); err != nil {
+			return err
+		}
+		mwin.showNotification(fmt.Sprintf("Exported trace in %s", roundDuration(time.Since(t))))
+		return nil
+	})
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	rtrace "runtime/trace"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/color"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+	"gioui.org/x/explorer"
+	exptrace "golang.org/x/exp/trace"
+)
+
+// FunctionComparison compares a function's behavior in two traces.
+type FunctionComparison struct {
+	Function string
+	// The number of CPU samples whose innermost frame is in the function.
+	SamplesA, SamplesB int
+	// The time spent running and blocked by goroutines whose start function is the function.
+	RunningA, RunningB time.Duration
+	BlockedA, BlockedB time.Duration
+}
+
+// Comparison describes the differences between two traces, a baseline A and a trace B that is compared against it.
+// All deltas are computed as B - A.
+type Comparison struct {
+	A, B       *ptrace.Trace
+	Functions  []FunctionComparison
+	GCA, GCB   DurationSummary
+	STWA, STWB DurationSummary
+
+	// A flame graph of B's CPU samples. Frames are colored by how much they grew or shrank relative to the same stack
+	// in A. Stacks that only occur in A are part of the flame graph, with a duration of zero.
+	FlameGraph *widget.FlameGraph
+	// The durations of the flame graph's frames in A.
+	baseline map[*widget.FlamegraphFrame]time.Duration
+}
+
+func NewComparison(a, b *ptrace.Trace) *Comparison {
+	c := &Comparison{
+		A:        a,
+		B:        b,
+		baseline: map[*widget.FlamegraphFrame]time.Duration{},
+	}
+
+	byName := map[string]*FunctionComparison{}
+	get := func(name string) *FunctionComparison {
+		fc, ok := byName[name]
+		if !ok {
+			fc = &FunctionComparison{Function: name}
+			byName[name] = fc
+		}
+		return fc
+	}
+	for i, tr := range [...]*ptrace.Trace{a, b} {
+		for _, ev := range tr.CPUSamples {
+			pcs := tr.Stacks[tr.Event(ev).Stack()]
+			if len(pcs) == 0 {
+				continue
+			}
+			fc := get(tr.PCs[pcs[0]].Func)
+			if i == 0 {
+				fc.SamplesA++
+			} else {
+				fc.SamplesB++
+			}
+		}
+		for _, fn := range tr.Functions {
+			var running, blocked time.Duration
+			for _, g := range fn.Goroutines {
+				stats := ptrace.ComputeStatistics(ptrace.ToSpans(g.Spans))
+				running += stats.Running()
+				blocked += stats.Blocked()
+			}
+			fc := get(fn.Func)
+			if i == 0 {
+				fc.RunningA, fc.BlockedA = running, blocked
+			} else {
+				fc.RunningB, fc.BlockedB = running, blocked
+			}
+		}
+	}
+	c.Functions = make([]FunctionComparison, 0, len(byName))
+	for _, fc := range byName {
+		c.Functions = append(c.Functions, *fc)
+	}
+	slices.SortFunc(c.Functions, func(x, y FunctionComparison) int {
+		return cmp(x.Function, y.Function, false)
+	})
+
+	for _, s := range a.GC {
+		c.GCA.add(s.Duration())
+	}
+	for _, s := range b.GC {
+		c.GCB.add(s.Duration())
+	}
+	for _, s := range a.STW {
+		c.STWA.add(s.Duration())
+	}
+	for _, s := range b.STW {
+		c.STWB.add(s.Duration())
+	}
+
+	var fgA, fgB widget.FlameGraph
+	addCPUSamples(&fgA, a, a.CPUSamples, cpuSampleDuration(a))
+	if d := cpuSampleDuration(b); d != 0 {
+		addCPUSamples(&fgB, b, b.CPUSamples, d)
+		// Add A's stacks without any duration, so that the flame graph also contains the frames that shrank to
+		// nothing. We can only do this if B has samples, as a flame graph can't consist of zero-width frames alone.
+		addCPUSamples(&fgB, a, a.CPUSamples, 0)
+	}
+	fgA.Compute()
+	fgB.Compute()
+	var match func(framesB, framesA []*widget.FlamegraphFrame)
+	match = func(framesB, framesA []*widget.FlamegraphFrame) {
+		for _, fb := range framesB {
+			for _, fa := range framesA {
+				if fa.Name == fb.Name {
+					c.baseline[fb] = fa.Duration
+					match(fb.Children, fa.Children)
+					break
+				}
+			}
+		}
+	}
+	match(fgB.Samples, fgA.Samples)
+	c.FlameGraph = &fgB
+
+	return c
+}
+
+func (c *Comparison) flameGraphColor(level, idx int, f *widget.FlamegraphFrame, hovered bool) color.Oklch {
+	if hovered {
+		return flameGraphColorFn(level, idx, f, hovered)
+	}
+
+	a, b := c.baseline[f], f.Duration
+	// The relative change, between -1 (the frame shrank to nothing) and 1 (the frame is new)
+	rel := float32(b-a) / float32(max(a, b))
+	// Red for growth, blue for shrinkage
+	hue := float32(25)
+	if rel < 0 {
+		hue = 250
+		rel = -rel
+	}
+	return color.Oklch{
+		L: 0.9 - 0.25*rel,
+		C: 0.17 * rel,
+		H: hue,
+		A: 1,
+	}
+}
+
+func (c *Comparison) annotateFlameGraphFrame(f *widget.FlamegraphFrame) string {
+	a, b := c.baseline[f], f.Duration
+	l := fmt.Sprintf("Baseline: %s\nChange: %s", roundDuration(a), formatDurationDelta(b-a))
+	if a != 0 {
+		l += fmt.Sprintf(" (%+.2f%%)", float64(b-a)/float64(a)*100)
+	}
+	return l + "\n"
+}
+
+func formatDurationDelta(d time.Duration) string {
+	if d < 0 {
+		return "-" + roundDuration(-d).String()
+	}
+	return "+" + roundDuration(d).String()
+}
+
+func formatCountDelta(a, b int) string {
+	return local.Sprintf("%d → %d (%+d)", a, b, b-a)
+}
+
+func formatDurationChange(a, b time.Duration) string {
+	return fmt.Sprintf("%s → %s (%s)", roundDuration(a), roundDuration(b), formatDurationDelta(b-a))
+}
+
+// ComparisonComponent displays the differences between the current trace and another trace.
+type ComparisonComponent struct {
+	cmp *Comparison
+	// The name of trace B
+	name string
+
+	tabbedState     theme.TabbedState
+	functions       SortedIndices[FunctionComparison, []FunctionComparison]
+	table           *theme.Table
+	scrollState     theme.YScrollableListState
+	cellFormatter   CellFormatter
+	descriptionText Text
+	fgState         theme.FlameGraphState
+}
+
+func NewComparisonComponent(c *Comparison, name string) *ComparisonComponent {
+	return &ComparisonComponent{
+		cmp:       c,
+		name:      name,
+		functions: NewSortedIndices(c.Functions),
+	}
+}
+
+func (cc *ComparisonComponent) Title() string {
+	return "Comparison with " + cc.name
+}
+
+func (cc *ComparisonComponent) Transition(theme.ComponentState) {
+}
+
+func (cc *ComparisonComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (cc *ComparisonComponent) buildDescription(win *theme.Window) Description {
+	tb := TextBuilder{Window: win}
+	c := cc.cmp
+	attrs := []DescriptionAttribute{
+		{Key: "Compared trace", Value: *tb.Span(cc.name)},
+		{Key: "Duration", Value: *tb.Span(formatDurationChange(c.A.Duration(), c.B.Duration()))},
+		{Key: "Goroutines", Value: *tb.Span(formatCountDelta(len(c.A.Goroutines), len(c.B.Goroutines)))},
+		{Key: "CPU samples", Value: *tb.Span(formatCountDelta(len(c.A.CPUSamples), len(c.B.CPUSamples)))},
+		{Key: "GC cycles", Value: *tb.Span(formatCountDelta(c.GCA.Count, c.GCB.Count))},
+		{Key: "Time in GC", Value: *tb.Span(formatDurationChange(c.GCA.Total, c.GCB.Total))},
+		{Key: "STW pauses", Value: *tb.Span(formatCountDelta(c.STWA.Count, c.STWB.Count))},
+		{Key: "Time in STW", Value: *tb.Span(formatDurationChange(c.STWA.Total, c.STWB.Total))},
+		{Key: "Longest STW", Value: *tb.Span(formatDurationChange(c.STWA.Max, c.STWB.Max))},
+	}
+	return Description{Attributes: attrs}
+}
+
+func (cc *ComparisonComponent) sortFunctions() {
+	desc := cc.table.SortOrder == theme.SortDescending
+	switch cc.table.Columns[cc.table.SortedBy].Name {
+	case "Function":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.Function, b.Function, desc) })
+	case "Samples A":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.SamplesA, b.SamplesA, desc) })
+	case "Samples B":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.SamplesB, b.SamplesB, desc) })
+	case "Δ Samples":
+		cc.functions.Sort(func(a, b FunctionComparison) int {
+			return cmp(a.SamplesB-a.SamplesA, b.SamplesB-b.SamplesA, desc)
+		})
+	case "Running A":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.RunningA, b.RunningA, desc) })
+	case "Running B":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.RunningB, b.RunningB, desc) })
+	case "Δ Running":
+		cc.functions.Sort(func(a, b FunctionComparison) int {
+			return cmp(a.RunningB-a.RunningA, b.RunningB-b.RunningA, desc)
+		})
+	case "Blocked A":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.BlockedA, b.BlockedA, desc) })
+	case "Blocked B":
+		cc.functions.Sort(func(a, b FunctionComparison) int { return cmp(a.BlockedB, b.BlockedB, desc) })
+	case "Δ Blocked":
+		cc.functions.Sort(func(a, b FunctionComparison) int {
+			return cmp(a.BlockedB-a.BlockedA, b.BlockedB-b.BlockedA, desc)
+		})
+	default:
+		panic("unreachable")
+	}
+}
+
+func (cc *ComparisonComponent) initTable(win *theme.Window, gtx layout.Context) {
+	if cc.table != nil {
+		return
+	}
+	cc.table = &theme.Table{}
+	cols := []theme.Column{{Name: "Function", Alignment: text.Start, Clickable: true}}
+	for _, name := range [...]string{
+		"Samples A", "Samples B", "Δ Samples",
+		"Running A", "Running B", "Δ Running",
+		"Blocked A", "Blocked B", "Δ Blocked",
+	} {
+		cols = append(cols, theme.Column{Name: name, Alignment: text.End, Clickable: true})
+	}
+	cc.table.SetColumns(win, gtx, cols)
+	// Show the functions whose CPU usage grew the most first.
+	cc.table.SortedBy = 3
+	cc.table.SortOrder = theme.SortDescending
+	cc.sortFunctions()
+}
+
+func (cc *ComparisonComponent) layoutFunctions(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	cc.initTable(win, gtx)
+	cc.table.Update(gtx)
+	if _, ok := cc.table.SortByClickedColumn(); ok {
+		cc.sortFunctions()
+	}
+	cc.cellFormatter.Update(win, gtx)
+
+	delta := func(win *theme.Window, gtx layout.Context, l string) layout.Dimensions {
+		return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{
+				MaxLines:  1,
+				Alignment: text.Start,
+			}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, l, win.ColorMaterial(gtx, win.Theme.Palette.Foreground))
+		})
+	}
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+
+		fc := cc.functions.Ptr(row)
+		switch colName := cc.table.Columns[col].Name; colName {
+		case "Function":
+			return cc.cellFormatter.Text(win, gtx, fc.Function)
+		case "Samples A":
+			return cc.cellFormatter.Number(win, gtx, fc.SamplesA)
+		case "Samples B":
+			return cc.cellFormatter.Number(win, gtx, fc.SamplesB)
+		case "Δ Samples":
+			return delta(win, gtx, local.Sprintf("%+d", fc.SamplesB-fc.SamplesA))
+		case "Running A":
+			return cc.cellFormatter.Duration(win, gtx, fc.RunningA, false)
+		case "Running B":
+			return cc.cellFormatter.Duration(win, gtx, fc.RunningB, false)
+		case "Δ Running":
+			return delta(win, gtx, formatDurationDelta(fc.RunningB-fc.RunningA))
+		case "Blocked A":
+			return cc.cellFormatter.Duration(win, gtx, fc.BlockedA, false)
+		case "Blocked B":
+			return cc.cellFormatter.Duration(win, gtx, fc.BlockedB, false)
+		case "Δ Blocked":
+			return delta(win, gtx, formatDurationDelta(fc.BlockedB-fc.BlockedA))
+		default:
+			panic(colName)
+		}
+	}
+
+	return theme.SimpleTable(win, gtx, cc.table, &cc.scrollState, cc.functions.Len(), cellFn)
+}
+
+func (cc *ComparisonComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.ComparisonComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		return AnalysisView{
+			Description:     cc.buildDescription(win),
+			DescriptionText: &cc.descriptionText,
+			Tabs:            []string{"Functions", "Differential flame graph"},
+			TabbedState:     &cc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "Functions":
+					return cc.layoutFunctions(win, gtx)
+				case "Differential flame graph":
+					fgs := theme.FlameGraph(cc.cmp.FlameGraph, &cc.fgState)
+					fgs.Color = cc.cmp.flameGraphColor
+					fgs.Annotate = cc.cmp.annotateFlameGraphFrame
+					return fgs.Layout(win, gtx)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// showComparisonFileOpenDialog lets the user choose a trace to compare the current trace with.
+func (mwin *MainWindow) showComparisonFileOpenDialog() {
+	if mwin.showingExplorer.CompareAndSwap(false, true) {
+		tr := mwin.trace.Trace
+		go func() {
+			rc, err := mwin.explorer.ChooseFile()
+			mwin.showingExplorer.Store(false)
+			if err != nil {
+				switch err {
+				case explorer.ErrUserDecline:
+					return
+				case explorer.ErrNotAvailable:
+					err = errors.New("opening file system dialogs isn't supported on this system")
+				}
+				mwin.showNotification(fmt.Sprintf("Couldn't open trace: %s", err))
+				return
+			}
+			defer rc.Close()
+
+			name := "other trace"
+			if f, ok := rc.(interface{ Name() string }); ok {
+				name = filepath.Base(f.Name())
+			}
+			mwin.OpenComparison(tr, rc, name)
+		}()
+	}
+}
+
+// OpenComparison loads a trace and opens a tab comparing it with tr, which should be the currently displayed trace.
+// OpenComparison should be called from a different goroutine than the render loop.
+func (mwin *MainWindow) OpenComparison(tr *ptrace.Trace, r io.Reader, name string) {
+	mwin.showNotification(fmt.Sprintf("Loading %s for comparison…", name))
+	exr, err := exptrace.NewReader(bufio.NewReader(r))
+	if err != nil {
+		mwin.showNotification(fmt.Sprintf("Couldn't read %s: %s", name, err))
+		return
+	}
+	other, err := ptrace.Parse(exr, func(float64) {})
+	if err != nil {
+		mwin.showNotification(fmt.Sprintf("Couldn't parse %s: %s", name, err))
+		return
+	}
+	c := NewComparison(tr, other)
+	mwin.twin.EmitAction(theme.ExecuteAction(func(gtx layout.Context) {
+		if mwin.trace == nil || mwin.trace.Trace != tr {
+			// A different trace has been opened in the meantime.
+			return
+		}
+		mwin.openTab(Tab{Component: NewComparisonComponent(c, name)})
+	}))
+}
+
+// showNotification shows a notification in the main window. It can be called from any goroutine.
+func (mwin *MainWindow) showNotification(msg string) {
+	mwin.twin.EmitAction(theme.ExecuteAction(func(gtx layout.Context) {
+		mwin.twin.ShowNotification(gtx, msg)
+	}))
+}
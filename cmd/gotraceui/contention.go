@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	rtrace "runtime/trace"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+)
+
+// ContentionSite is a place in user code that blocked on sync primitives, together with the goroutines that released
+// it.
+type ContentionSite struct {
+	*ptrace.ContentionSite
+	// The function containing the site, or nil if the trace didn't record stacks.
+	Function *ptrace.Function
+	Location string
+	Releases []*ContentionRelease
+	// The number of distinct goroutines that released goroutines waiting at the site.
+	Releasers int
+}
+
+// ContentionRelease aggregates the waits of a site that were ended by goroutines running the same function.
+type ContentionRelease struct {
+	Site *ContentionSite
+	// The function of the releasing goroutines, or nil if it isn't known.
+	By    *ptrace.Function
+	Total time.Duration
+	Waits []ptrace.ContentionWait
+}
+
+// ContentionReport groups the times goroutines blocked on sync primitives by where they blocked.
+type ContentionReport struct {
+	Sites    []*ContentionSite
+	Releases []*ContentionRelease
+	Waits    int
+	Total    time.Duration
+}
+
+func NewContentionReport(tr *ptrace.Trace) *ContentionReport {
+	var r ContentionReport
+	for _, cs := range ptrace.ComputeContention(tr) {
+		site := &ContentionSite{ContentionSite: cs, Location: "<unknown>"}
+		if cs.PC != 0 {
+			frame := tr.PCs[cs.PC]
+			site.Function = tr.Functions[frame.Func]
+			site.Location = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+
+		byFn := map[*ptrace.Function]*ContentionRelease{}
+		releasers := map[*ptrace.Goroutine]struct{}{}
+		for _, w := range cs.Waits {
+			var fn *ptrace.Function
+			if w.ReleasedBy != nil {
+				fn = w.ReleasedBy.Function
+				releasers[w.ReleasedBy] = struct{}{}
+			}
+			rel, ok := byFn[fn]
+			if !ok {
+				rel = &ContentionRelease{Site: site, By: fn}
+				byFn[fn] = rel
+				site.Releases = append(site.Releases, rel)
+			}
+			rel.Waits = append(rel.Waits, w)
+			rel.Total += w.Goroutine.Spans[w.Span].Duration()
+		}
+		site.Releasers = len(releasers)
+		slices.SortFunc(site.Releases, func(a, b *ContentionRelease) int {
+			return cmp(a.Total, b.Total, true)
+		})
+
+		r.Sites = append(r.Sites, site)
+		r.Releases = append(r.Releases, site.Releases...)
+		r.Waits += len(cs.Waits)
+		r.Total += cs.Total
+	}
+	slices.SortStableFunc(r.Releases, func(a, b *ContentionRelease) int {
+		return cmp(a.Total, b.Total, true)
+	})
+	return &r
+}
+
+// ContentionComponent displays where goroutines blocked on sync primitives, how long for, and who released them, like
+// pprof's mutex profile.
+type ContentionComponent struct {
+	trace  *Trace
+	report *theme.Future[*ContentionReport]
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	sites          []*ContentionSite
+	sitesTable     *theme.Table
+	sitesScroll    theme.YScrollableListState
+	releasesTable  *theme.Table
+	releasesScroll theme.YScrollableListState
+	cellFormatter  CellFormatter
+}
+
+func NewContentionComponent(win *theme.Window, tr *Trace) *ContentionComponent {
+	return &ContentionComponent{
+		trace: tr,
+		report: theme.NewFuture(win, func(cancelled <-chan struct{}) *ContentionReport {
+			return NewContentionReport(tr.Trace)
+		}),
+	}
+}
+
+func (cc *ContentionComponent) Title() string {
+	return "Sync contention"
+}
+
+func (cc *ContentionComponent) Transition(theme.ComponentState) {
+}
+
+func (cc *ContentionComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (cc *ContentionComponent) buildDescription(win *theme.Window, r *ContentionReport) Description {
+	tb := TextBuilder{Window: win}
+	attrs := []DescriptionAttribute{
+		{Key: "Waits", Value: *tb.Span(local.Sprintf("%d", r.Waits))},
+		{Key: "Time spent waiting", Value: *tb.Span(roundDuration(r.Total).String())},
+		{Key: "Sites", Value: *tb.Span(local.Sprintf("%d", len(r.Sites)))},
+	}
+	return Description{Attributes: attrs}
+}
+
+// waitsLink lays out a link that opens the spans of a list of waits.
+func (cc *ContentionComponent) waitsLink(win *theme.Window, gtx layout.Context, waits []ptrace.ContentionWait, title string) layout.Dimensions {
+	return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+		link := cc.cellFormatter.Clicks.Grow()
+		link.Link = &ContentionWaitsObjectLink{Waits: waits, Title: title}
+		return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{
+				MaxLines:  1,
+				Alignment: text.Start,
+			}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, local.Sprintf("%d", len(waits)), win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+		})
+	})
+}
+
+func (cc *ContentionComponent) sortSites() {
+	desc := cc.sitesTable.SortOrder == theme.SortDescending
+	switch cc.sitesTable.Columns[cc.sitesTable.SortedBy].Name {
+	case "Function":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(unblockFunctionName(a.Function), unblockFunctionName(b.Function), desc)
+		})
+	case "Location":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Location, b.Location, desc)
+		})
+	case "Waits":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(len(a.Waits), len(b.Waits), desc)
+		})
+	case "Goroutines":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Goroutines, b.Goroutines, desc)
+		})
+	case "Releasers":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Releasers, b.Releasers, desc)
+		})
+	case "Total":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Total, b.Total, desc)
+		})
+	case "P50":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Percentile(0.5), b.Percentile(0.5), desc)
+		})
+	case "P90":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Percentile(0.9), b.Percentile(0.9), desc)
+		})
+	case "P99":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Percentile(0.99), b.Percentile(0.99), desc)
+		})
+	case "Max":
+		slices.SortStableFunc(cc.sites, func(a, b *ContentionSite) int {
+			return cmp(a.Percentile(1), b.Percentile(1), desc)
+		})
+	}
+}
+
+func (cc *ContentionComponent) layoutSites(win *theme.Window, gtx layout.Context, r *ContentionReport) layout.Dimensions {
+	if cc.sitesTable == nil {
+		cc.sitesTable = &theme.Table{}
+		cc.sitesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Function", Alignment: text.Start, Clickable: true},
+			{Name: "Location", Alignment: text.Start, Clickable: true},
+			{Name: "Waits", Alignment: text.End, Clickable: true},
+			{Name: "Goroutines", Alignment: text.End, Clickable: true},
+			{Name: "Releasers", Alignment: text.End, Clickable: true},
+			{Name: "Total", Alignment: text.End, Clickable: true},
+			{Name: "P50", Alignment: text.End, Clickable: true},
+			{Name: "P90", Alignment: text.End, Clickable: true},
+			{Name: "P99", Alignment: text.End, Clickable: true},
+			{Name: "Max", Alignment: text.End, Clickable: true},
+		})
+		cc.sitesTable.SortedBy = 5
+		cc.sitesTable.SortOrder = theme.SortDescending
+		cc.sites = slices.Clone(r.Sites)
+	}
+	cc.sitesTable.Update(gtx)
+	if _, ok := cc.sitesTable.SortByClickedColumn(); ok {
+		cc.sortSites()
+	}
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		site := cc.sites[row]
+		switch colName := cc.sitesTable.Columns[col].Name; colName {
+		case "Function":
+			return cc.cellFormatter.Function(win, gtx, site.Function)
+		case "Location":
+			return cc.cellFormatter.Text(win, gtx, site.Location)
+		case "Waits":
+			return cc.waitsLink(win, gtx, site.Waits, local.Sprintf("Waits in %s", site.Location))
+		case "Goroutines":
+			return cc.cellFormatter.Number(win, gtx, site.Goroutines)
+		case "Releasers":
+			return cc.cellFormatter.Number(win, gtx, site.Releasers)
+		case "Total":
+			return cc.cellFormatter.Duration(win, gtx, site.Total, false)
+		case "P50":
+			return cc.cellFormatter.Duration(win, gtx, site.Percentile(0.5), false)
+		case "P90":
+			return cc.cellFormatter.Duration(win, gtx, site.Percentile(0.9), false)
+		case "P99":
+			return cc.cellFormatter.Duration(win, gtx, site.Percentile(0.99), false)
+		case "Max":
+			return cc.cellFormatter.Duration(win, gtx, site.Percentile(1), false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, cc.sitesTable, &cc.sitesScroll, len(cc.sites), cellFn)
+}
+
+func (cc *ContentionComponent) layoutReleases(win *theme.Window, gtx layout.Context, r *ContentionReport) layout.Dimensions {
+	if cc.releasesTable == nil {
+		cc.releasesTable = &theme.Table{}
+		cc.releasesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Function", Alignment: text.Start},
+			{Name: "Location", Alignment: text.Start},
+			{Name: "Released by", Alignment: text.Start},
+			{Name: "Waits", Alignment: text.End},
+			{Name: "Total", Alignment: text.End},
+		})
+	}
+	cc.releasesTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		rel := r.Releases[row]
+		switch colName := cc.releasesTable.Columns[col].Name; colName {
+		case "Function":
+			return cc.cellFormatter.Function(win, gtx, rel.Site.Function)
+		case "Location":
+			return cc.cellFormatter.Text(win, gtx, rel.Site.Location)
+		case "Released by":
+			if rel.By == nil {
+				return cc.cellFormatter.Text(win, gtx, "<unknown>")
+			}
+			return cc.cellFormatter.Function(win, gtx, rel.By)
+		case "Waits":
+			title := local.Sprintf("Waits in %s released by %s", rel.Site.Location, unblockFunctionName(rel.By))
+			return cc.waitsLink(win, gtx, rel.Waits, title)
+		case "Total":
+			return cc.cellFormatter.Duration(win, gtx, rel.Total, false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, cc.releasesTable, &cc.releasesScroll, len(r.Releases), cellFn)
+}
+
+func (cc *ContentionComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.ContentionComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		r, ok := cc.report.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		cc.cellFormatter.Update(win, gtx)
+		for _, ev := range cc.descriptionText.Update(gtx, cc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     cc.buildDescription(win, r),
+			DescriptionText: &cc.descriptionText,
+			PrevSpans:       &cc.prevSpans,
+			Tabs:            []string{"Sites", "Releasers"},
+			TabbedState:     &cc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "Sites":
+					return cc.layoutSites(win, gtx, r)
+				case "Releasers":
+					return cc.layoutReleases(win, gtx, r)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// contentionSpans returns the spans of a list of waits.
+func contentionSpans(waits []ptrace.ContentionWait, timelines map[any]*Timeline) Items[ptrace.Span] {
+	var bases []Items[ptrace.Span]
+	byGoroutine := map[*ptrace.Goroutine][]int{}
+	var gs []*ptrace.Goroutine
+	for _, w := range waits {
+		if _, ok := byGoroutine[w.Goroutine]; !ok {
+			gs = append(gs, w.Goroutine)
+		}
+		byGoroutine[w.Goroutine] = append(byGoroutine[w.Goroutine], w.Span)
+	}
+	for _, g := range gs {
+		tl := timelines[g]
+		if tl == nil {
+			continue
+		}
+		bases = append(bases, ItemsSubset[ptrace.Span]{
+			Base: SimpleItems[ptrace.Span, any]{
+				items: g.Spans,
+				container: ItemContainer{
+					Timeline: tl,
+					Track:    tl.tracks[0],
+				},
+				contiguous: true,
+				subslice:   true,
+			},
+			Subset: byGoroutine[g],
+		})
+	}
+	return MergeItems(bases, func(a, b *ptrace.Span) bool {
+		return a.Start < b.Start
+	})
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+	exptrace "golang.org/x/exp/trace"
+)
+
+// CriticalPathAnalysis breaks down a critical path by the states and goroutines it passes through.
+type CriticalPathAnalysis struct {
+	Path    *ptrace.CriticalPath
+	Covered time.Duration
+
+	ByState     []criticalPathState
+	ByGoroutine []criticalPathGoroutine
+}
+
+type criticalPathState struct {
+	State    ptrace.SchedulingState
+	Duration time.Duration
+}
+
+type criticalPathGoroutine struct {
+	Goroutine *ptrace.Goroutine
+	// How many segments of the path are in this goroutine.
+	Segments int
+	Duration time.Duration
+}
+
+func NewCriticalPathAnalysis(tr *ptrace.Trace, g *ptrace.Goroutine, start, end exptrace.Time) *CriticalPathAnalysis {
+	a := &CriticalPathAnalysis{
+		Path: ptrace.ComputeCriticalPath(tr, g, start, end),
+	}
+
+	var byState [ptrace.StateLast]time.Duration
+	byGoroutine := map[*ptrace.Goroutine]*criticalPathGoroutine{}
+	for i := range a.Path.Segments {
+		seg := &a.Path.Segments[i]
+		d := seg.Duration()
+		a.Covered += d
+		byState[seg.Goroutine.Spans[seg.Span].State] += d
+		cg, ok := byGoroutine[seg.Goroutine]
+		if !ok {
+			cg = &criticalPathGoroutine{Goroutine: seg.Goroutine}
+			byGoroutine[seg.Goroutine] = cg
+		}
+		cg.Segments++
+		cg.Duration += d
+	}
+
+	for state, d := range byState {
+		if d != 0 {
+			a.ByState = append(a.ByState, criticalPathState{ptrace.SchedulingState(state), d})
+		}
+	}
+	slices.SortFunc(a.ByState, func(a, b criticalPathState) int {
+		return cmp(a.Duration, b.Duration, true)
+	})
+	for _, cg := range byGoroutine {
+		a.ByGoroutine = append(a.ByGoroutine, *cg)
+	}
+	slices.SortFunc(a.ByGoroutine, func(a, b criticalPathGoroutine) int {
+		if c := cmp(a.Duration, b.Duration, true); c != 0 {
+			return c
+		}
+		return cmp(a.Goroutine.ID, b.Goroutine.ID, false)
+	})
+	return a
+}
+
+// CriticalPathComponent displays the critical path that led a goroutine to a point in time, and highlights it in the
+// timelines.
+type CriticalPathComponent struct {
+	trace     *Trace
+	label     string
+	timelines map[any]*Timeline
+	analysis  *theme.Future[*CriticalPathAnalysis]
+
+	// Whether we've highlighted the path after computing it.
+	highlighted bool
+	buttons     struct {
+		highlight widget.PrimaryClickable
+		clear     widget.PrimaryClickable
+	}
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	statesTable      *theme.Table
+	statesScroll     theme.YScrollableListState
+	goroutinesTable  *theme.Table
+	goroutinesScroll theme.YScrollableListState
+	segmentsTable    *theme.Table
+	segmentsScroll   theme.YScrollableListState
+	cellFormatter    CellFormatter
+}
+
+// NewCriticalPathComponent returns a component for the critical path that led goroutine g to reach end, starting at
+// start. label describes what the path leads to, such as a task.
+func NewCriticalPathComponent(win *theme.Window, tr *Trace, timelines map[any]*Timeline, g *ptrace.Goroutine, start, end exptrace.Time, label string) *CriticalPathComponent {
+	return &CriticalPathComponent{
+		trace:     tr,
+		label:     label,
+		timelines: timelines,
+		analysis: theme.NewFuture(win, func(cancelled <-chan struct{}) *CriticalPathAnalysis {
+			return NewCriticalPathAnalysis(tr.Trace, g, start, end)
+		}),
+	}
+}
+
+// newCriticalPathMenuItem returns a menu item that opens the critical path that led goroutine g to reach end.
+func newCriticalPathMenuItem(g *ptrace.Goroutine, start, end exptrace.Time, label string) *theme.MenuItem {
+	return &theme.MenuItem{
+		Label: PlainLabel("Show critical path"),
+		Action: func() theme.Action {
+			return &OpenCriticalPathAction{
+				Goroutine: g,
+				Start:     start,
+				End:       end,
+				Label:     label,
+			}
+		},
+	}
+}
+
+// criticalPathForTask returns the action for opening the critical path of a task, which leads to the goroutine that
+// ended the task, or the goroutine that started it if the task didn't end.
+func criticalPathForTask(tr *Trace, t *ptrace.Task) (*OpenCriticalPathAction, bool) {
+	gid := exptrace.NoGoroutine
+	if t.EndEvent != 0 && t.EndEvent != ptrace.NoEvent {
+		gid = tr.Event(t.EndEvent).Goroutine()
+	}
+	if gid == exptrace.NoGoroutine {
+		gid = goroutineIDForTask(t, tr)
+	}
+	if gid == exptrace.NoGoroutine {
+		return nil, false
+	}
+	label := local.Sprintf("task %d", t.ID)
+	if t.Name != "" {
+		label = local.Sprintf("task %d: %s", t.ID, t.Name)
+	}
+	return &OpenCriticalPathAction{
+		Goroutine: tr.G(gid),
+		Start:     t.EffectiveStart(),
+		End:       t.EffectiveEnd(),
+		Label:     label,
+	}, true
+}
+
+func (cc *CriticalPathComponent) Title() string {
+	return "Critical path of " + cc.label
+}
+
+func (cc *CriticalPathComponent) Transition(theme.ComponentState) {
+}
+
+func (cc *CriticalPathComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (cc *CriticalPathComponent) buildDescription(win *theme.Window, a *CriticalPathAnalysis) Description {
+	tb := TextBuilder{Window: win}
+	cp := a.Path
+	d := time.Duration(cp.End - cp.Start)
+	var coveredPct float64
+	if d != 0 {
+		coveredPct = float64(a.Covered) / float64(d) * 100
+	}
+	attrs := []DescriptionAttribute{
+		{Key: "Of", Value: *tb.Span(cc.label)},
+		{Key: "Start", Value: *tb.DefaultLink(formatTimestamp(nil, cc.trace.AdjustedTime(cp.Start)), "Start of critical path", cp.Start)},
+		{Key: "End", Value: *tb.DefaultLink(formatTimestamp(nil, cc.trace.AdjustedTime(cp.End)), "End of critical path", cp.End)},
+		{Key: "Duration", Value: *tb.Span(roundDuration(d).String())},
+		{Key: "Explained", Value: *tb.Span(local.Sprintf("%s (%.2f%%)", roundDuration(a.Covered), coveredPct))},
+		{Key: "Goroutines", Value: *tb.Span(local.Sprintf("%d", cp.Goroutines()))},
+		{Key: "Segments", Value: *tb.Span(local.Sprintf("%d", len(cp.Segments)))},
+	}
+	return Description{Attributes: attrs}
+}
+
+// segmentSpans returns the span that contains a segment of the critical path, for linking to it.
+func (cc *CriticalPathComponent) segmentSpans(seg *ptrace.CriticalPathSegment) (Items[ptrace.Span], bool) {
+	tl := cc.timelines[seg.Goroutine]
+	if tl == nil {
+		return nil, false
+	}
+	return SimpleItems[ptrace.Span, any]{
+		items: seg.Goroutine.Spans[seg.Span : seg.Span+1],
+		container: ItemContainer{
+			Timeline: tl,
+			Track:    tl.tracks[0],
+		},
+		subslice: true,
+	}, true
+}
+
+func (cc *CriticalPathComponent) layoutStates(win *theme.Window, gtx layout.Context, a *CriticalPathAnalysis) layout.Dimensions {
+	if cc.statesTable == nil {
+		cc.statesTable = &theme.Table{}
+		cc.statesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "State", Alignment: text.Start},
+			{Name: "Time", Alignment: text.End},
+			{Name: "Share", Alignment: text.End},
+		})
+	}
+	cc.statesTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		s := &a.ByState[row]
+		switch colName := cc.statesTable.Columns[col].Name; colName {
+		case "State":
+			return cc.cellFormatter.Text(win, gtx, stateNamesCapitalized[s.State])
+		case "Time":
+			return cc.cellFormatter.Duration(win, gtx, s.Duration, false)
+		case "Share":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cc.cellFormatter.Text(win, gtx, cc.sharePct(a, s.Duration))
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, cc.statesTable, &cc.statesScroll, len(a.ByState), cellFn)
+}
+
+func (cc *CriticalPathComponent) layoutGoroutines(win *theme.Window, gtx layout.Context, a *CriticalPathAnalysis) layout.Dimensions {
+	if cc.goroutinesTable == nil {
+		cc.goroutinesTable = &theme.Table{}
+		cc.goroutinesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Goroutine", Alignment: text.End},
+			{Name: "Function", Alignment: text.Start},
+			{Name: "Segments", Alignment: text.End},
+			{Name: "Time", Alignment: text.End},
+			{Name: "Share", Alignment: text.End},
+		})
+	}
+	cc.goroutinesTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		cg := &a.ByGoroutine[row]
+		switch colName := cc.goroutinesTable.Columns[col].Name; colName {
+		case "Goroutine":
+			return cc.cellFormatter.Goroutine(win, gtx, cg.Goroutine, "")
+		case "Function":
+			return cc.cellFormatter.Function(win, gtx, cg.Goroutine.Function)
+		case "Segments":
+			return cc.cellFormatter.Number(win, gtx, cg.Segments)
+		case "Time":
+			return cc.cellFormatter.Duration(win, gtx, cg.Duration, false)
+		case "Share":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cc.cellFormatter.Text(win, gtx, cc.sharePct(a, cg.Duration))
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, cc.goroutinesTable, &cc.goroutinesScroll, len(a.ByGoroutine), cellFn)
+}
+
+func (cc *CriticalPathComponent) layoutSegments(win *theme.Window, gtx layout.Context, a *CriticalPathAnalysis) layout.Dimensions {
+	if cc.segmentsTable == nil {
+		cc.segmentsTable = &theme.Table{}
+		cc.segmentsTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Start", Alignment: text.End},
+			{Name: "Goroutine", Alignment: text.End},
+			{Name: "Function", Alignment: text.Start},
+			{Name: "State", Alignment: text.Start},
+			{Name: "Duration", Alignment: text.End},
+		})
+	}
+	cc.segmentsTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		seg := &a.Path.Segments[row]
+		switch colName := cc.segmentsTable.Columns[col].Name; colName {
+		case "Start":
+			return cc.cellFormatter.Timestamp(win, gtx, cc.trace, seg.Start, "")
+		case "Goroutine":
+			return cc.cellFormatter.Goroutine(win, gtx, seg.Goroutine, "")
+		case "Function":
+			return cc.cellFormatter.Function(win, gtx, seg.Goroutine.Function)
+		case "State":
+			return cc.cellFormatter.Text(win, gtx, stateNamesCapitalized[seg.Goroutine.Spans[seg.Span].State])
+		case "Duration":
+			spans, ok := cc.segmentSpans(seg)
+			if !ok {
+				return cc.cellFormatter.Duration(win, gtx, seg.Duration(), false)
+			}
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				link := cc.cellFormatter.Clicks.Grow()
+				link.Link = &SpansObjectLink{Spans: spans}
+				return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					value, unit := durationNumberFormatSITable.format(seg.Duration())
+					return widget.Label{
+						MaxLines:  1,
+						Alignment: text.Start,
+					}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, value+" "+unit, win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+				})
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, cc.segmentsTable, &cc.segmentsScroll, len(a.Path.Segments), cellFn)
+}
+
+func (cc *CriticalPathComponent) sharePct(a *CriticalPathAnalysis, d time.Duration) string {
+	var share float64
+	if a.Covered != 0 {
+		share = float64(d) / float64(a.Covered) * 100
+	}
+	return local.Sprintf("%.2f%%", share)
+}
+
+func (cc *CriticalPathComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.CriticalPathComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		a, ok := cc.analysis.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		if !cc.highlighted {
+			cc.highlighted = true
+			win.EmitAction(&HighlightCriticalPathAction{Path: a.Path})
+		}
+		for cc.buttons.highlight.Clicked(gtx) {
+			win.EmitAction(&HighlightCriticalPathAction{Path: a.Path})
+		}
+		for cc.buttons.clear.Clicked(gtx) {
+			win.EmitAction(&HighlightCriticalPathAction{})
+		}
+		cc.cellFormatter.Update(win, gtx)
+		for _, ev := range cc.descriptionText.Update(gtx, cc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Header: func(gtx layout.Context) layout.Dimensions {
+				return layout.Rigids(gtx, layout.Horizontal,
+					theme.Dumb(win, theme.Button(win.Theme, &cc.buttons.highlight.Clickable, "Highlight in timelines").Layout),
+					layout.Spacer{Width: 5}.Layout,
+					theme.Dumb(win, theme.Button(win.Theme, &cc.buttons.clear.Clickable, "Clear highlighting").Layout),
+				)
+			},
+			Description:     cc.buildDescription(win, a),
+			DescriptionText: &cc.descriptionText,
+			PrevSpans:       &cc.prevSpans,
+			Tabs:            []string{"By state", "By goroutine", "Segments"},
+			TabbedState:     &cc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "By state":
+					return cc.layoutStates(win, gtx, a)
+				case "By goroutine":
+					return cc.layoutGoroutines(win, gtx, a)
+				case "Segments":
+					return cc.layoutSegments(win, gtx, a)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
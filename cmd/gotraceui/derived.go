@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// A MetricSource computes the points of a plot series, either by looking up a metric recorded in the trace, or by
+// deriving new points from other sources or from the trace itself.
+type MetricSource interface {
+	Compute(tr *ptrace.Trace) ptrace.Metric
+	String() string
+}
+
+// NamedMetric is a metric recorded in the trace, such as "/gc/heap/goal:bytes".
+type NamedMetric string
+
+// MetricRate is the per-second rate of change of a cumulative counter. Decreasing values, such as from counters
+// getting reset, result in a rate of zero.
+type MetricRate struct {
+	Source MetricSource
+}
+
+// MetricDifference is A - B, clamped to zero.
+type MetricDifference struct {
+	A, B MetricSource
+}
+
+// MetricRatio is A / B, multiplied by Scale. Because plots only display integers, Scale should usually be 100, to
+// turn the ratio into a percentage.
+type MetricRatio struct {
+	A, B  MetricSource
+	Scale uint64
+}
+
+// MovingAverage is the average of the points of Source that lie within Window of each point. A non-positive Window
+// averages each point only with itself.
+type MovingAverage struct {
+	Source MetricSource
+	Window time.Duration
+}
+
+// ProcessorUtilization is the average utilization of all processors, in percent, as computed by
+// ptrace.ComputeProcessorBusy. If BucketSize is zero, the trace is split into 1000 buckets.
+type ProcessorUtilization struct {
+	BucketSize time.Duration
+}
+
+func (m NamedMetric) Compute(tr *ptrace.Trace) ptrace.Metric {
+	return tr.Metrics[string(m)]
+}
+
+func (m MetricRate) Compute(tr *ptrace.Trace) ptrace.Metric {
+	src := m.Source.Compute(tr)
+	var out ptrace.Metric
+	for i := 1; i < len(src.Values); i++ {
+		dt := src.Timestamps[i] - src.Timestamps[i-1]
+		if dt == 0 {
+			continue
+		}
+		var v uint64
+		if src.Values[i] > src.Values[i-1] {
+			v = uint64(math.Round(float64(src.Values[i]-src.Values[i-1]) / time.Duration(dt).Seconds()))
+		}
+		out.Timestamps = append(out.Timestamps, src.Timestamps[i])
+		out.Values = append(out.Values, v)
+	}
+	return out
+}
+
+func (m MetricDifference) Compute(tr *ptrace.Trace) ptrace.Metric {
+	return combineMetrics(m.A.Compute(tr), m.B.Compute(tr), func(a, b uint64) uint64 {
+		if b > a {
+			return 0
+		}
+		return a - b
+	})
+}
+
+func (m MetricRatio) Compute(tr *ptrace.Trace) ptrace.Metric {
+	return combineMetrics(m.A.Compute(tr), m.B.Compute(tr), func(a, b uint64) uint64 {
+		if b == 0 {
+			return 0
+		}
+		return uint64(math.Round(float64(a) / float64(b) * float64(m.Scale)))
+	})
+}
+
+func (m MovingAverage) Compute(tr *ptrace.Trace) ptrace.Metric {
+	src := m.Source.Compute(tr)
+	out := ptrace.Metric{
+		Timestamps: src.Timestamps,
+		Values:     make([]uint64, len(src.Values)),
+	}
+	var sum float64
+	start := 0
+	for i, v := range src.Values {
+		sum += float64(v)
+		for start < i && src.Timestamps[i]-src.Timestamps[start] >= exptrace.Time(m.Window) {
+			sum -= float64(src.Values[start])
+			start++
+		}
+		out.Values[i] = uint64(math.Round(sum / float64(i-start+1)))
+	}
+	return out
+}
+
+func (m ProcessorUtilization) Compute(tr *ptrace.Trace) ptrace.Metric {
+	if len(tr.Processors) == 0 {
+		return ptrace.Metric{}
+	}
+	bucketSize := m.BucketSize
+	if bucketSize == 0 {
+		bucketSize = max(tr.Duration()/1000, 1)
+	}
+	var sums []int
+	for _, p := range tr.Processors {
+		busy := ptrace.ComputeProcessorBusy(tr, p, bucketSize)
+		if sums == nil {
+			sums = make([]int, len(busy))
+		}
+		for i, v := range busy {
+			sums[i] += v
+		}
+	}
+	out := ptrace.Metric{
+		Timestamps: make([]exptrace.Time, len(sums)),
+		Values:     make([]uint64, len(sums)),
+	}
+	for i, sum := range sums {
+		out.Timestamps[i] = tr.Start() + exptrace.Time(i)*exptrace.Time(bucketSize)
+		out.Values[i] = uint64(math.Round(float64(sum) / float64(len(tr.Processors))))
+	}
+	return out
+}
+
+func (m NamedMetric) String() string {
+	name, _ := splitMetricName(string(m))
+	return name
+}
+
+func (m MetricRate) String() string       { return fmt.Sprintf("rate(%s)", m.Source) }
+func (m MetricDifference) String() string { return fmt.Sprintf("%s - %s", m.A, m.B) }
+func (m MetricRatio) String() string      { return fmt.Sprintf("%s / %s", m.A, m.B) }
+func (m MovingAverage) String() string {
+	return fmt.Sprintf("avg(%s, %s)", m.Source, m.Window)
+}
+func (m ProcessorUtilization) String() string { return "processor utilization" }
+
+// combineMetrics combines two metrics point by point. Metrics change in steps, which is why the value of a metric
+// between two of its points is that of the earlier point. The result has a point for each point of either metric,
+// starting at the first time both metrics have a value.
+func combineMetrics(a, b ptrace.Metric, fn func(a, b uint64) uint64) ptrace.Metric {
+	var out ptrace.Metric
+	i, j := 0, 0
+	for i < len(a.Timestamps) || j < len(b.Timestamps) {
+		var ts exptrace.Time
+		switch {
+		case j == len(b.Timestamps) || (i < len(a.Timestamps) && a.Timestamps[i] < b.Timestamps[j]):
+			ts = a.Timestamps[i]
+			i++
+		case i == len(a.Timestamps) || b.Timestamps[j] < a.Timestamps[i]:
+			ts = b.Timestamps[j]
+			j++
+		default:
+			ts = a.Timestamps[i]
+			i++
+			j++
+		}
+		if i == 0 || j == 0 {
+			continue
+		}
+		out.Timestamps = append(out.Timestamps, ts)
+		out.Values = append(out.Values, fn(a.Values[i-1], b.Values[j-1]))
+	}
+	return out
+}
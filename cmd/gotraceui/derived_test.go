@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+func metric(points ...uint64) ptrace.Metric {
+	var m ptrace.Metric
+	for i := 0; i < len(points); i += 2 {
+		m.Timestamps = append(m.Timestamps, exptrace.Time(points[i]))
+		m.Values = append(m.Values, points[i+1])
+	}
+	return m
+}
+
+func sameMetric(a, b ptrace.Metric) bool {
+	return len(a.Timestamps) == len(b.Timestamps) &&
+		(len(a.Timestamps) == 0 || reflect.DeepEqual(a, b))
+}
+
+func TestCombineMetrics(t *testing.T) {
+	// Encode both operands in the result so that we can tell which points were combined.
+	fn := func(a, b uint64) uint64 { return a*1000 + b }
+
+	tests := []struct {
+		name string
+		a, b ptrace.Metric
+		want ptrace.Metric
+	}{
+		{"both empty", metric(), metric(), metric()},
+		{"a empty", metric(), metric(1, 1, 2, 2), metric()},
+		{"b empty", metric(1, 1, 2, 2), metric(), metric()},
+		{"same timestamps", metric(1, 10, 2, 20), metric(1, 1, 2, 2), metric(1, 10001, 2, 20002)},
+		{"interleaved", metric(1, 10, 3, 30), metric(2, 5, 4, 7), metric(2, 10005, 3, 30005, 4, 30007)},
+		{"b starts first", metric(3, 30), metric(1, 1, 2, 2, 5, 5), metric(3, 30002, 5, 30005)},
+		{"a ends first", metric(1, 10), metric(1, 1, 2, 2), metric(1, 10001, 2, 10002)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := combineMetrics(tt.a, tt.b, fn); !sameMetric(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerivedMetrics(t *testing.T) {
+	const s = uint64(time.Second)
+	tr := &ptrace.Trace{
+		Metrics: map[string]ptrace.Metric{
+			"counter": metric(0, 0, 1*s, 100, 2*s, 300, 2*s, 300, 3*s, 200, 3*s+s/2, 250),
+			"single":  metric(1*s, 100),
+			"steps":   metric(0, 10, 10, 20, 20, 30, 30, 40),
+			"a":       metric(1, 50, 2, 10),
+			"b":       metric(1, 20, 2, 40),
+			"zero":    metric(1, 0),
+		},
+	}
+
+	tests := []struct {
+		name string
+		src  MetricSource
+		want ptrace.Metric
+	}{
+		{"rate", MetricRate{NamedMetric("counter")}, metric(1*s, 100, 2*s, 200, 3*s, 0, 3*s+s/2, 100)},
+		{"rate of single point", MetricRate{NamedMetric("single")}, metric()},
+		{"rate of missing metric", MetricRate{NamedMetric("missing")}, metric()},
+		{"rate of rate", MetricRate{MetricRate{NamedMetric("counter")}}, metric(2*s, 100, 3*s, 0, 3*s+s/2, 200)},
+		{"difference", MetricDifference{NamedMetric("a"), NamedMetric("b")}, metric(1, 30, 2, 0)},
+		{"ratio", MetricRatio{NamedMetric("b"), NamedMetric("a"), 100}, metric(1, 40, 2, 400)},
+		{"ratio by zero", MetricRatio{NamedMetric("a"), NamedMetric("zero"), 100}, metric(1, 0, 2, 0)},
+		{"moving average", MovingAverage{NamedMetric("steps"), 15}, metric(0, 10, 10, 15, 20, 25, 30, 35)},
+		{"moving average, large window", MovingAverage{NamedMetric("steps"), time.Hour}, metric(0, 10, 10, 15, 20, 20, 30, 25)},
+		{"moving average, zero window", MovingAverage{NamedMetric("steps"), 0}, metric(0, 10, 10, 20, 20, 30, 30, 40)},
+		{"moving average, negative window", MovingAverage{NamedMetric("steps"), -1}, metric(0, 10, 10, 20, 20, 30, 30, 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.src.Compute(tr); !sameMetric(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"math/bits"
 	rtrace "runtime/trace"
 
 	"honnef.co/go/gotraceui/layout"
@@ -22,12 +23,18 @@ type Filter struct {
 
 	// Bitmap of ptrace.SchedulingState
 	States uint64
+	// Bitmap of ptrace.SpanTags
+	Tags uint32
 }
 
 func (f Filter) HasState(state ptrace.SchedulingState) bool {
 	return f.States&(1<<state) != 0
 }
 
+func (f Filter) HasTags(tags ptrace.SpanTags) bool {
+	return f.Tags&uint32(tags) != 0
+}
+
 func (f Filter) Match(spans ptrace.Spans, container ItemContainer) bool {
 	if !f.couldMatch(spans, container) {
 		return false
@@ -46,6 +53,19 @@ func (f Filter) Match(spans ptrace.Spans, container ItemContainer) bool {
 			}
 			return false, false
 		},
+
+		func() (bool, bool) {
+			if f.Tags == 0 {
+				return false, true
+			}
+
+			for i := range spans.Len() {
+				if f.HasTags(spans.AtPtr(i).Tags) {
+					return true, false
+				}
+			}
+			return false, false
+		},
 	}
 
 	switch f.Mode {
@@ -91,9 +111,19 @@ func (f Filter) couldMatch(spans ptrace.Spans, container ItemContainer) bool {
 
 	b := f.couldMatchState(spans, container)
 	b = b || f.couldMatchProcessor(spans, container)
+	b = b || f.couldMatchTags(spans, container)
 	return b
 }
 
+func (f Filter) couldMatchTags(spans ptrace.Spans, container ItemContainer) bool {
+	if f.Tags == 0 {
+		return false
+	}
+	// Patterns only tag the spans of goroutines.
+	_, ok := container.Timeline.item.(*ptrace.Goroutine)
+	return ok && container.Track.kind == TrackKindUnspecified
+}
+
 func (f Filter) couldMatchProcessor(spans ptrace.Spans, container ItemContainer) bool {
 	switch container.Timeline.item.(type) {
 	case *ptrace.Processor:
@@ -106,6 +136,9 @@ func (f Filter) couldMatchProcessor(spans ptrace.Spans, container ItemContainer)
 func (f Filter) couldMatchState(spans ptrace.Spans, container ItemContainer) bool {
 	switch item := container.Timeline.item.(type) {
 	case *ptrace.Processor:
+		if container.Track.kind == TrackKindRange {
+			return false
+		}
 		return f.HasState(ptrace.StateProcRunningG)
 	case *ptrace.Goroutine:
 		switch container.Track.kind {
@@ -124,6 +157,12 @@ func (f Filter) couldMatchState(spans ptrace.Spans, container ItemContainer) boo
 			return f.HasState(ptrace.StateStack)
 		}
 
+	case *ptrace.Machine:
+		if container.Track.kind == TrackKindRange {
+			return false
+		}
+		return f.HasState(ptrace.StateActive) || f.HasState(ptrace.StateBlockedSyscall)
+
 	case *STW, *GC:
 		return f.HasState(ptrace.StateActive)
 	}
@@ -134,11 +173,15 @@ func (f Filter) couldMatchState(spans ptrace.Spans, container ItemContainer) boo
 type HighlightDialogStyle struct {
 	Filter *Filter
 
-	bits [ptrace.StateLast]widget.BackedBit[uint64]
+	bits    [ptrace.StateLast]widget.BackedBit[uint64]
+	tagBits []widget.BackedBit[uint32]
+	// The labels of the tags in tagBits.
+	tagLabels []string
 
 	list      widget.List
 	foldables struct {
 		states widget.Bool
+		tags   widget.Bool
 	}
 	stateClickables []widget.Clickable
 }
@@ -154,6 +197,17 @@ func HighlightDialog(win *theme.Window, f *Filter) HighlightDialogStyle {
 		hd.bits[i].Bit = i
 	}
 
+	for _, info := range ptrace.SpanTagInfos() {
+		if info.Label == "" {
+			continue
+		}
+		hd.tagBits = append(hd.tagBits, widget.BackedBit[uint32]{
+			Bits: &f.Tags,
+			Bit:  bits.TrailingZeros32(uint32(info.Tag)),
+		})
+		hd.tagLabels = append(hd.tagLabels, info.Label)
+	}
+
 	hd.stateClickables = make([]widget.Clickable, 3)
 
 	return hd
@@ -162,7 +216,19 @@ func HighlightDialog(win *theme.Window, f *Filter) HighlightDialogStyle {
 func (hd *HighlightDialogStyle) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
 	defer rtrace.StartRegion(context.Background(), "main.HighlightDialogStyle.Layout").End()
 
-	return theme.List(win.Theme, &hd.list).Layout(win, gtx, 1, func(gtx layout.Context, index int) layout.Dimensions {
+	return theme.List(win.Theme, &hd.list).Layout(win, gtx, 2, func(gtx layout.Context, index int) layout.Dimensions {
+		if index == 1 {
+			return theme.Foldable(win.Theme, &hd.foldables.tags, "Tags").Layout(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+				children := make([]layout.Widget, len(hd.tagBits))
+				for i := range hd.tagBits {
+					children[i] = func(gtx layout.Context) layout.Dimensions {
+						return theme.CheckBox(win.Theme, &hd.tagBits[i], hd.tagLabels[i]).Layout(win, gtx)
+					}
+				}
+				return layout.Rigids(gtx, layout.Vertical, children...)
+			})
+		}
+
 		return theme.Foldable(win.Theme, &hd.foldables.states, "States").Layout(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
 			return layout.Rigids(gtx, layout.Vertical,
 				func(gtx layout.Context) layout.Dimensions {
@@ -13,16 +13,34 @@ import (
 	"honnef.co/go/gotraceui/theme"
 	"honnef.co/go/gotraceui/trace/ptrace"
 	"honnef.co/go/gotraceui/widget"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+type flameGraphKind uint8
+
+const (
+	// A flame graph of CPU samples, and for goroutine flame graphs, of blocked and ready time.
+	flameGraphCPU flameGraphKind = iota
+	// A flame graph of allocated bytes.
+	flameGraphAllocations
+	// A flame graph of the time goroutines spent blocked.
+	flameGraphOffCPU
 )
 
 type FlameGraphComponent struct {
 	g     *ptrace.Goroutine
 	fg    *theme.Future[*widget.FlameGraph]
+	kind  flameGraphKind
 	state theme.FlameGraphState
 }
 
 func (fc *FlameGraphComponent) Title() string {
-	if fc.g == nil {
+	if fc.kind == flameGraphAllocations {
+		return "Allocation flame graph"
+	} else if fc.kind == flameGraphOffCPU {
+		return "Off-CPU flame graph"
+	} else if fc.g == nil {
 		return "Flame graph"
 	} else {
 		// OPT(dh): avoid the allocation
@@ -37,55 +55,62 @@ func (tlc *FlameGraphComponent) WantsTransition(gtx layout.Context) theme.Compon
 	return theme.ComponentStateNone
 }
 
+// cpuSampleDuration computes the duration represented by a single CPU sample by dividing the active time of all Ps by
+// the total number of samples. This should closely approximate the inverse of the configured sampling rate.
+//
+// For the global flame graph, this is the most obvious choice. For goroutine flame graphs, we could arguably compute
+// per-G averages, so that a goroutine that ran for 1ms won't show a flame graph span that's 10ms long. However, this
+// wouldn't solve other, related problems, such as limiting the global flame graph to a portion of time.
+//
+// In the end, samples happen on Ms, not Gs, and using an average is the simplest approximation that we can explain. It
+// also corresponds to what go tool pprof does, although it doesn't have the trouble of showing graphs for individual
+// goroutines.
+func cpuSampleDuration(tr *ptrace.Trace) time.Duration {
+	if len(tr.CPUSamples) == 0 {
+		return 0
+	}
+	var totalDuration time.Duration
+	for _, p := range tr.Processors {
+		for _, s := range p.Spans {
+			totalDuration += s.Duration()
+		}
+	}
+	return time.Duration(math.Round(float64(totalDuration) / float64(len(tr.CPUSamples))))
+}
+
+// flameGraphSample turns a stack, leaf first, into a flame graph sample, root first.
+func flameGraphSample(tr *ptrace.Trace, pcs []uint64, d time.Duration) widget.FlamegraphSample {
+	frames := make(widget.FlamegraphSample, 0, len(pcs))
+	for i := len(pcs) - 1; i >= 0; i-- {
+		frames = append(frames, widget.FlamegraphFrame{
+			Name:     tr.PCs[pcs[i]].Func,
+			Duration: d,
+		})
+	}
+	return frames
+}
+
+// addCPUSamples adds the stacks of CPU samples to a flame graph, below the "Running" root.
+func addCPUSamples(fg *widget.FlameGraph, tr *ptrace.Trace, samples []ptrace.EventID, sampleDuration time.Duration) {
+	for _, sample := range samples {
+		pcs := tr.Stacks[tr.Event(sample).Stack()]
+		fg.AddSample(flameGraphSample(tr, pcs, sampleDuration), "Running")
+	}
+}
+
 func NewFlameGraphComponent(win *theme.Window, tr *ptrace.Trace, g *ptrace.Goroutine) *FlameGraphComponent {
 	return &FlameGraphComponent{
 		g: g,
 		fg: theme.NewFuture(win, func(cancelled <-chan struct{}) *widget.FlameGraph {
-			// Compute the sample duration by dividing the active time of all Ps by the total number of samples. This should
-			// closely approximate the inverse of the configured sampling rate.
-			//
-			// For the global flame graph, this is the most obvious choice. For goroutine flame graphs, we could arguably
-			// compute per-G averages, so that a goroutine that ran for 1ms won't show a flame graph span that's 10ms long.
-			// However, this wouldn't solve other, related problems, such as limiting the global flame graph to a portion of
-			// time.
-			//
-			// In the end, samples happen on Ms, not Gs, and using an average is the simplest approximation that we can
-			// explain. It also corresponds to what go tool pprof does, although it doesn't have the trouble of showing
-			// graphs for individual goroutines.
-			var (
-				totalDuration  time.Duration
-				sampleDuration time.Duration
-			)
-			for _, p := range tr.Processors {
-				for _, s := range p.Spans {
-					totalDuration += s.Duration()
-				}
-			}
-			totalSamples := len(tr.CPUSamples)
-			sampleDuration = time.Duration(math.Round(float64(totalDuration) / float64(totalSamples)))
+			sampleDuration := cpuSampleDuration(tr)
 
 			var fg widget.FlameGraph
-			do := func(samples []ptrace.EventID) {
-				for _, sample := range samples {
-					pcs := tr.Stacks[tr.Event(sample).Stack()]
-					var frames widget.FlamegraphSample
-					for i := len(pcs) - 1; i >= 0; i-- {
-						fn := tr.PCs[pcs[i]].Func
-						frames = append(frames, widget.FlamegraphFrame{
-							Name:     fn,
-							Duration: sampleDuration,
-						})
-					}
-
-					fg.AddSample(frames, "Running")
-				}
-			}
 			if g == nil {
 				for _, samples := range tr.CPUSamplesByP {
-					do(samples)
+					addCPUSamples(&fg, tr, samples, sampleDuration)
 				}
 			} else {
-				do(tr.CPUSamplesByG[g.ID])
+				addCPUSamples(&fg, tr, tr.CPUSamplesByG[g.ID], sampleDuration)
 
 				for _, span := range g.Spans {
 					var root string
@@ -96,28 +121,10 @@ func NewFlameGraphComponent(win *theme.Window, tr *ptrace.Trace, g *ptrace.Gorou
 					case ptrace.StateGCIdle:
 					case ptrace.StateGCDedicated:
 					case ptrace.StateGCFractional:
-					case ptrace.StateBlocked:
-						root = "blocked"
-					case ptrace.StateBlockedSend:
-						root = "send"
-					case ptrace.StateBlockedRecv:
-						root = "recv"
-					case ptrace.StateBlockedSelect:
-						root = "select"
-					case ptrace.StateBlockedSync:
-						root = "sync"
-					case ptrace.StateBlockedSyncOnce:
-						root = "sync.Once"
-					case ptrace.StateBlockedSyncTriggeringGC:
-						root = "triggering GC"
-					case ptrace.StateBlockedCond:
-						root = "sync.Cond"
-					case ptrace.StateBlockedNet:
-						root = "I/O"
-					case ptrace.StateBlockedGC:
-						root = "GC"
-					case ptrace.StateBlockedSyscall:
-						root = "blocking syscall"
+					case ptrace.StateBlocked, ptrace.StateBlockedSend, ptrace.StateBlockedRecv, ptrace.StateBlockedSelect,
+						ptrace.StateBlockedSync, ptrace.StateBlockedSyncOnce, ptrace.StateBlockedSyncTriggeringGC,
+						ptrace.StateBlockedCond, ptrace.StateBlockedNet, ptrace.StateBlockedGC, ptrace.StateBlockedSyscall:
+						root = flameGraphBlockedRoots[span.State]
 					case ptrace.StateStuck:
 					case ptrace.StateReady, ptrace.StateCreated, ptrace.StateWaitingPreempted:
 						root = "ready"
@@ -131,13 +138,7 @@ func NewFlameGraphComponent(win *theme.Window, tr *ptrace.Trace, g *ptrace.Gorou
 						var frames widget.FlamegraphSample
 						if root != "ready" {
 							pcs := tr.Stacks[tr.Event(span.StartEvent).Stack()]
-							for i := len(pcs) - 1; i >= 0; i-- {
-								fn := tr.PCs[pcs[i]].Func
-								frames = append(frames, widget.FlamegraphFrame{
-									Name:     fn,
-									Duration: span.Duration(),
-								})
-							}
+							frames = flameGraphSample(tr, pcs, span.Duration())
 						}
 						fg.AddSample(frames, root)
 					}
@@ -150,6 +151,64 @@ func NewFlameGraphComponent(win *theme.Window, tr *ptrace.Trace, g *ptrace.Gorou
 	}
 }
 
+// NewAllocationFlameGraphComponent returns a flame graph of the bytes allocated for heap objects. The trace doesn't
+// contain stacks for allocations, so instead of call stacks, the flame graph shows the functions of the allocating
+// goroutines, followed by the types of the allocated objects.
+func NewAllocationFlameGraphComponent(win *theme.Window, tr *ptrace.Trace) *FlameGraphComponent {
+	return &FlameGraphComponent{
+		kind: flameGraphAllocations,
+		fg: theme.NewFuture(win, func(cancelled <-chan struct{}) *widget.FlameGraph {
+			var fg widget.FlameGraph
+			allocs := &tr.Allocations
+			gs := make(map[exptrace.GoID]*ptrace.Goroutine, len(tr.Goroutines))
+			for _, g := range tr.Goroutines {
+				gs[g.ID] = g
+			}
+			for gid, indices := range allocs.ByG {
+				var fn string
+				if g := gs[gid]; g != nil && g.Function != nil {
+					fn = g.Function.Func
+				} else {
+					fn = local.Sprintf("goroutine %d", gid)
+				}
+				for _, idx := range indices {
+					a := &allocs.Events[idx]
+					if a.Kind != ptrace.AllocHeapObject || a.Free {
+						continue
+					}
+					typ := "unknown type"
+					if a.Type != -1 {
+						typ = allocs.Types[a.Type].Name
+					}
+					fg.AddSample(widget.FlamegraphSample{
+						{Name: fn, Duration: time.Duration(a.Size)},
+						{Name: typ, Duration: time.Duration(a.Size)},
+					}, "heap objects")
+				}
+			}
+			fg.Compute()
+			return &fg
+		}),
+	}
+}
+
+// NewOffCPUFlameGraphComponent returns a flame graph of the time goroutines spent blocked, grouped by the kind of
+// blocking and the stacks at which goroutines blocked.
+func NewOffCPUFlameGraphComponent(win *theme.Window, tr *ptrace.Trace) *FlameGraphComponent {
+	return &FlameGraphComponent{
+		kind: flameGraphOffCPU,
+		fg: theme.NewFuture(win, func(cancelled <-chan struct{}) *widget.FlameGraph {
+			var fg widget.FlameGraph
+			for _, sample := range ptrace.ComputeOffCPU(tr, nil, tr.Start(), tr.End()) {
+				pcs := tr.Stacks[sample.Stack]
+				fg.AddSample(flameGraphSample(tr, pcs, sample.Duration), flameGraphBlockedRoots[sample.State])
+			}
+			fg.Compute()
+			return &fg
+		}),
+	}
+}
+
 func (fgc *FlameGraphComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
 	defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
 	theme.Fill(win, gtx.Ops, win.Theme.Palette.Background)
@@ -160,6 +219,15 @@ func (fgc *FlameGraphComponent) Layout(win *theme.Window, gtx layout.Context) la
 	}
 	fgs := theme.FlameGraph(fg, &fgc.state)
 	fgs.Color = flameGraphColorFn
+	switch fgc.kind {
+	case flameGraphAllocations:
+		fgs.Quantity = "Allocated"
+		fgs.FormatValue = func(d time.Duration) string {
+			return local.Sprintf("%d bytes", int64(d))
+		}
+	case flameGraphOffCPU:
+		fgs.Quantity = "Blocked"
+	}
 	return fgs.Layout(win, gtx)
 }
 
@@ -200,7 +268,7 @@ func flameGraphColorFn(level, idx int, f *widget.FlamegraphFrame, hovered bool)
 		switch f.Name {
 		case "Running":
 			return adjustLight(colors[colorStateActive])
-		case "blocked":
+		case "blocked", "stuck":
 			return adjustLight(colors[colorStateBlocked])
 		case "send", "recv", "select", "sync", "sync.Once", "sync.Cond":
 			return adjustLight(colors[colorStateBlockedHappensBefore])
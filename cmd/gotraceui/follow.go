@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+const (
+	// How often to check whether a followed trace has grown.
+	followPollInterval = 250 * time.Millisecond
+	// How often to update the UI with new events.
+	followUpdateInterval = time.Second
+)
+
+// followReader reads from a file that is still being written to. Instead of returning io.EOF, it waits for more data
+// to be written, until stop is closed.
+type followReader struct {
+	r    io.Reader
+	stop <-chan struct{}
+}
+
+func (fr *followReader) Read(b []byte) (int, error) {
+	for {
+		n, err := fr.r.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		select {
+		case <-fr.stop:
+			return 0, io.EOF
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+type followState struct {
+	parser *ptrace.Parser
+	stop   chan struct{}
+	// Whether the trace has been loaded into the UI yet.
+	loaded bool
+	// The versions of goroutines, processors, machines and tasks as of the last update, used to find timelines that
+	// don't need to be recreated.
+	versions map[any]itemVersion
+}
+
+// itemVersion summarizes the parts of a goroutine, processor, machine or task that change as a followed trace grows.
+// Open spans end at the end of the trace, which moves with every update, so live items always change.
+type itemVersion struct {
+	spans   int
+	events  int
+	samples int
+	end     exptrace.Time
+}
+
+func (v *itemVersion) addSpans(spans []ptrace.Span) {
+	v.spans += len(spans)
+	if len(spans) != 0 {
+		v.end = max(v.end, spans[len(spans)-1].End)
+	}
+}
+
+func (v *itemVersion) addRanges(ranges map[string][]ptrace.Span) {
+	for _, spans := range ranges {
+		v.addSpans(spans)
+	}
+}
+
+// unchangedTimelines returns the existing timelines of items that haven't changed since the last call, and records
+// the items' current versions.
+func (fs *followState) unchangedTimelines(tr *Trace, itemToTimeline map[any]*Timeline) map[any]*Timeline {
+	out := make(map[any]*Timeline)
+	check := func(item any, v itemVersion) {
+		if old, ok := fs.versions[item]; ok && old == v {
+			if tl, ok := itemToTimeline[item]; ok {
+				out[item] = tl
+			}
+		}
+		fs.versions[item] = v
+	}
+
+	for _, g := range tr.Goroutines {
+		v := itemVersion{events: len(g.Events), samples: len(tr.CPUSamplesByG[g.ID])}
+		v.addSpans(g.Spans)
+		for _, ug := range g.UserRegions {
+			v.addSpans(ug)
+		}
+		v.addRanges(g.Ranges)
+		check(g, v)
+	}
+	for _, p := range tr.Processors {
+		var v itemVersion
+		v.addSpans(p.Spans)
+		v.addRanges(p.Ranges)
+		check(p, v)
+	}
+	for _, m := range tr.Machines {
+		var v itemVersion
+		v.addSpans(m.Spans)
+		v.addSpans(m.Goroutines)
+		v.addRanges(m.Ranges)
+		check(m, v)
+	}
+	for _, t := range tr.Tasks {
+		v := itemVersion{events: len(t.Events)}
+		v.addSpans(t.Spans)
+		check(t, v)
+	}
+	return out
+}
+
+// FollowTrace is like OpenTrace, but keeps reading events from r as they get written and periodically updates the UI
+// to include them. FollowTrace returns once following has been stopped, either because a different trace has been
+// opened or because of an error.
+//
+// The trace parser only emits a generation's events once it has seen the start of the next generation. The UI thus
+// lags behind the trace by up to one generation, which the runtime starts roughly once per second.
+func (mwin *MainWindow) FollowTrace(r io.Reader) {
+	mwin.SetState("loadingTrace")
+	mwin.SetProgressStages([]string{"Waiting for events"})
+	mwin.SetProgressStage(0)
+
+	fs := &followState{
+		parser:   ptrace.NewParser(),
+		stop:     make(chan struct{}),
+		versions: make(map[any]itemVersion),
+	}
+	mwin.twin.EmitAction(theme.ExecuteAction(func(gtx layout.Context) {
+		mwin.stopFollowing()
+		mwin.following = fs
+	}))
+
+	er, err := exptrace.NewReader(&followReader{r: r, stop: fs.stop})
+	if err != nil {
+		select {
+		case <-fs.stop:
+		default:
+			mwin.SetError(fmt.Errorf("couldn't load trace: %w", err))
+		}
+		return
+	}
+
+	events := make(chan exptrace.Event, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			ev, err := er.ReadEvent()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case events <- ev:
+			case <-fs.stop:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(followUpdateInterval)
+	defer ticker.Stop()
+	var batch []exptrace.Event
+	for {
+		select {
+		case ev := <-events:
+			batch = append(batch, ev)
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			evs := batch
+			batch = nil
+			mwin.twin.EmitAction(theme.ExecuteAction(func(gtx layout.Context) {
+				mwin.updateFollowedTrace(fs, evs)
+			}))
+		case err := <-errs:
+			select {
+			case <-fs.stop:
+				// We stopped following the trace, the error is a result of that.
+			default:
+				mwin.SetError(fmt.Errorf("couldn't load trace: %w", err))
+			}
+			return
+		case <-fs.stop:
+			return
+		}
+	}
+}
+
+// stopFollowing stops following the current trace, if any. It must be called from the UI goroutine.
+func (mwin *MainWindow) stopFollowing() {
+	if mwin.following != nil {
+		close(mwin.following.stop)
+		mwin.following = nil
+	}
+}
+
+// updateFollowedTrace processes new events of a followed trace and updates the UI to reflect them. It must be called
+// from the UI goroutine, as updating the trace modifies data that is used for rendering.
+func (mwin *MainWindow) updateFollowedTrace(fs *followState, events []exptrace.Event) {
+	if mwin.following != fs {
+		// We've stopped following this trace.
+		return
+	}
+	if err := fs.parser.Update(events); err != nil {
+		mwin.stopFollowing()
+		mwin.err = fmt.Errorf("couldn't load trace: %w", err)
+		mwin.setState("error")
+		return
+	}
+
+	if !fs.loaded {
+		fs.loaded = true
+		tr := &Trace{Trace: fs.parser.Trace()}
+		fs.unchangedTimelines(tr, nil)
+		res := processTrace(tr, nopProgresser{}, &mwin.canvas, nil)
+		mwin.loadTraceImpl(res)
+		mwin.computeCustomPlots()
+		mwin.setState("main")
+		return
+	}
+
+	// Reuse the existing *Trace so that panels and tabs that refer to it see the updated labels. Only the timelines of
+	// items that changed get recreated.
+	reuse := fs.unchangedTimelines(mwin.trace, mwin.canvas.itemToTimeline)
+	res := processTrace(mwin.trace, nopProgresser{}, &mwin.canvas, reuse)
+	mwin.refreshTrace(res)
+}
+
+// refreshTrace replaces the canvas's timelines, plots, and the goroutine and task lists after the trace has been
+// updated. Unlike loadCanvas, it keeps the rest of the canvas's state, such as the user's position and display
+// settings.
+func (mwin *MainWindow) refreshTrace(res loadTraceResult) {
+	cv := &mwin.canvas
+	cv.timelines = cv.timelines[:0]
+	cv.addGlobalTimelines(res.trace)
+	cv.timelines = append(cv.timelines, res.timelines...)
+	clear(cv.itemToTimeline)
+	for _, tl := range res.timelines {
+		cv.itemToTimeline[tl.item] = tl
+	}
+	cv.memoryGraph = res.plot
+	cv.goroutineGraph = res.goroutinePlot
+	cv.allocationGraph = res.allocationPlot
+	cv.customPlots = newCustomPlots(mwin.plotConfigs, res.trace.Trace)
+	mwin.computeCustomPlots()
+
+	cv.timeline.hoveredTimeline = nil
+	cv.clickedTimelines = cv.clickedTimelines[:0]
+	cv.rightClickedTimelines = cv.rightClickedTimelines[:0]
+	// Force the timelines' heights and the displayed timelines to be recomputed.
+	cv.timelineEnds = cv.timelineEnds[:0]
+	cv.prevFrame.nsPerPx = 0
+
+	for i := range mwin.tabs {
+		switch mwin.tabs[i].Component.(type) {
+		case *GoroutinesComponent:
+			mwin.tabs[i].Component = NewGoroutinesComponent(mwin.trace.Goroutines, mwin.trace)
+		case *TasksComponent:
+			mwin.tabs[i].Component = NewTasksComponent(mwin.trace.Tasks, mwin.trace)
+		}
+	}
+}
+
+// computeCustomPlots computes the series of the user's plots right away. Normally they get computed in the
+// background, but the followed trace gets updated on the UI goroutine.
+func (mwin *MainWindow) computeCustomPlots() {
+	for i := range mwin.canvas.customPlots {
+		mwin.canvas.customPlots[i].computeSourcesNow(mwin.trace.Trace)
+	}
+}
+
+type nopProgresser struct{}
+
+func (nopProgresser) SetProgressStages(names []string) {}
+func (nopProgresser) SetProgressStage(stage int)       {}
+func (nopProgresser) SetProgress(p float64)            {}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"strings"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+	"honnef.co/go/stuff/container/maybe"
+
+	"gioui.org/font"
+	"gioui.org/text"
+)
+
+// formatHeapSize formats a heap size recorded by a GC cycle, or returns the empty string if the trace didn't record
+// it.
+func formatHeapSize(size maybe.Option[uint64]) string {
+	v, ok := size.Get()
+	if !ok {
+		return ""
+	}
+	return local.Sprintf("%.2f MiB", float64(v)/1024/1024)
+}
+
+// formatGCPauses formats the stop-the-world pauses of a GC cycle, such as "GC sweep termination (15µs)".
+func formatGCPauses(c *ptrace.GCCycle) string {
+	var sb strings.Builder
+	for i, p := range c.Pauses {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(local.Sprintf("%s (%s)", p.Reason, roundDuration(p.Span.Duration())))
+	}
+	return sb.String()
+}
+
+// GCCyclesComponent lists the trace's GC cycles, with their pauses, the work done by mark workers and assists, and
+// how the heap changed.
+type GCCyclesComponent struct {
+	trace  *Trace
+	cycles *theme.Future[[]*ptrace.GCCycle]
+
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	table         *theme.Table
+	scroll        theme.YScrollableListState
+	cellFormatter CellFormatter
+}
+
+func NewGCCyclesComponent(win *theme.Window, tr *Trace) *GCCyclesComponent {
+	return &GCCyclesComponent{
+		trace: tr,
+		cycles: theme.NewFuture(win, func(cancelled <-chan struct{}) []*ptrace.GCCycle {
+			return ptrace.ComputeGCCycles(tr.Trace)
+		}),
+	}
+}
+
+func (gc *GCCyclesComponent) Title() string {
+	return "GC cycles"
+}
+
+func (gc *GCCyclesComponent) Transition(theme.ComponentState) {
+}
+
+func (gc *GCCyclesComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (gc *GCCyclesComponent) buildDescription(win *theme.Window, cycles []*ptrace.GCCycle) Description {
+	tb := TextBuilder{Window: win}
+	var total, stw, assist, workers time.Duration
+	for _, c := range cycles {
+		total += time.Duration(c.End - c.Start)
+		stw += c.STW()
+		assist += c.MarkAssist
+		workers += c.Dedicated + c.Fractional + c.Idle
+	}
+	attrs := []DescriptionAttribute{
+		{Key: "GC cycles", Value: *tb.Span(local.Sprintf("%d", len(cycles)))},
+		{Key: "Time in GC", Value: *tb.Span(roundDuration(total).String())},
+		{Key: "Time in STW", Value: *tb.Span(roundDuration(stw).String())},
+		{Key: "Mark assist time", Value: *tb.Span(roundDuration(assist).String())},
+		{Key: "Mark worker time", Value: *tb.Span(roundDuration(workers).String())},
+		{Key: "Note", Value: *tb.Span("Mark assist and worker times are summed across goroutines and processors and can exceed the duration of a cycle.")},
+	}
+	return Description{Attributes: attrs}
+}
+
+func (gc *GCCyclesComponent) layoutCycles(win *theme.Window, gtx layout.Context, cycles []*ptrace.GCCycle) layout.Dimensions {
+	if gc.table == nil {
+		gc.table = &theme.Table{}
+		gc.table.SetColumns(win, gtx, []theme.Column{
+			{Name: "Cycle", Alignment: text.End},
+			{Name: "Start", Alignment: text.End},
+			{Name: "Duration", Alignment: text.End},
+			{Name: "STW", Alignment: text.End},
+			{Name: "Pauses", Alignment: text.Start},
+			{Name: "Mark assist", Alignment: text.End},
+			{Name: "Dedicated", Alignment: text.End},
+			{Name: "Fractional", Alignment: text.End},
+			{Name: "Idle", Alignment: text.End},
+			{Name: "Heap at start", Alignment: text.End},
+			{Name: "Heap at end", Alignment: text.End},
+			{Name: "Heap goal", Alignment: text.End},
+		})
+	}
+	gc.table.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		c := cycles[row]
+		rightAligned := func(s string) layout.Dimensions {
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return gc.cellFormatter.Text(win, gtx, s)
+			})
+		}
+		switch colName := gc.table.Columns[col].Name; colName {
+		case "Cycle":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				link := gc.cellFormatter.Clicks.Grow()
+				link.Link = &GCCycleObjectLink{Cycle: c}
+				return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return widget.Label{
+						MaxLines:  1,
+						Alignment: text.Start,
+					}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, local.Sprintf("%d", row+1), win.ColorMaterial(gtx, win.Theme.Palette.NavigationLink))
+				})
+			})
+		case "Start":
+			return gc.cellFormatter.Timestamp(win, gtx, gc.trace, c.Start, "")
+		case "Duration":
+			return gc.cellFormatter.Duration(win, gtx, time.Duration(c.End-c.Start), false)
+		case "STW":
+			return gc.cellFormatter.Duration(win, gtx, c.STW(), false)
+		case "Pauses":
+			return gc.cellFormatter.Text(win, gtx, formatGCPauses(c))
+		case "Mark assist":
+			return gc.cellFormatter.Duration(win, gtx, c.MarkAssist, false)
+		case "Dedicated":
+			return gc.cellFormatter.Duration(win, gtx, c.Dedicated, false)
+		case "Fractional":
+			return gc.cellFormatter.Duration(win, gtx, c.Fractional, false)
+		case "Idle":
+			return gc.cellFormatter.Duration(win, gtx, c.Idle, false)
+		case "Heap at start":
+			return rightAligned(formatHeapSize(c.HeapStart))
+		case "Heap at end":
+			return rightAligned(formatHeapSize(c.HeapEnd))
+		case "Heap goal":
+			return rightAligned(formatHeapSize(c.HeapGoal))
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, gc.table, &gc.scroll, len(cycles), cellFn)
+}
+
+func (gc *GCCyclesComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.GCCyclesComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		cycles, ok := gc.cycles.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		gc.cellFormatter.Update(win, gtx)
+		for _, ev := range gc.descriptionText.Update(gtx, gc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     gc.buildDescription(win, cycles),
+			DescriptionText: &gc.descriptionText,
+			PrevSpans:       &gc.prevSpans,
+			Content: func(win *theme.Window, gtx layout.Context, _ string) layout.Dimensions {
+				return gc.layoutCycles(win, gtx, cycles)
+			},
+		}.Layout(win, gtx)
+	})
+}
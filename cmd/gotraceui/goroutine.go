@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"iter"
 	"math"
 	rtrace "runtime/trace"
 	"strings"
@@ -49,6 +50,13 @@ func goroutineTrack0SpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*the
 	}
 
 	if spans.Len() == 1 {
+		if c, ok := spans.Container(); ok {
+			g := c.Timeline.item.(*ptrace.Goroutine)
+			s := spans.AtPtr(0)
+			label := local.Sprintf("%s span of goroutine %d", stateNames[s.State], g.ID)
+			items = append(items, newCriticalPathMenuItem(g, s.Start, s.End, label))
+		}
+
 		switch spans.AtPtr(0).State {
 		case ptrace.StateActive, ptrace.StateGCIdle, ptrace.StateGCDedicated, ptrace.StateGCFractional, ptrace.StateGCMarkAssist, ptrace.StateGCSweep:
 			// These are the states that are actually on-CPU
@@ -64,12 +72,12 @@ func goroutineTrack0SpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*the
 
 		case ptrace.StateBlocked, ptrace.StateBlockedSend, ptrace.StateBlockedRecv, ptrace.StateBlockedSelect, ptrace.StateBlockedSync,
 			ptrace.StateBlockedSyncOnce, ptrace.StateBlockedSyncTriggeringGC, ptrace.StateBlockedCond, ptrace.StateBlockedNet, ptrace.StateBlockedGC:
-			gid, ok := unblockedByGoroutine(cv.trace, spans.AtPtr(0))
+			gid, ok := ptrace.UnblockedBy(cv.trace.Trace, spans.AtPtr(0))
 			if ok {
 				items = append(items, &theme.MenuItem{
 					Label: PlainLabel(local.Sprintf("Scroll to unblocking goroutine %d", gid)),
 					Action: func() theme.Action {
-						gid, _ := unblockedByGoroutine(cv.trace, spans.AtPtr(0))
+						gid, _ := ptrace.UnblockedBy(cv.trace.Trace, spans.AtPtr(0))
 						return &ScrollToObjectAction{
 							Object: cv.trace.G(gid),
 						}
@@ -82,6 +90,24 @@ func goroutineTrack0SpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*the
 	return items
 }
 
+func userRegionSpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		newZoomMenuItem(cv, spans),
+		newOpenSpansMenuItem(spans),
+	}
+
+	if spans.Len() == 1 {
+		if c, ok := spans.Container(); ok {
+			g := c.Timeline.item.(*ptrace.Goroutine)
+			s := spans.AtPtr(0)
+			label := local.Sprintf("user region %q of goroutine %d", cv.trace.Event(s.StartEvent).Region().Type, g.ID)
+			items = append(items, newCriticalPathMenuItem(g, s.Start, s.End, label))
+		}
+	}
+
+	return items
+}
+
 func userRegionSpanLabel(spans Items[ptrace.Span], tr *Trace, out []string) []string {
 	if spans.Len() != 1 {
 		return out
@@ -190,6 +216,7 @@ func NewGoroutineTimeline(tr *Trace, cv *Canvas, g *ptrace.Goroutine) *Timeline
 		})
 		track.spanLabel = userRegionSpanLabel
 		track.spanTooltip = userRegionSpanTooltip
+		track.spanContextMenu = userRegionSpanContextMenu
 		track.spanColor = singleSpanColor(colorStateUserRegion)
 		tl.tracks = append(tl.tracks, track)
 	}
@@ -277,23 +304,6 @@ func (tt GoroutineTooltip) Layout(win *theme.Window, gtx layout.Context) layout.
 	return theme.Tooltip(win.Theme, l).Layout(win, gtx)
 }
 
-func unblockedByGoroutine(tr *Trace, s *ptrace.Span) (exptrace.GoID, bool) {
-	switch s.State {
-	case ptrace.StateBlocked, ptrace.StateBlockedSend, ptrace.StateBlockedRecv, ptrace.StateBlockedSelect, ptrace.StateBlockedSync,
-		ptrace.StateBlockedSyncOnce, ptrace.StateBlockedSyncTriggeringGC, ptrace.StateBlockedCond, ptrace.StateBlockedNet, ptrace.StateBlockedGC:
-		if s.EndEvent == ptrace.NoEvent {
-			return 0, false
-		}
-		endEv := tr.Event(s.EndEvent)
-		if endEv.Kind() == exptrace.EventStateTransition {
-			if gid := endEv.Goroutine(); gid != exptrace.NoGoroutine {
-				return gid, true
-			}
-		}
-	}
-	return 0, false
-}
-
 func goroutineSpanTooltip(win *theme.Window, gtx layout.Context, tr *Trace, spans Items[ptrace.Span]) layout.Dimensions {
 	var label string
 	if debug {
@@ -329,7 +339,7 @@ func goroutineSpanTooltip(win *theme.Window, gtx layout.Context, tr *Trace, span
 			}
 		}
 
-		if g, ok := unblockedByGoroutine(tr, s); ok {
+		if g, ok := ptrace.UnblockedBy(tr.Trace, s); ok {
 			label += local.Sprintf("\nUnblocked by goroutine %d (%s)", g, tr.G(g).Function)
 		}
 	} else {
@@ -823,3 +833,39 @@ func GoroutineLabel(g *ptrace.Goroutine) string {
 		return local.Sprintf("%d", g.ID)
 	}
 }
+
+// goroutineSpans returns a subset of the spans of goroutines, given as pairs of goroutines and indices into their
+// spans, merged in the order of their start times. The indices of each goroutine have to be in ascending order.
+// Goroutines without timelines are skipped.
+func goroutineSpans(refs iter.Seq2[*ptrace.Goroutine, int], timelines map[any]*Timeline) Items[ptrace.Span] {
+	var bases []Items[ptrace.Span]
+	byGoroutine := map[*ptrace.Goroutine][]int{}
+	var gs []*ptrace.Goroutine
+	for g, span := range refs {
+		if _, ok := byGoroutine[g]; !ok {
+			gs = append(gs, g)
+		}
+		byGoroutine[g] = append(byGoroutine[g], span)
+	}
+	for _, g := range gs {
+		tl := timelines[g]
+		if tl == nil {
+			continue
+		}
+		bases = append(bases, ItemsSubset[ptrace.Span]{
+			Base: SimpleItems[ptrace.Span, any]{
+				items: g.Spans,
+				container: ItemContainer{
+					Timeline: tl,
+					Track:    tl.tracks[0],
+				},
+				contiguous: true,
+				subslice:   true,
+			},
+			Subset: byGoroutine[g],
+		})
+	}
+	return MergeItems(bases, func(a, b *ptrace.Span) bool {
+		return a.Start < b.Start
+	})
+}
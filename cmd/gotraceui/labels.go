@@ -128,3 +128,35 @@ var stateNamesCapitalized = [ptrace.StateLast]string{
 	ptrace.StateProcRunningG:            "Proc running",
 	ptrace.StateProcRunningBlocked:      "Proc waiting for goroutine to unblock",
 }
+
+// Mapping from blocking states to the roots of flame graphs
+var flameGraphBlockedRoots = [ptrace.StateLast]string{
+	ptrace.StateBlocked:                 "blocked",
+	ptrace.StateBlockedSend:             "send",
+	ptrace.StateBlockedRecv:             "recv",
+	ptrace.StateBlockedSelect:           "select",
+	ptrace.StateBlockedSync:             "sync",
+	ptrace.StateBlockedSyncOnce:         "sync.Once",
+	ptrace.StateBlockedSyncTriggeringGC: "triggering GC",
+	ptrace.StateBlockedCond:             "sync.Cond",
+	ptrace.StateBlockedNet:              "I/O",
+	ptrace.StateBlockedGC:               "GC",
+	ptrace.StateBlockedSyscall:          "blocking syscall",
+	ptrace.StateStuck:                   "stuck",
+}
+
+// Mapping from blocking states to the sample types of off-CPU profiles
+var offCPUSampleTypes = [ptrace.StateLast]string{
+	ptrace.StateBlocked:                 "blocked",
+	ptrace.StateBlockedSend:             "send",
+	ptrace.StateBlockedRecv:             "recv",
+	ptrace.StateBlockedSelect:           "select",
+	ptrace.StateBlockedSync:             "sync",
+	ptrace.StateBlockedSyncOnce:         "sync_once",
+	ptrace.StateBlockedSyncTriggeringGC: "triggering_gc",
+	ptrace.StateBlockedCond:             "sync_cond",
+	ptrace.StateBlockedNet:              "io",
+	ptrace.StateBlockedGC:               "gc",
+	ptrace.StateBlockedSyscall:          "syscall",
+	ptrace.StateStuck:                   "stuck",
+}
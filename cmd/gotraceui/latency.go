@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+	exptrace "golang.org/x/exp/trace"
+)
+
+// SchedulingLatencyAnalysis summarizes how long goroutines had to wait to run after becoming runnable.
+type SchedulingLatencyAnalysis struct {
+	// All latencies, sorted in descending order.
+	Latencies []ptrace.SchedulingLatency
+	Total     DurationSummary
+	ByReason  [ptrace.WakeupLast]DurationSummary
+
+	Median, P90, P99 time.Duration
+}
+
+func NewSchedulingLatencyAnalysis(tr *ptrace.Trace, gs []*ptrace.Goroutine) *SchedulingLatencyAnalysis {
+	a := &SchedulingLatencyAnalysis{
+		Latencies: ptrace.ComputeSchedulingLatencies(tr, gs),
+	}
+	for _, l := range a.Latencies {
+		a.Total.add(l.Latency)
+		a.ByReason[l.Reason].add(l.Latency)
+	}
+	a.Median = a.percentile(0.5)
+	a.P90 = a.percentile(0.9)
+	a.P99 = a.percentile(0.99)
+	return a
+}
+
+func (a *SchedulingLatencyAnalysis) percentile(p float64) time.Duration {
+	if len(a.Latencies) == 0 {
+		return 0
+	}
+	// Latencies are sorted in descending order.
+	return a.Latencies[int(float64(len(a.Latencies)-1)*(1-p))].Latency
+}
+
+// SchedulingLatencyComponent displays the scheduling latencies of all goroutines or of a function's goroutines.
+type SchedulingLatencyComponent struct {
+	trace *Trace
+	// The function whose goroutines to analyze, or nil for all goroutines.
+	fn        *ptrace.Function
+	timelines map[any]*Timeline
+	analysis  *theme.Future[*SchedulingLatencyAnalysis]
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+	hist            InteractiveHistogram
+	histInitialized bool
+
+	reasonsTable  *theme.Table
+	reasonsScroll theme.YScrollableListState
+	worstTable    *theme.Table
+	worstScroll   theme.YScrollableListState
+	cellFormatter CellFormatter
+}
+
+func NewSchedulingLatencyComponent(win *theme.Window, tr *Trace, timelines map[any]*Timeline, fn *ptrace.Function) *SchedulingLatencyComponent {
+	var gs []*ptrace.Goroutine
+	if fn != nil {
+		gs = fn.Goroutines
+	}
+	return &SchedulingLatencyComponent{
+		trace:     tr,
+		fn:        fn,
+		timelines: timelines,
+		analysis: theme.NewFuture(win, func(cancelled <-chan struct{}) *SchedulingLatencyAnalysis {
+			return NewSchedulingLatencyAnalysis(tr.Trace, gs)
+		}),
+	}
+}
+
+func (sc *SchedulingLatencyComponent) Title() string {
+	if sc.fn == nil {
+		return "Scheduling latency"
+	}
+	return "Scheduling latency of " + sc.fn.Func
+}
+
+func (sc *SchedulingLatencyComponent) Transition(theme.ComponentState) {
+}
+
+func (sc *SchedulingLatencyComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (sc *SchedulingLatencyComponent) buildDescription(win *theme.Window, a *SchedulingLatencyAnalysis) Description {
+	tb := TextBuilder{Window: win}
+	var attrs []DescriptionAttribute
+	if sc.fn != nil {
+		attrs = append(attrs, DescriptionAttribute{Key: "Function", Value: *tb.Link(sc.fn.Func, &FunctionObjectLink{Function: sc.fn})})
+	}
+	var mean time.Duration
+	if a.Total.Count != 0 {
+		mean = a.Total.Total / time.Duration(a.Total.Count)
+	}
+	attrs = append(attrs,
+		DescriptionAttribute{Key: "Times scheduled", Value: *tb.Span(local.Sprintf("%d", a.Total.Count))},
+		DescriptionAttribute{Key: "Total latency", Value: *tb.Span(roundDuration(a.Total.Total).String())},
+		DescriptionAttribute{Key: "Mean latency", Value: *tb.Span(roundDuration(mean).String())},
+		DescriptionAttribute{Key: "Median latency", Value: *tb.Span(roundDuration(a.Median).String())},
+		DescriptionAttribute{Key: "90th percentile", Value: *tb.Span(roundDuration(a.P90).String())},
+		DescriptionAttribute{Key: "99th percentile", Value: *tb.Span(roundDuration(a.P99).String())},
+		DescriptionAttribute{Key: "Maximum latency", Value: *tb.Span(roundDuration(a.Total.Max).String())},
+	)
+	return Description{Attributes: attrs}
+}
+
+func (sc *SchedulingLatencyComponent) layoutReasons(win *theme.Window, gtx layout.Context, a *SchedulingLatencyAnalysis) layout.Dimensions {
+	if sc.reasonsTable == nil {
+		sc.reasonsTable = &theme.Table{}
+		sc.reasonsTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Cause", Alignment: text.Start},
+			{Name: "Count", Alignment: text.End},
+			{Name: "Total", Alignment: text.End},
+			{Name: "Mean", Alignment: text.End},
+			{Name: "Max", Alignment: text.End},
+			{Name: "Share of total", Alignment: text.End},
+		})
+	}
+	sc.reasonsTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		reason := ptrace.WakeupReason(row)
+		sum := &a.ByReason[reason]
+		switch colName := sc.reasonsTable.Columns[col].Name; colName {
+		case "Cause":
+			return sc.cellFormatter.Text(win, gtx, reason.String())
+		case "Count":
+			return sc.cellFormatter.Number(win, gtx, sum.Count)
+		case "Total":
+			return sc.cellFormatter.Duration(win, gtx, sum.Total, false)
+		case "Mean":
+			var mean time.Duration
+			if sum.Count != 0 {
+				mean = sum.Total / time.Duration(sum.Count)
+			}
+			return sc.cellFormatter.Duration(win, gtx, mean, false)
+		case "Max":
+			return sc.cellFormatter.Duration(win, gtx, sum.Max, false)
+		case "Share of total":
+			var share float64
+			if a.Total.Total != 0 {
+				share = float64(sum.Total) / float64(a.Total.Total) * 100
+			}
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return sc.cellFormatter.Text(win, gtx, local.Sprintf("%.2f%%", share))
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, sc.reasonsTable, &sc.reasonsScroll, int(ptrace.WakeupLast), cellFn)
+}
+
+// latencySpan returns the span during which the goroutine was waiting to be scheduled, for linking to it.
+func (sc *SchedulingLatencyComponent) latencySpan(l *ptrace.SchedulingLatency) (Items[ptrace.Span], bool) {
+	tl := sc.timelines[l.Goroutine]
+	if tl == nil {
+		return nil, false
+	}
+	return SimpleItems[ptrace.Span, any]{
+		items: l.Goroutine.Spans[l.Span : l.Span+1],
+		container: ItemContainer{
+			Timeline: tl,
+			Track:    tl.tracks[0],
+		},
+		subslice: true,
+	}, true
+}
+
+func (sc *SchedulingLatencyComponent) layoutWorst(win *theme.Window, gtx layout.Context, a *SchedulingLatencyAnalysis) layout.Dimensions {
+	if sc.worstTable == nil {
+		sc.worstTable = &theme.Table{}
+		sc.worstTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Goroutine", Alignment: text.End},
+			{Name: "Function", Alignment: text.Start},
+			{Name: "Cause", Alignment: text.Start},
+			{Name: "Woken by", Alignment: text.End},
+			{Name: "Runnable at", Alignment: text.End},
+			{Name: "Latency", Alignment: text.End},
+		})
+	}
+	sc.worstTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		l := &a.Latencies[row]
+		switch colName := sc.worstTable.Columns[col].Name; colName {
+		case "Goroutine":
+			return sc.cellFormatter.Goroutine(win, gtx, l.Goroutine, "")
+		case "Function":
+			return sc.cellFormatter.Function(win, gtx, l.Goroutine.Function)
+		case "Cause":
+			return sc.cellFormatter.Text(win, gtx, l.Reason.String())
+		case "Woken by":
+			if l.By == exptrace.NoGoroutine {
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}
+			return sc.cellFormatter.Goroutine(win, gtx, sc.trace.G(l.By), "")
+		case "Runnable at":
+			return sc.cellFormatter.Timestamp(win, gtx, sc.trace, l.Goroutine.Spans[l.Span].Start, "")
+		case "Latency":
+			spans, ok := sc.latencySpan(l)
+			if !ok {
+				return sc.cellFormatter.Duration(win, gtx, l.Latency, false)
+			}
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				link := sc.cellFormatter.Clicks.Grow()
+				link.Link = &SpansObjectLink{Spans: spans}
+				return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					value, unit := durationNumberFormatSITable.format(l.Latency)
+					return widget.Label{
+						MaxLines:  1,
+						Alignment: text.Start,
+					}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, value+" "+unit, win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+				})
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, sc.worstTable, &sc.worstScroll, len(a.Latencies), cellFn)
+}
+
+func (sc *SchedulingLatencyComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.SchedulingLatencyComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		a, ok := sc.analysis.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		if !sc.histInitialized {
+			sc.histInitialized = true
+			sc.hist.Config = widget.HistogramConfig{RejectOutliers: true, Bins: widget.DefaultHistogramBins}
+			sc.setHistogram(win, a)
+		}
+		if sc.hist.Update(gtx) {
+			sc.setHistogram(win, a)
+		}
+		sc.cellFormatter.Update(win, gtx)
+		for _, ev := range sc.descriptionText.Update(gtx, sc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     sc.buildDescription(win, a),
+			DescriptionText: &sc.descriptionText,
+			PrevSpans:       &sc.prevSpans,
+			Tabs:            []string{"Histogram", "By cause", "Longest latencies"},
+			TabbedState:     &sc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "Histogram":
+					return sc.hist.Layout(win, gtx)
+				case "By cause":
+					return sc.layoutReasons(win, gtx, a)
+				case "Longest latencies":
+					return sc.layoutWorst(win, gtx, a)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+func (sc *SchedulingLatencyComponent) setHistogram(win *theme.Window, a *SchedulingLatencyAnalysis) {
+	ds := make([]time.Duration, len(a.Latencies))
+	for i, l := range a.Latencies {
+		ds[i] = l.Latency
+	}
+	sc.hist.Set(win, ds)
+}
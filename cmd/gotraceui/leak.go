@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	rtrace "runtime/trace"
+	"strings"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/op"
+	"gioui.org/text"
+	exptrace "golang.org/x/exp/trace"
+)
+
+// leakLocation returns the function in which the goroutines of a leak group blocked.
+func leakLocation(tr *Trace, lg *ptrace.LeakGroup) string {
+	if lg.Stack == exptrace.NoStack {
+		return ""
+	}
+	pcs := tr.Stacks[lg.Stack]
+	g := lg.Goroutines[0]
+	at := int(g.Spans[len(g.Spans)-1].At)
+	if at >= len(pcs) {
+		return ""
+	}
+	return tr.PCs[pcs[at]].Func
+}
+
+// leakGrowth returns the rate at which goroutines joined a leak group, in goroutines per second.
+func leakGrowth(lg *ptrace.LeakGroup) (float64, bool) {
+	n := len(lg.Goroutines)
+	d := time.Duration(lg.BlockedAt(n-1) - lg.BlockedAt(0))
+	if n < 2 || d == 0 {
+		return 0, false
+	}
+	return float64(n-1) / d.Seconds(), true
+}
+
+// LeaksComponent lists groups of goroutines that blocked and never ran again.
+type LeaksComponent struct {
+	trace  *Trace
+	groups *theme.Future[[]*ptrace.LeakGroup]
+
+	// The runtime has several goroutines that block for as long as the program runs, such as the ones writing the
+	// trace. We hide them by default.
+	includeRuntime widget.Bool
+	shown          []*ptrace.LeakGroup
+	initialized    bool
+
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	table         *theme.Table
+	scroll        theme.YScrollableListState
+	cellFormatter CellFormatter
+}
+
+func NewLeaksComponent(win *theme.Window, tr *Trace) *LeaksComponent {
+	return &LeaksComponent{
+		trace: tr,
+		groups: theme.NewFuture(win, func(cancelled <-chan struct{}) []*ptrace.LeakGroup {
+			return ptrace.ComputeLeaks(tr.Trace)
+		}),
+	}
+}
+
+func (lc *LeaksComponent) Title() string {
+	return "Leaked goroutines"
+}
+
+func (lc *LeaksComponent) Transition(theme.ComponentState) {
+}
+
+func (lc *LeaksComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (lc *LeaksComponent) buildDescription(win *theme.Window, groups []*ptrace.LeakGroup) Description {
+	tb := TextBuilder{Window: win}
+	var n int
+	for _, lg := range groups {
+		n += len(lg.Goroutines)
+	}
+	attrs := []DescriptionAttribute{
+		{Key: "Leaked goroutines", Value: *tb.Span(local.Sprintf("%d", n))},
+		{Key: "Groups", Value: *tb.Span(local.Sprintf("%d", len(groups)))},
+		{Key: "Note", Value: *tb.Span("Goroutines that blocked and never ran again. Some may just be idle, or have blocked shortly before the end of the trace.")},
+	}
+	return Description{Attributes: attrs}
+}
+
+func (lc *LeaksComponent) layoutGroups(win *theme.Window, gtx layout.Context, groups []*ptrace.LeakGroup) layout.Dimensions {
+	if lc.table == nil {
+		lc.table = &theme.Table{}
+		lc.table.SetColumns(win, gtx, []theme.Column{
+			{Name: "Goroutines", Alignment: text.End},
+			{Name: "Function", Alignment: text.Start},
+			{Name: "State", Alignment: text.Start},
+			{Name: "Blocked in", Alignment: text.Start},
+			{Name: "First blocked", Alignment: text.End},
+			{Name: "Last blocked", Alignment: text.End},
+			{Name: "Growth", Alignment: text.End},
+		})
+	}
+	lc.table.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		lg := groups[row]
+		switch colName := lc.table.Columns[col].Name; colName {
+		case "Goroutines":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				link := lc.cellFormatter.Clicks.Grow()
+				link.Link = &LeakGroupObjectLink{Group: lg}
+				return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return widget.Label{
+						MaxLines:  1,
+						Alignment: text.Start,
+					}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, local.Sprintf("%d", len(lg.Goroutines)), win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+				})
+			})
+		case "Function":
+			return lc.cellFormatter.Function(win, gtx, lg.Function)
+		case "State":
+			return lc.cellFormatter.Text(win, gtx, stateNamesCapitalized[lg.State])
+		case "Blocked in":
+			return lc.cellFormatter.Text(win, gtx, leakLocation(lc.trace, lg))
+		case "First blocked":
+			return lc.cellFormatter.Timestamp(win, gtx, lc.trace, lg.BlockedAt(0), "")
+		case "Last blocked":
+			return lc.cellFormatter.Timestamp(win, gtx, lc.trace, lg.BlockedAt(len(lg.Goroutines)-1), "")
+		case "Growth":
+			rate, ok := leakGrowth(lg)
+			if !ok {
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return lc.cellFormatter.Text(win, gtx, local.Sprintf("%.2f/s", rate))
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, lc.table, &lc.scroll, len(groups), cellFn)
+}
+
+func (lc *LeaksComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.LeaksComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		groups, ok := lc.groups.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		if lc.includeRuntime.Update(gtx) || !lc.initialized {
+			lc.initialized = true
+			lc.shown = lc.shown[:0]
+			for _, lg := range groups {
+				if !lc.includeRuntime.Value && lg.Function != nil && strings.HasPrefix(lg.Function.Func, "runtime.") {
+					continue
+				}
+				lc.shown = append(lc.shown, lg)
+			}
+		}
+		lc.cellFormatter.Update(win, gtx)
+		for _, ev := range lc.descriptionText.Update(gtx, lc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     lc.buildDescription(win, lc.shown),
+			DescriptionText: &lc.descriptionText,
+			PrevSpans:       &lc.prevSpans,
+			Content: func(win *theme.Window, gtx layout.Context, _ string) layout.Dimensions {
+				return layout.Rigids(gtx, layout.Vertical,
+					func(gtx layout.Context) layout.Dimensions {
+						return theme.CheckBox(win.Theme, &lc.includeRuntime, "Include goroutines of the runtime").Layout(win, gtx)
+					},
+					layout.Spacer{Height: 5}.Layout,
+					func(gtx layout.Context) layout.Dimensions {
+						gtx.Constraints.Min = gtx.Constraints.Max
+						return lc.layoutGroups(win, gtx, lc.shown)
+					},
+				)
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// LeakGroupInfo is a panel that shows the goroutines of a leak group, when they blocked, and where.
+type LeakGroupInfo struct {
+	mwin          *theme.Window
+	group         *ptrace.LeakGroup
+	trace         *Trace
+	tabbedState   theme.TabbedState
+	goroutineList GoroutineList
+	hist          InteractiveHistogram
+
+	stacktrace      string
+	stacktraceList  widget.List
+	stackSelectable widget.Selectable
+
+	descriptionText Text
+	hoveredLink     ObjectLink
+	prevSpans       []TextSpan
+
+	initialized bool
+
+	theme.ComponentButtons
+}
+
+func NewLeakGroupInfo(tr *Trace, mwin *theme.Window, lg *ptrace.LeakGroup) *LeakGroupInfo {
+	return &LeakGroupInfo{
+		mwin:  mwin,
+		group: lg,
+		trace: tr,
+	}
+}
+
+func (li *LeakGroupInfo) HoveredLink() ObjectLink {
+	return li.hoveredLink
+}
+
+func (li *LeakGroupInfo) Title() string {
+	if li.group.Function == nil {
+		return "Leaked goroutines"
+	}
+	return "Leaked goroutines of " + li.group.Function.Func
+}
+
+func (li *LeakGroupInfo) init(win *theme.Window) {
+	li.goroutineList.Trace = li.trace
+	li.goroutineList.HiddenColumns.Function = true
+	li.goroutineList.HiddenColumns.EndTime = true
+	li.goroutineList.HiddenColumns.Duration = true
+	li.hist.Config = widget.HistogramConfig{Bins: widget.DefaultHistogramBins}
+	li.computeHistogram(win)
+
+	if li.group.Stack != exptrace.NoStack {
+		var sb strings.Builder
+		for frame := range li.group.Stack.Frames() {
+			fmt.Fprintf(&sb, "%s\n        %s:%d\n", frame.Func, frame.File, frame.Line)
+		}
+		li.stacktrace = strings.TrimSuffix(sb.String(), "\n")
+	}
+}
+
+// computeHistogram computes the histogram of the times, relative to the start of the trace, at which the goroutines
+// blocked, which shows how the group grew over time.
+func (li *LeakGroupInfo) computeHistogram(win *theme.Window) {
+	cfg := &li.hist.Config
+	start := li.trace.Start()
+	var ds []time.Duration
+	for i := range li.group.Goroutines {
+		d := time.Duration(li.group.BlockedAt(i) - start)
+		if fd := widget.FloatDuration(d); fd >= cfg.Start && (cfg.End == 0 || fd <= cfg.End) {
+			ds = append(ds, d)
+		}
+	}
+	li.hist.Set(win, ds)
+}
+
+func (li *LeakGroupInfo) buildDescription(win *theme.Window) Description {
+	tb := TextBuilder{Window: win}
+	lg := li.group
+	var attrs []DescriptionAttribute
+	if lg.Function != nil {
+		attrs = append(attrs, DescriptionAttribute{Key: "Function", Value: *tb.Link(lg.Function.Func, &FunctionObjectLink{Function: lg.Function})})
+	}
+	attrs = append(attrs,
+		DescriptionAttribute{Key: "State", Value: *tb.Span(stateNamesCapitalized[lg.State])},
+	)
+	if loc := leakLocation(li.trace, lg); loc != "" {
+		attrs = append(attrs, DescriptionAttribute{Key: "Blocked in", Value: *tb.Span(loc)})
+	}
+	last := lg.Goroutines[len(lg.Goroutines)-1]
+	attrs = append(attrs,
+		DescriptionAttribute{Key: "# of goroutines", Value: *tb.Span(local.Sprintf("%d", len(lg.Goroutines)))},
+		DescriptionAttribute{Key: "First blocked", Value: *tb.DefaultLink(formatTimestamp(nil, li.trace.AdjustedTime(lg.BlockedAt(0))), "First blocked", lg.BlockedAt(0))},
+		DescriptionAttribute{Key: "Last blocked", Value: *tb.DefaultLink(formatTimestamp(nil, li.trace.AdjustedTime(lg.BlockedAt(len(lg.Goroutines)-1))), "Last blocked", lg.BlockedAt(len(lg.Goroutines)-1))},
+		DescriptionAttribute{Key: "Blocked for at least", Value: *tb.Span(roundDuration(time.Duration(last.EffectiveEnd() - lg.BlockedAt(len(lg.Goroutines)-1))).String())},
+	)
+	if rate, ok := leakGrowth(lg); ok {
+		attrs = append(attrs, DescriptionAttribute{Key: "Growth", Value: *tb.Span(local.Sprintf("%.2f goroutines/s", rate))})
+	}
+	return Description{Attributes: attrs}
+}
+
+func (li *LeakGroupInfo) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.LeakGroupInfo.Layout").End()
+
+	if !li.initialized {
+		li.init(win)
+		li.initialized = true
+	}
+
+	for _, ev := range li.descriptionText.Update(gtx, li.prevSpans) {
+		handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+	}
+	firstNonNil := func(els ...ObjectLink) ObjectLink {
+		for _, el := range els {
+			if el != nil {
+				return el
+			}
+		}
+		return nil
+	}
+	li.hoveredLink = firstNonNil(
+		li.goroutineList.HoveredLink(),
+		li.descriptionText.HoveredLink(),
+	)
+
+	for li.ComponentButtons.Backed(gtx) {
+		li.mwin.EmitAction(&PrevPanelAction{})
+	}
+
+	if li.hist.Update(gtx) {
+		li.computeHistogram(win)
+	}
+
+	// Inset of 5 pixels on all sides. We can't use layout.Inset because it doesn't decrease the minimum constraint,
+	// which we do care about here.
+	gtx.Constraints.Min = gtx.Constraints.Min.Sub(image.Pt(2*5, 2*5))
+	gtx.Constraints.Max = gtx.Constraints.Max.Sub(image.Pt(2*5, 2*5))
+	gtx.Constraints = layout.Normalize(gtx.Constraints)
+	defer op.Offset(image.Pt(5, 5)).Push(gtx.Ops).Pop()
+
+	nothing := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	}
+
+	tabs := []string{"Goroutines", "Growth"}
+	if li.stacktrace != "" {
+		tabs = append(tabs, "Stack trace")
+	}
+
+	return layout.Rigids(gtx, layout.Vertical,
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Flexed(1, nothing),
+				layout.Rigid(theme.Dumb(win, li.ComponentButtons.Layout)),
+			)
+		},
+
+		func(gtx layout.Context) layout.Dimensions { return layout.Spacer{Height: 10}.Layout(gtx) },
+		func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = image.Point{}
+			li.descriptionText.Reset(win.Theme)
+			dims, spans := li.buildDescription(win).Layout(win, gtx, &li.descriptionText)
+			li.prevSpans = spans
+			return dims
+		},
+
+		func(gtx layout.Context) layout.Dimensions { return layout.Spacer{Height: 10}.Layout(gtx) },
+		func(gtx layout.Context) layout.Dimensions {
+			return theme.Tabbed(&li.tabbedState, tabs).Layout(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min = gtx.Constraints.Max
+				switch tabs[li.tabbedState.Current] {
+				case "Goroutines":
+					if li.goroutineList.Goroutines.Len() == 0 {
+						li.goroutineList.SetGoroutines(win, gtx, li.group.Goroutines)
+					}
+					return li.goroutineList.Layout(win, gtx)
+				case "Growth":
+					return li.hist.Layout(win, gtx)
+				case "Stack trace":
+					return theme.List(win.Theme, &li.stacktraceList).Layout(
+						win,
+						gtx,
+						1,
+						func(gtx layout.Context, index int) layout.Dimensions {
+							if li.stackSelectable.Text() == "" {
+								li.stackSelectable.SetText(li.stacktrace)
+							}
+							return li.stackSelectable.Layout(
+								gtx,
+								win.Theme.Shaper,
+								font.Font{},
+								win.Theme.TextSize,
+								win.ColorMaterial(gtx, win.Theme.Palette.Foreground),
+								win.ColorMaterial(gtx, win.Theme.Palette.PrimarySelection))
+						},
+					)
+				default:
+					panic("unreachable")
+				}
+			})
+		},
+	)
+}
@@ -72,6 +72,10 @@ type OpenTaskAction struct {
 	Task       *ptrace.Task
 	Provenance string
 }
+type OpenTaskCriticalPathAction struct {
+	Task       *ptrace.Task
+	Provenance string
+}
 type ScrollToTimestampAction exptrace.Time
 type OpenFunctionAction struct {
 	Function   *ptrace.Function
@@ -97,6 +101,9 @@ type ZoomToObjectAction struct {
 	Object     any
 	Provenance string
 }
+type ZoomToTimeRangeAction struct {
+	Start, End exptrace.Time
+}
 type CanvasJumpToBeginningAction struct{}
 type CanvasScrollToTopAction struct{}
 type CanvasUndoNavigationAction struct{}
@@ -118,6 +125,59 @@ type StopCPUProfileAction struct{}
 type OpenPanelAction struct {
 	Panel Panel
 }
+type OpenSchedulingLatencyAction struct {
+	// The function whose goroutines to analyze, or nil for all goroutines.
+	Function *ptrace.Function
+}
+type OpenCriticalPathAction struct {
+	Goroutine  *ptrace.Goroutine
+	Start, End exptrace.Time
+	// What the critical path leads to, such as a task.
+	Label string
+}
+type HighlightCriticalPathAction struct {
+	// The critical path to highlight, or nil to stop highlighting.
+	Path *ptrace.CriticalPath
+}
+type OpenUnblockGraphAction struct{}
+type OpenUnblockingsAction struct {
+	Edge *ptrace.UnblockEdge
+}
+type OpenLeaksAction struct{}
+type OpenLeakGroupAction struct {
+	Group *ptrace.LeakGroup
+}
+type OpenContentionAction struct{}
+type OpenContentionWaitsAction struct {
+	Waits []ptrace.ContentionWait
+	Title string
+}
+type OpenNetworkAction struct{}
+type OpenNetworkWaitsAction struct {
+	Waits []ptrace.NetworkWait
+	Title string
+}
+type OpenSyscallsAction struct{}
+type OpenSyscallCallsAction struct {
+	Calls []ptrace.Syscall
+	Title string
+}
+type OpenGCCyclesAction struct{}
+type OpenMetricsAction struct{}
+type SetPlotsAction struct {
+	Plots []PlotConfig
+}
+type OpenSearchAction struct{}
+type HighlightSearchMatchesAction struct {
+	// The search matches to highlight, or nil to stop highlighting.
+	Matches *SearchMatches
+}
+type ExportProfileAction struct {
+	Kind profileKind
+	// The goroutines to include in the profile, or nil for all goroutines.
+	Goroutines []*ptrace.Goroutine
+	Start, End exptrace.Time
+}
 type PrevPanelAction struct{}
 
 type GoroutineObjectLink struct {
@@ -128,6 +188,10 @@ type ProcessorObjectLink struct {
 	Processor  *ptrace.Processor
 	Provenance string
 }
+type MachineObjectLink struct {
+	Machine    *ptrace.Machine
+	Provenance string
+}
 type TimestampObjectLink struct {
 	Timestamp  exptrace.Time
 	Provenance string
@@ -144,6 +208,10 @@ type STWObjectLink struct {
 	STW        *STW
 	Provenance string
 }
+type RangeObjectLink struct {
+	Range      *Range
+	Provenance string
+}
 type SpansObjectLink struct {
 	Spans Items[ptrace.Span]
 }
@@ -151,10 +219,32 @@ type TaskObjectLink struct {
 	Task       *ptrace.Task
 	Provenance string
 }
+type LeakGroupObjectLink struct {
+	Group *ptrace.LeakGroup
+}
+type UnblockEdgeObjectLink struct {
+	Edge *ptrace.UnblockEdge
+}
+type ContentionWaitsObjectLink struct {
+	Waits []ptrace.ContentionWait
+	Title string
+}
+type NetworkWaitsObjectLink struct {
+	Waits []ptrace.NetworkWait
+	Title string
+}
+type SyscallsObjectLink struct {
+	Calls []ptrace.Syscall
+	Title string
+}
+type GCCycleObjectLink struct {
+	Cycle *ptrace.GCCycle
+}
 
 func (*OpenGoroutineAction) IsAction()              {}
 func (*OpenGoroutineFlameGraphAction) IsAction()    {}
 func (*OpenTaskAction) IsAction()                   {}
+func (*OpenTaskCriticalPathAction) IsAction()       {}
 func (ScrollToTimestampAction) IsAction()           {}
 func (*OpenFunctionAction) IsAction()               {}
 func (*SpansAction) IsAction()                      {}
@@ -165,6 +255,7 @@ func (*ScrollToTimelineAction) IsAction()           {}
 func (*ZoomToTimelineAction) IsAction()             {}
 func (*ScrollToObjectAction) IsAction()             {}
 func (*ZoomToObjectAction) IsAction()               {}
+func (*ZoomToTimeRangeAction) IsAction()            {}
 func (*CanvasJumpToBeginningAction) IsAction()      {}
 func (*CanvasScrollToTopAction) IsAction()          {}
 func (*CanvasUndoNavigationAction) IsAction()       {}
@@ -185,6 +276,25 @@ func (*StartCPUProfileAction) IsAction()            {}
 func (*StopCPUProfileAction) IsAction()             {}
 func (*OpenPanelAction) IsAction()                  {}
 func (*PrevPanelAction) IsAction()                  {}
+func (*OpenSchedulingLatencyAction) IsAction()      {}
+func (*OpenCriticalPathAction) IsAction()           {}
+func (*HighlightCriticalPathAction) IsAction()      {}
+func (*OpenUnblockGraphAction) IsAction()           {}
+func (*OpenUnblockingsAction) IsAction()            {}
+func (*OpenLeaksAction) IsAction()                  {}
+func (*OpenLeakGroupAction) IsAction()              {}
+func (*OpenContentionAction) IsAction()             {}
+func (*OpenContentionWaitsAction) IsAction()        {}
+func (*OpenNetworkAction) IsAction()                {}
+func (*OpenNetworkWaitsAction) IsAction()           {}
+func (*OpenSyscallsAction) IsAction()               {}
+func (*OpenSyscallCallsAction) IsAction()           {}
+func (*OpenGCCyclesAction) IsAction()               {}
+func (*OpenMetricsAction) IsAction()                {}
+func (*SetPlotsAction) IsAction()                   {}
+func (*OpenSearchAction) IsAction()                 {}
+func (*HighlightSearchMatchesAction) IsAction()     {}
+func (*ExportProfileAction) IsAction()              {}
 
 func defaultObjectLink(obj any, provenance string) ObjectLink {
 	switch obj := obj.(type) {
@@ -192,6 +302,8 @@ func defaultObjectLink(obj any, provenance string) ObjectLink {
 		return &GoroutineObjectLink{obj, provenance}
 	case *ptrace.Processor:
 		return &ProcessorObjectLink{obj, provenance}
+	case *ptrace.Machine:
+		return &MachineObjectLink{obj, provenance}
 	case *exptrace.Time:
 		return &TimestampObjectLink{*obj, provenance}
 	case exptrace.Time:
@@ -202,6 +314,8 @@ func defaultObjectLink(obj any, provenance string) ObjectLink {
 		return &GCObjectLink{obj, provenance}
 	case *STW:
 		return &STWObjectLink{obj, provenance}
+	case *Range:
+		return &RangeObjectLink{obj, provenance}
 	case *ptrace.Task:
 		return &TaskObjectLink{obj, provenance}
 	default:
@@ -258,6 +372,18 @@ func (l *GoroutineObjectLink) ContextMenu() []*theme.MenuItem {
 				return (*OpenGoroutineFlameGraphAction)(l)
 			},
 		},
+		{
+			Label: PlainLabel("Export CPU profile…"),
+			Action: func() theme.Action {
+				return &ExportProfileAction{Kind: profileCPU, Goroutines: []*ptrace.Goroutine{l.Goroutine}}
+			},
+		},
+		{
+			Label: PlainLabel("Export off-CPU profile…"),
+			Action: func() theme.Action {
+				return &ExportProfileAction{Kind: profileOffCPU, Goroutines: []*ptrace.Goroutine{l.Goroutine}}
+			},
+		},
 	}
 }
 
@@ -300,6 +426,45 @@ func (l *ProcessorObjectLink) ContextMenu() []*theme.MenuItem {
 	}
 }
 
+func (l *MachineObjectLink) Action(mods key.Modifiers) theme.Action {
+	// There are no machine panels yet, so key.ModShift doesn't do anything
+	switch mods {
+	default:
+		return &ScrollToObjectAction{
+			Object:     l.Machine,
+			Provenance: l.Provenance,
+		}
+	case key.ModShortcut:
+		return &ZoomToObjectAction{
+			Object:     l.Machine,
+			Provenance: l.Provenance,
+		}
+	}
+}
+
+func (l *MachineObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Scroll to machine"),
+			Action: func() theme.Action {
+				return &ScrollToObjectAction{
+					Object:     l.Machine,
+					Provenance: l.Provenance,
+				}
+			},
+		},
+		{
+			Label: PlainLabel("Zoom to machine"),
+			Action: func() theme.Action {
+				return &ZoomToObjectAction{
+					Object:     l.Machine,
+					Provenance: l.Provenance,
+				}
+			},
+		},
+	}
+}
+
 func (l *TimestampObjectLink) Action(mods key.Modifiers) theme.Action {
 	return ScrollToTimestampAction(l.Timestamp)
 }
@@ -313,7 +478,26 @@ func (l *FunctionObjectLink) Action(mods key.Modifiers) theme.Action {
 }
 
 func (l *FunctionObjectLink) ContextMenu() []*theme.MenuItem {
-	return nil
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Open scheduling latency analysis"),
+			Action: func() theme.Action {
+				return &OpenSchedulingLatencyAction{Function: l.Function}
+			},
+		},
+		{
+			Label: PlainLabel("Export CPU profile of goroutines…"),
+			Action: func() theme.Action {
+				return &ExportProfileAction{Kind: profileCPU, Goroutines: l.Function.Goroutines}
+			},
+		},
+		{
+			Label: PlainLabel("Export off-CPU profile of goroutines…"),
+			Action: func() theme.Action {
+				return &ExportProfileAction{Kind: profileOffCPU, Goroutines: l.Function.Goroutines}
+			},
+		},
+	}
 }
 
 func (l *GCObjectLink) Action(mods key.Modifiers) theme.Action {
@@ -416,6 +600,56 @@ func (l *STWObjectLink) ContextMenu() []*theme.MenuItem {
 	}
 }
 
+func (l *RangeObjectLink) Action(mods key.Modifiers) theme.Action {
+	switch mods {
+	default:
+		return &OpenSpansAction{
+			Spans: l.Range.Spans,
+		}
+	case key.ModShortcut:
+		return &ZoomToObjectAction{
+			Object:     l.Range,
+			Provenance: l.Provenance,
+		}
+	case key.ModShift:
+		return &ScrollToObjectAction{
+			Object:     l.Range,
+			Provenance: l.Provenance,
+		}
+	}
+}
+
+func (l *RangeObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Scroll to range timeline"),
+			Action: func() theme.Action {
+				return &ScrollToObjectAction{
+					Object:     l.Range,
+					Provenance: l.Provenance,
+				}
+			},
+		},
+		{
+			Label: PlainLabel("Zoom to range timeline"),
+			Action: func() theme.Action {
+				return &ZoomToObjectAction{
+					Object:     l.Range,
+					Provenance: l.Provenance,
+				}
+			},
+		},
+		{
+			Label: PlainLabel("Show range information"),
+			Action: func() theme.Action {
+				return &OpenSpansAction{
+					Spans: l.Range.Spans,
+				}
+			},
+		},
+	}
+}
+
 func (l *SpansObjectLink) Action(mods key.Modifiers) theme.Action {
 	switch mods {
 	default:
@@ -528,6 +762,135 @@ func (l *TaskObjectLink) ContextMenu() []*theme.MenuItem {
 				return (*OpenTaskAction)(l)
 			},
 		},
+		{
+			Label: PlainLabel("Show critical path"),
+			Action: func() theme.Action {
+				return (*OpenTaskCriticalPathAction)(l)
+			},
+		},
+	}
+}
+
+func (l *LeakGroupObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &OpenLeakGroupAction{Group: l.Group}
+}
+
+func (l *LeakGroupObjectLink) ContextMenu() []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		{
+			Label: PlainLabel("Show leaked goroutines"),
+			Action: func() theme.Action {
+				return &OpenLeakGroupAction{Group: l.Group}
+			},
+		},
+	}
+	if l.Group.Function != nil {
+		items = append(items, &theme.MenuItem{
+			Label: PlainLabel(local.Sprintf("Show function %s", l.Group.Function.Func)),
+			Action: func() theme.Action {
+				return &OpenFunctionAction{Function: l.Group.Function}
+			},
+		})
+	}
+	return items
+}
+
+func (l *UnblockEdgeObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &OpenUnblockingsAction{Edge: l.Edge}
+}
+
+func (l *UnblockEdgeObjectLink) ContextMenu() []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		{
+			Label: PlainLabel("Show wake-ups"),
+			Action: func() theme.Action {
+				return &OpenUnblockingsAction{Edge: l.Edge}
+			},
+		},
+	}
+	for _, fn := range []*ptrace.Function{l.Edge.From, l.Edge.To} {
+		if fn == nil {
+			continue
+		}
+		items = append(items, &theme.MenuItem{
+			Label: PlainLabel(local.Sprintf("Show function %s", fn.Func)),
+			Action: func() theme.Action {
+				return &OpenFunctionAction{Function: fn}
+			},
+		})
+	}
+	return items
+}
+
+func (l *ContentionWaitsObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &OpenContentionWaitsAction{Waits: l.Waits, Title: l.Title}
+}
+
+func (l *ContentionWaitsObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Show waits"),
+			Action: func() theme.Action {
+				return &OpenContentionWaitsAction{Waits: l.Waits, Title: l.Title}
+			},
+		},
+	}
+}
+
+func (l *NetworkWaitsObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &OpenNetworkWaitsAction{Waits: l.Waits, Title: l.Title}
+}
+
+func (l *NetworkWaitsObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Show waits"),
+			Action: func() theme.Action {
+				return &OpenNetworkWaitsAction{Waits: l.Waits, Title: l.Title}
+			},
+		},
+	}
+}
+
+func (l *SyscallsObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &OpenSyscallCallsAction{Calls: l.Calls, Title: l.Title}
+}
+
+func (l *SyscallsObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Show syscalls"),
+			Action: func() theme.Action {
+				return &OpenSyscallCallsAction{Calls: l.Calls, Title: l.Title}
+			},
+		},
+	}
+}
+
+func (l *GCCycleObjectLink) Action(mods key.Modifiers) theme.Action {
+	return &ZoomToTimeRangeAction{Start: l.Cycle.Start, End: l.Cycle.End}
+}
+
+func (l *GCCycleObjectLink) ContextMenu() []*theme.MenuItem {
+	return []*theme.MenuItem{
+		{
+			Label: PlainLabel("Zoom to GC cycle"),
+			Action: func() theme.Action {
+				return &ZoomToTimeRangeAction{Start: l.Cycle.Start, End: l.Cycle.End}
+			},
+		},
+		{
+			Label: PlainLabel("Scroll to start of GC cycle"),
+			Action: func() theme.Action {
+				return ScrollToTimestampAction(l.Cycle.Start)
+			},
+		},
+		{
+			Label: PlainLabel("Scroll to end of GC cycle"),
+			Action: func() theme.Action {
+				return ScrollToTimestampAction(l.Cycle.End)
+			},
+		},
 	}
 }
 
@@ -535,11 +898,21 @@ func (l *ScrollToTimelineAction) Open(gtx layout.Context, mwin *MainWindow) {
 	mwin.canvas.scrollToTimeline(gtx, l.Timeline)
 }
 
-func (l *ZoomToTimelineAction) Open(gtx layout.Context, mwin *MainWindow) {
+// zoomBounds returns the range of time that zooming to a timeline displays.
+func zoomBounds(tl *Timeline) (start, end exptrace.Time) {
+	if m, ok := tl.item.(*ptrace.Machine); ok {
+		// The first track of a machine only covers the time it held a processor, which may be never.
+		return machineBounds(m)
+	}
 	// TODO(dh): this assumes that the first track is always the longest
-	tr := l.Timeline.tracks[0]
+	tr := tl.tracks[0]
+	return tr.Start, tr.End
+}
+
+func (l *ZoomToTimelineAction) Open(gtx layout.Context, mwin *MainWindow) {
+	start, end := zoomBounds(l.Timeline)
 	y := mwin.canvas.timelineY(gtx, l.Timeline)
-	mwin.canvas.navigateToStartAndEnd(gtx, tr.Start, tr.End, y)
+	mwin.canvas.navigateToStartAndEnd(gtx, start, end, y)
 }
 
 func (l *ScrollToObjectAction) Open(gtx layout.Context, mwin *MainWindow) {
@@ -553,18 +926,21 @@ func (l *ScrollToObjectAction) Open(gtx layout.Context, mwin *MainWindow) {
 }
 
 func (l *ZoomToObjectAction) Open(gtx layout.Context, mwin *MainWindow) {
-	// TODO(dh): this assumes that the first track is always the longest
 	// OPT(dh): don't be O(n)
 	for _, tl := range mwin.canvas.timelines {
 		if tl.item == l.Object {
-			tr := tl.tracks[0]
+			start, end := zoomBounds(tl)
 			y := mwin.canvas.timelineY(gtx, tl)
-			mwin.canvas.navigateToStartAndEnd(gtx, tr.Start, tr.End, y)
+			mwin.canvas.navigateToStartAndEnd(gtx, start, end, y)
 			return
 		}
 	}
 }
 
+func (l *ZoomToTimeRangeAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.canvas.navigateToStartAndEnd(gtx, l.Start, l.End, mwin.canvas.y)
+}
+
 func (l *OpenGoroutineAction) Open(_ layout.Context, mwin *MainWindow) {
 	mwin.openGoroutine(l.Goroutine)
 }
@@ -751,6 +1127,91 @@ func (*PrevPanelAction) Open(gtx layout.Context, mwin *MainWindow) {
 	mwin.prevPanel()
 }
 
+func (l *OpenSchedulingLatencyAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openSchedulingLatency(l.Function)
+}
+
+func (l *OpenTaskCriticalPathAction) Open(gtx layout.Context, mwin *MainWindow) {
+	a, ok := criticalPathForTask(mwin.trace, l.Task)
+	if !ok {
+		mwin.showNotification("Can't compute the critical path of a task that has no goroutine")
+		return
+	}
+	a.Open(gtx, mwin)
+}
+
+func (l *OpenLeaksAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openLeaks()
+}
+
+func (l *OpenLeakGroupAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openPanel(NewLeakGroupInfo(mwin.trace, mwin.twin, l.Group))
+}
+
+func (l *OpenContentionAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openContention()
+}
+
+func (l *OpenContentionWaitsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openContentionWaits(l.Waits, l.Title)
+}
+
+func (l *OpenNetworkAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openNetwork()
+}
+
+func (l *OpenNetworkWaitsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openNetworkWaits(l.Waits, l.Title)
+}
+
+func (l *OpenSyscallsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openSyscalls()
+}
+
+func (l *OpenSyscallCallsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openSyscallCalls(l.Calls, l.Title)
+}
+
+func (l *OpenGCCyclesAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openGCCycles()
+}
+
+func (l *OpenMetricsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openMetrics()
+}
+
+func (l *SetPlotsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.setPlots(l.Plots)
+}
+
+func (l *OpenSearchAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openSearch()
+}
+
+func (l *HighlightSearchMatchesAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.canvas.timeline.searchMatches = l.Matches
+}
+
+func (l *OpenUnblockGraphAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openUnblockGraph()
+}
+
+func (l *OpenUnblockingsAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openUnblockings(l.Edge)
+}
+
+func (l *OpenCriticalPathAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.openCriticalPath(l.Goroutine, l.Start, l.End, l.Label)
+}
+
+func (l *HighlightCriticalPathAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.canvas.timeline.criticalPath = l.Path
+}
+
+func (l *ExportProfileAction) Open(gtx layout.Context, mwin *MainWindow) {
+	mwin.exportProfile(l.Kind, l.Goroutines, l.Start, l.End)
+}
+
 func (*OpenGoroutineAction) IsOpenAction()                    {}
 func (*OpenGoroutineFlameGraphAction) IsOpenAction()          {}
 func (*OpenTaskAction) IsOpenAction()                         {}
@@ -764,6 +1225,7 @@ func (*ScrollToTimelineAction) IsNavigationAction()           {}
 func (*ZoomToTimelineAction) IsNavigationAction()             {}
 func (*ScrollToObjectAction) IsNavigationAction()             {}
 func (*ZoomToObjectAction) IsNavigationAction()               {}
+func (*ZoomToTimeRangeAction) IsNavigationAction()            {}
 func (*CanvasJumpToBeginningAction) IsNavigationAction()      {}
 func (*CanvasScrollToTopAction) IsNavigationAction()          {}
 func (*CanvasUndoNavigationAction) IsNavigationAction()       {}
@@ -774,3 +1236,19 @@ func (*OpenHighlightSpansDialogAction) IsOpenAction()         {}
 func (*OpenScrollToTimelineAction) IsOpenAction()             {}
 func (*OpenFileOpenAction) IsOpenAction()                     {}
 func (*OpenPanelAction) IsOpenAction()                        {}
+func (*OpenSchedulingLatencyAction) IsOpenAction()            {}
+func (*OpenCriticalPathAction) IsOpenAction()                 {}
+func (*OpenUnblockGraphAction) IsOpenAction()                 {}
+func (*OpenUnblockingsAction) IsOpenAction()                  {}
+func (*OpenLeaksAction) IsOpenAction()                        {}
+func (*OpenLeakGroupAction) IsOpenAction()                    {}
+func (*OpenContentionAction) IsOpenAction()                   {}
+func (*OpenContentionWaitsAction) IsOpenAction()              {}
+func (*OpenNetworkAction) IsOpenAction()                      {}
+func (*OpenNetworkWaitsAction) IsOpenAction()                 {}
+func (*OpenSyscallsAction) IsOpenAction()                     {}
+func (*OpenSyscallCallsAction) IsOpenAction()                 {}
+func (*OpenGCCyclesAction) IsOpenAction()                     {}
+func (*OpenMetricsAction) IsOpenAction()                      {}
+func (*OpenSearchAction) IsOpenAction()                       {}
+func (*OpenTaskCriticalPathAction) IsOpenAction()             {}
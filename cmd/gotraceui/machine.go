@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"time"
+
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+type MachineTooltip struct {
+	m     *ptrace.Machine
+	trace *Trace
+}
+
+func (tt MachineTooltip) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.MachineTooltip.Layout").End()
+
+	// OPT(dh): compute statistics once, not on every frame
+
+	var procD, runningD, syscallD time.Duration
+	for i := range tt.m.Spans {
+		procD += tt.m.Spans[i].Duration()
+	}
+	for i := range tt.m.Goroutines {
+		s := &tt.m.Goroutines[i]
+		switch s.State {
+		case ptrace.StateActive:
+			runningD += s.Duration()
+		case ptrace.StateBlockedSyscall:
+			syscallD += s.Duration()
+		}
+	}
+
+	l := local.Sprintf(
+		"Machine %d\n"+
+			"Processor spans: %d\n"+
+			"Goroutine spans: %d\n"+
+			"Time holding processors: %s\n"+
+			"Time running goroutines: %s\n"+
+			"Time blocked in syscalls: %s",
+		tt.m.ID,
+		len(tt.m.Spans),
+		len(tt.m.Goroutines),
+		roundDuration(procD),
+		roundDuration(runningD),
+		roundDuration(syscallD),
+	)
+
+	return theme.Tooltip(win.Theme, l).Layout(win, gtx)
+}
+
+func machineProcessorTrackSpanTooltip(win *theme.Window, gtx layout.Context, tr *Trace, spans Items[ptrace.Span]) layout.Dimensions {
+	var label string
+	if spans.Len() == 1 {
+		pid := tr.Event(spans.AtPtr(0).StartEvent).StateTransition().Resource.Proc()
+		label = local.Sprintf("Holding processor %d\n", pid)
+	} else {
+		label = local.Sprintf("%d spans\n", spans.Len())
+	}
+	label += spansDurationForTooltip(spans)
+	return theme.Tooltip(win.Theme, label).Layout(win, gtx)
+}
+
+func machineProcessorTrackSpanLabel(spans Items[ptrace.Span], tr *Trace, out []string) []string {
+	if spans.Len() != 1 {
+		return out
+	}
+	p := tr.P(tr.Event(spans.AtPtr(0).StartEvent).StateTransition().Resource.Proc())
+	return append(out, tr.processorSpanLabels(p)...)
+}
+
+func machineProcessorTrackSpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		newZoomMenuItem(cv, spans),
+		newOpenSpansMenuItem(spans),
+	}
+
+	if spans.Len() == 1 {
+		pid := cv.trace.Event(spans.AtPtr(0).StartEvent).StateTransition().Resource.Proc()
+		items = append(items, &theme.MenuItem{
+			Label: PlainLabel(local.Sprintf("Scroll to processor %d", pid)),
+			Action: func() theme.Action {
+				return &ScrollToObjectAction{Object: cv.trace.P(pid)}
+			},
+		})
+	}
+
+	return items
+}
+
+func machineGoroutineTrackSpanTooltip(win *theme.Window, gtx layout.Context, tr *Trace, spans Items[ptrace.Span]) layout.Dimensions {
+	var label string
+	if spans.Len() == 1 {
+		s := spans.AtPtr(0)
+		gid := tr.Event(s.StartEvent).StateTransition().Resource.Goroutine()
+		label = tooltipStateLabels[s.State] + "\n"
+		// OPT(dh): cache these strings
+		label += local.Sprintf("Goroutine %d: %s\n", gid, tr.G(gid).Function)
+	} else {
+		label = local.Sprintf("%d spans\n", spans.Len())
+	}
+	label += spansDurationForTooltip(spans)
+	return theme.Tooltip(win.Theme, label).Layout(win, gtx)
+}
+
+func machineGoroutineTrackSpanLabel(spans Items[ptrace.Span], tr *Trace, out []string) []string {
+	if spans.Len() != 1 {
+		return out
+	}
+	g := tr.G(tr.Event(spans.AtPtr(0).StartEvent).StateTransition().Resource.Goroutine())
+	return append(out, tr.goroutineSpanLabels(g)...)
+}
+
+func machineGoroutineTrackSpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		newZoomMenuItem(cv, spans),
+		newOpenSpansMenuItem(spans),
+	}
+
+	if spans.Len() == 1 {
+		gid := cv.trace.Event(spans.AtPtr(0).StartEvent).StateTransition().Resource.Goroutine()
+		items = append(items, &theme.MenuItem{
+			Label: PlainLabel(local.Sprintf("Scroll to goroutine %d", gid)),
+			Action: func() theme.Action {
+				return &ScrollToObjectAction{Object: cv.trace.G(gid)}
+			},
+		})
+	}
+
+	return items
+}
+
+// NewMachineTimeline returns a timeline for an OS thread. The first track shows the processors held by the thread, the
+// second track shows the goroutines it ran and the syscalls it blocked in. They're followed by one track per range
+// scoped to the thread.
+func NewMachineTimeline(tr *Trace, cv *Canvas, m *ptrace.Machine) *Timeline {
+	l := local.Sprintf("Machine %d", m.ID)
+	tl := &Timeline{
+		cv: cv,
+
+		widgetTooltip: func(win *theme.Window, gtx layout.Context, tl *Timeline) layout.Dimensions {
+			return MachineTooltip{m, cv.trace}.Layout(win, gtx)
+		},
+		item:      m,
+		label:     l,
+		shortName: l,
+	}
+	tl.tracks = []*Track{
+		NewTrack(tl, TrackKindUnspecified),
+		NewTrack(tl, TrackKindUnspecified),
+	}
+
+	for i, spans := range [...][]ptrace.Span{m.Spans, m.Goroutines} {
+		track := tl.tracks[i]
+		ss := SimpleItems[ptrace.Span, any]{
+			items: spans,
+			container: ItemContainer{
+				Timeline: tl,
+				Track:    track,
+			},
+			subslice: true,
+		}
+		// A thread may not have held any processors, e.g. when it was only ever blocked in syscalls.
+		if len(spans) > 0 {
+			track.Start = spans[0].Start
+			track.End = spans[len(spans)-1].End
+		}
+		track.spans = theme.Immediate[Items[ptrace.Span]](ss)
+	}
+
+	tl.tracks[0].spanLabel = machineProcessorTrackSpanLabel
+	tl.tracks[0].spanColor = singleSpanColor(colorStateProcRunningG)
+	tl.tracks[0].spanTooltip = machineProcessorTrackSpanTooltip
+	tl.tracks[0].spanContextMenu = machineProcessorTrackSpanContextMenu
+
+	tl.tracks[1].spanLabel = machineGoroutineTrackSpanLabel
+	tl.tracks[1].spanTooltip = machineGoroutineTrackSpanTooltip
+	tl.tracks[1].spanContextMenu = machineGoroutineTrackSpanContextMenu
+
+	addRangeTracks(tl, m.Ranges)
+
+	return tl
+}
+
+// machineBounds returns the start of the first and the end of the last span of the machine, including its ranges.
+func machineBounds(m *ptrace.Machine) (start, end exptrace.Time) {
+	first := true
+	add := func(spans []ptrace.Span) {
+		if len(spans) == 0 {
+			return
+		}
+		if first || spans[0].Start < start {
+			start = spans[0].Start
+		}
+		if first || spans[len(spans)-1].End > end {
+			end = spans[len(spans)-1].End
+		}
+		first = false
+	}
+	add(m.Spans)
+	add(m.Goroutines)
+	for _, spans := range m.Ranges {
+		add(spans)
+	}
+	return start, end
+}
@@ -108,6 +108,9 @@ var (
 	memprofileExit     string
 	traceFile          string
 	disableCaching     bool
+	cacheTraces        bool
+	followTrace        bool
+	patternsFile       string
 	exitAfterLoading   bool
 	exitAfterParsing   bool
 	measureFrameAllocs bool
@@ -265,16 +268,170 @@ func (mwin *MainWindow) openPanelWindow(p Panel) {
 
 }
 
+// analysisUnavailable reports whether analyses can't be opened, telling the user why. Analyses read the trace from
+// other goroutines, but the trace we're following gets updated on the UI goroutine.
+func (mwin *MainWindow) analysisUnavailable() bool {
+	if mwin.following != nil {
+		mwin.showNotification("Analyses aren't available while following a trace")
+		return true
+	}
+	return false
+}
+
 func (mwin *MainWindow) openHeatmap() {
+	if mwin.analysisUnavailable() {
+		return
+	}
 	c := NewHeatmapComponent(mwin.trace)
 	mwin.openTab(Tab{Component: c})
 }
 
 func (mwin *MainWindow) openFlameGraph(g *ptrace.Goroutine) {
+	if mwin.analysisUnavailable() {
+		return
+	}
 	c := NewFlameGraphComponent(mwin.twin, mwin.trace.Trace, g)
 	mwin.openTab(Tab{Component: c})
 }
 
+func (mwin *MainWindow) openOffCPUFlameGraph() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	c := NewOffCPUFlameGraphComponent(mwin.twin, mwin.trace.Trace)
+	mwin.openTab(Tab{Component: c})
+}
+
+func (mwin *MainWindow) openLeaks() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewLeaksComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openUnblockGraph() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewUnblockGraphComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openUnblockings(e *ptrace.UnblockEdge) {
+	label := local.Sprintf("%s unblocked by %s", unblockFunctionName(e.To), unblockFunctionName(e.From))
+	cfg := SpansInfoConfig{
+		Title:         label,
+		Label:         label,
+		ShowHistogram: true,
+	}
+	spans := unblockingSpans(e, mwin.canvas.itemToTimeline)
+	mwin.openPanel(NewSpansInfo(cfg, mwin.trace, mwin.twin, theme.Immediate(spans), mwin.canvas.timelines))
+}
+
+func (mwin *MainWindow) openContention() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewContentionComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openContentionWaits(waits []ptrace.ContentionWait, title string) {
+	cfg := SpansInfoConfig{
+		Title:         title,
+		Label:         title,
+		ShowHistogram: true,
+	}
+	spans := contentionSpans(waits, mwin.canvas.itemToTimeline)
+	mwin.openPanel(NewSpansInfo(cfg, mwin.trace, mwin.twin, theme.Immediate(spans), mwin.canvas.timelines))
+}
+
+func (mwin *MainWindow) openNetwork() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewNetworkComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openNetworkWaits(waits []ptrace.NetworkWait, title string) {
+	cfg := SpansInfoConfig{
+		Title:         title,
+		Label:         title,
+		ShowHistogram: true,
+	}
+	spans := networkSpans(waits, mwin.canvas.itemToTimeline)
+	mwin.openPanel(NewSpansInfo(cfg, mwin.trace, mwin.twin, theme.Immediate(spans), mwin.canvas.timelines))
+}
+
+func (mwin *MainWindow) openSyscalls() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewSyscallComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openSyscallCalls(calls []ptrace.Syscall, title string) {
+	cfg := SpansInfoConfig{
+		Title:         title,
+		Label:         title,
+		ShowHistogram: true,
+	}
+	spans := syscallSpans(calls, mwin.canvas.itemToTimeline)
+	mwin.openPanel(NewSpansInfo(cfg, mwin.trace, mwin.twin, theme.Immediate(spans), mwin.canvas.timelines))
+}
+
+func (mwin *MainWindow) openGCCycles() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewGCCyclesComponent(mwin.twin, mwin.trace)})
+}
+
+func (mwin *MainWindow) openMetrics() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewMetricsComponent(mwin.trace, mwin.plotConfigs)})
+}
+
+// setPlots replaces the user's plots, both on the canvas and in the user's configuration directory.
+func (mwin *MainWindow) setPlots(plots []PlotConfig) {
+	mwin.plotConfigs = plots
+	mwin.canvas.customPlots = newCustomPlots(plots, mwin.trace.Trace)
+	if err := savePlotConfigs(plots); err != nil {
+		mwin.showNotification(fmt.Sprintf("Couldn't save plots: %s", err))
+	}
+}
+
+func (mwin *MainWindow) openSearch() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	mwin.openTab(Tab{Component: NewSearchComponent(mwin.trace, mwin.canvas.timelines, mwin.canvas.itemToTimeline)})
+}
+
+func (mwin *MainWindow) openCriticalPath(g *ptrace.Goroutine, start, end exptrace.Time, label string) {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	c := NewCriticalPathComponent(mwin.twin, mwin.trace, mwin.canvas.itemToTimeline, g, start, end, label)
+	mwin.openTab(Tab{Component: c})
+}
+
+func (mwin *MainWindow) openSchedulingLatency(fn *ptrace.Function) {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	c := NewSchedulingLatencyComponent(mwin.twin, mwin.trace, mwin.canvas.itemToTimeline, fn)
+	mwin.openTab(Tab{Component: c})
+}
+
+func (mwin *MainWindow) openAllocationFlameGraph() {
+	if mwin.analysisUnavailable() {
+		return
+	}
+	c := NewAllocationFlameGraphComponent(mwin.twin, mwin.trace.Trace)
+	mwin.openTab(Tab{Component: c})
+}
+
 func (mwin *MainWindow) openTab(tab Tab) {
 	mwin.tabs = append(mwin.tabs, tab)
 	mwin.tabbedState.Current = len(mwin.tabs) - 1
@@ -343,6 +500,12 @@ type MainWindow struct {
 	progressStages []string
 	err            error
 
+	// The trace we're following, if any. Only accessed from the UI goroutine.
+	following *followState
+
+	// The plots the user created from runtime metrics.
+	plotConfigs []PlotConfig
+
 	debugWindow *DebugWindow
 }
 
@@ -434,6 +597,7 @@ func (mwin *MainWindow) SetProgressStage(idx int) {
 
 func (mwin *MainWindow) LoadTrace(res loadTraceResult) {
 	mwin.twin.EmitAction(theme.ExecuteAction(func(gtx layout.Context) {
+		mwin.stopFollowing()
 		mwin.loadTraceImpl(res)
 		mwin.setState("main")
 	}))
@@ -470,8 +634,15 @@ func PlainLabel(s string) func() string { return func() string { return s } }
 
 type MainMenu struct {
 	File struct {
-		OpenTrace theme.MenuItem
-		Quit      theme.MenuItem
+		OpenTrace         theme.MenuItem
+		OpenComparison    theme.MenuItem
+		ExportChrome      theme.MenuItem
+		ExportChromeRange theme.MenuItem
+		ExportCPU         theme.MenuItem
+		ExportCPURange    theme.MenuItem
+		ExportOffCPU      theme.MenuItem
+		ExportOffCPURange theme.MenuItem
+		Quit              theme.MenuItem
 	}
 
 	Display struct {
@@ -487,8 +658,19 @@ type MainMenu struct {
 	}
 
 	Analyze struct {
-		OpenHeatmap    theme.MenuItem
-		OpenFlameGraph theme.MenuItem
+		OpenHeatmap              theme.MenuItem
+		OpenFlameGraph           theme.MenuItem
+		OpenAllocationFlameGraph theme.MenuItem
+		OpenOffCPUFlameGraph     theme.MenuItem
+		OpenSchedulingLatency    theme.MenuItem
+		OpenUnblockGraph         theme.MenuItem
+		OpenLeaks                theme.MenuItem
+		OpenContention           theme.MenuItem
+		OpenNetwork              theme.MenuItem
+		OpenSyscalls             theme.MenuItem
+		OpenGCCycles             theme.MenuItem
+		OpenMetrics              theme.MenuItem
+		OpenSearch               theme.MenuItem
 	}
 
 	Debug struct {
@@ -505,9 +687,22 @@ func NewMainMenu(mwin *MainWindow, win *theme.Window) *MainMenu {
 	m := &MainMenu{}
 
 	m.File.OpenTrace = theme.MenuItem{Shortcut: key.ModShortcut.String() + "+O", Label: PlainLabel("Open trace")}
+	notMainDisabled := func() bool { return mwin.state != "main" }
+	followingDisabled := func() bool {
+		// Analyses read the trace from different goroutines, which isn't safe while the trace is still being
+		// updated.
+		return mwin.state != "main" || mwin.following != nil
+	}
+	m.File.OpenComparison = theme.MenuItem{Label: PlainLabel("Compare with trace…"), Disabled: followingDisabled}
+	m.File.ExportChrome = theme.MenuItem{Label: PlainLabel("Export as Chrome trace…"), Disabled: followingDisabled}
+	m.File.ExportChromeRange = theme.MenuItem{Label: PlainLabel("Export visible range as Chrome trace…"), Disabled: followingDisabled}
+	noCPUSamplesDisabled := func() bool { return followingDisabled() || len(mwin.trace.CPUSamples) == 0 }
+	m.File.ExportCPU = theme.MenuItem{Label: PlainLabel("Export CPU profile…"), Disabled: noCPUSamplesDisabled}
+	m.File.ExportCPURange = theme.MenuItem{Label: PlainLabel("Export CPU profile of visible range…"), Disabled: noCPUSamplesDisabled}
+	m.File.ExportOffCPU = theme.MenuItem{Label: PlainLabel("Export off-CPU profile…"), Disabled: followingDisabled}
+	m.File.ExportOffCPURange = theme.MenuItem{Label: PlainLabel("Export off-CPU profile of visible range…"), Disabled: followingDisabled}
 	m.File.Quit = theme.MenuItem{Shortcut: key.ModShortcut.String() + "+Q", Label: PlainLabel("Quit")}
 
-	notMainDisabled := func() bool { return mwin.state != "main" }
 	m.Display.UndoNavigation = theme.MenuItem{Shortcut: key.ModShortcut.String() + "+Z", Label: PlainLabel("Undo previous navigation"), Disabled: notMainDisabled}
 	m.Display.RedoNavigation = theme.MenuItem{Shortcut: key.ModShortcut.String() + "+Y", Label: PlainLabel("Redo navigation"), Disabled: notMainDisabled}
 	m.Display.ScrollToTop = theme.MenuItem{Shortcut: "Home", Label: PlainLabel("Scroll to top of canvas"), Disabled: notMainDisabled}
@@ -529,8 +724,21 @@ func NewMainMenu(mwin *MainWindow, win *theme.Window) *MainMenu {
 	m.Debug.GC = theme.MenuItem{Label: PlainLabel("Force garbage collection")}
 	m.Debug.FreeOSMemory = theme.MenuItem{Label: PlainLabel("Force garbage collection & return unused memory to OS")}
 
-	m.Analyze.OpenHeatmap = theme.MenuItem{Label: PlainLabel("Open processor utilization heatmap"), Disabled: notMainDisabled}
-	m.Analyze.OpenFlameGraph = theme.MenuItem{Label: PlainLabel("Open flame graph"), Disabled: notMainDisabled}
+	m.Analyze.OpenHeatmap = theme.MenuItem{Label: PlainLabel("Open processor utilization heatmap"), Disabled: followingDisabled}
+	m.Analyze.OpenFlameGraph = theme.MenuItem{Label: PlainLabel("Open flame graph"), Disabled: followingDisabled}
+	m.Analyze.OpenOffCPUFlameGraph = theme.MenuItem{Label: PlainLabel("Open off-CPU flame graph"), Disabled: followingDisabled}
+	m.Analyze.OpenSchedulingLatency = theme.MenuItem{Label: PlainLabel("Open scheduling latency analysis"), Disabled: followingDisabled}
+	m.Analyze.OpenUnblockGraph = theme.MenuItem{Label: PlainLabel("Open wake-up graph"), Disabled: followingDisabled}
+	m.Analyze.OpenLeaks = theme.MenuItem{Label: PlainLabel("Find leaked goroutines"), Disabled: followingDisabled}
+	m.Analyze.OpenContention = theme.MenuItem{Label: PlainLabel("Open sync contention report"), Disabled: followingDisabled}
+	m.Analyze.OpenNetwork = theme.MenuItem{Label: PlainLabel("Open network wait analysis"), Disabled: followingDisabled}
+	m.Analyze.OpenSyscalls = theme.MenuItem{Label: PlainLabel("Open syscall analysis"), Disabled: followingDisabled}
+	m.Analyze.OpenGCCycles = theme.MenuItem{Label: PlainLabel("Open GC cycles"), Disabled: followingDisabled}
+	m.Analyze.OpenMetrics = theme.MenuItem{Label: PlainLabel("Open metrics browser"), Disabled: followingDisabled}
+	m.Analyze.OpenSearch = theme.MenuItem{Shortcut: key.ModShortcut.String() + "+F", Label: PlainLabel("Search trace…"), Disabled: followingDisabled}
+	m.Analyze.OpenAllocationFlameGraph = theme.MenuItem{Label: PlainLabel("Open allocation flame graph"), Disabled: func() bool {
+		return followingDisabled() || len(mwin.trace.Allocations.Events) == 0
+	}}
 
 	m.menu = &theme.Menu{
 		Groups: []theme.MenuGroup{
@@ -538,6 +746,15 @@ func NewMainMenu(mwin *MainWindow, win *theme.Window) *MainMenu {
 				Label: "File",
 				Items: []theme.Widget{
 					theme.NewMenuItemStyle(win.Theme, &m.File.OpenTrace).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.OpenComparison).Layout,
+					theme.MenuDivider(win.Theme).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportChrome).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportChromeRange).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportCPU).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportCPURange).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportOffCPU).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.File.ExportOffCPURange).Layout,
+					theme.MenuDivider(win.Theme).Layout,
 					theme.NewMenuItemStyle(win.Theme, &m.File.Quit).Layout,
 				},
 			},
@@ -572,6 +789,17 @@ func NewMainMenu(mwin *MainWindow, win *theme.Window) *MainMenu {
 				Items: []theme.Widget{
 					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenHeatmap).Layout,
 					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenFlameGraph).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenOffCPUFlameGraph).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenSchedulingLatency).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenUnblockGraph).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenLeaks).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenContention).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenNetwork).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenSyscalls).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenGCCycles).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenMetrics).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenSearch).Layout,
+					theme.NewMenuItemStyle(win.Theme, &m.Analyze.OpenAllocationFlameGraph).Layout,
 				},
 			},
 		},
@@ -709,6 +937,50 @@ func (mwin *MainWindow) Run() error {
 					win.Menu.Close()
 					mwin.openFlameGraph(nil)
 				}
+				if mwin.mainMenu.Analyze.OpenOffCPUFlameGraph.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openOffCPUFlameGraph()
+				}
+				if mwin.mainMenu.Analyze.OpenSchedulingLatency.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openSchedulingLatency(nil)
+				}
+				if mwin.mainMenu.Analyze.OpenUnblockGraph.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openUnblockGraph()
+				}
+				if mwin.mainMenu.Analyze.OpenLeaks.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openLeaks()
+				}
+				if mwin.mainMenu.Analyze.OpenContention.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openContention()
+				}
+				if mwin.mainMenu.Analyze.OpenNetwork.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openNetwork()
+				}
+				if mwin.mainMenu.Analyze.OpenSyscalls.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openSyscalls()
+				}
+				if mwin.mainMenu.Analyze.OpenGCCycles.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openGCCycles()
+				}
+				if mwin.mainMenu.Analyze.OpenMetrics.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openMetrics()
+				}
+				if mwin.mainMenu.Analyze.OpenSearch.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openSearch()
+				}
+				if mwin.mainMenu.Analyze.OpenAllocationFlameGraph.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.openAllocationFlameGraph()
+				}
 				if mwin.mainMenu.Debug.Cpuprofile.Clicked(gtx) {
 					win.Menu.Close()
 					if mwin.cpuProfile != nil {
@@ -780,6 +1052,34 @@ func (mwin *MainWindow) Run() error {
 					win.Menu.Close()
 					mwin.showFileOpenDialog()
 				}
+				if mwin.mainMenu.File.OpenComparison.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.showComparisonFileOpenDialog()
+				}
+				if mwin.mainMenu.File.ExportChrome.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportChromeTrace(0, 0)
+				}
+				if mwin.mainMenu.File.ExportChromeRange.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportChromeTrace(mwin.canvas.start, mwin.canvas.End())
+				}
+				if mwin.mainMenu.File.ExportCPU.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportProfile(profileCPU, nil, 0, 0)
+				}
+				if mwin.mainMenu.File.ExportCPURange.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportProfile(profileCPU, nil, mwin.canvas.start, mwin.canvas.End())
+				}
+				if mwin.mainMenu.File.ExportOffCPU.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportProfile(profileOffCPU, nil, 0, 0)
+				}
+				if mwin.mainMenu.File.ExportOffCPURange.Clicked(gtx) {
+					win.Menu.Close()
+					mwin.exportProfile(profileOffCPU, nil, mwin.canvas.start, mwin.canvas.End())
+				}
 
 				for _, ev := range gtx.Events(profileTag) {
 					// Yup, profile.Event only contains a string. No structured access to data.
@@ -958,6 +1258,7 @@ func (mwin *MainWindow) renderLoadingTraceScene(win *theme.Window, gtx layout.Co
 func (mwin *MainWindow) renderMainScene(win *theme.Window, gtx layout.Context, shortcuts []theme.Shortcut) layout.Dimensions {
 	win.AddShortcut(theme.Shortcut{Name: "G"})
 	win.AddShortcut(theme.Shortcut{Name: "H"})
+	win.AddShortcut(theme.Shortcut{Modifiers: key.ModShortcut, Name: "F"})
 
 	for _, s := range shortcuts {
 		switch s {
@@ -968,6 +1269,9 @@ func (mwin *MainWindow) renderMainScene(win *theme.Window, gtx layout.Context, s
 
 		case theme.Shortcut{Name: "H"}:
 			displayHighlightSpansDialog(win, &mwin.canvas.timeline.filter)
+
+		case theme.Shortcut{Modifiers: key.ModShortcut, Name: "F"}:
+			mwin.openSearch()
 		}
 	}
 
@@ -1119,16 +1423,50 @@ func (mwin *MainWindow) showFileOpenDialog() {
 	}
 }
 
-func (mwin *MainWindow) loadTraceImpl(res loadTraceResult) {
+// showFileSaveDialog lets the user choose a file to save to, suggesting name, and calls write with the file from a
+// different goroutine.
+func (mwin *MainWindow) showFileSaveDialog(name string, write func(w io.Writer) error) {
+	if mwin.showingExplorer.CompareAndSwap(false, true) {
+		go func() {
+			wc, err := mwin.explorer.CreateFile(name)
+			mwin.showingExplorer.Store(false)
+			if err != nil {
+				switch err {
+				case explorer.ErrUserDecline:
+					return
+				case explorer.ErrNotAvailable:
+					err = errors.New("opening file system dialogs isn't supported on this system")
+				}
+				mwin.showNotification(fmt.Sprintf("Couldn't save file: %s", err))
+				return
+			}
+			err = write(wc)
+			if cerr := wc.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				mwin.showNotification(fmt.Sprintf("Couldn't save file: %s", err))
+			}
+		}()
+	}
+}
+
+func (mwin *MainWindow) loadCanvas(res loadTraceResult) {
 	NewCanvasInto(&mwin.canvas, mwin.debugWindow, res.trace)
 	mwin.canvas.memoryGraph = res.plot
 	mwin.canvas.goroutineGraph = res.goroutinePlot
+	mwin.canvas.allocationGraph = res.allocationPlot
+	mwin.canvas.customPlots = newCustomPlots(mwin.plotConfigs, res.trace.Trace)
 	mwin.canvas.timelines = append(mwin.canvas.timelines, res.timelines...)
 
 	for _, tl := range res.timelines {
 		assert(tl.item != nil, "unexpected nil item")
 		mwin.canvas.itemToTimeline[tl.item] = tl
 	}
+}
+
+func (mwin *MainWindow) loadTraceImpl(res loadTraceResult) {
+	mwin.loadCanvas(res)
 
 	mwin.trace = res.trace
 	mwin.panel = nil
@@ -1296,13 +1634,21 @@ func openTraceFromCmdline(mwin *MainWindow) {
 	mwin.SetState("loadingTrace")
 	go func() {
 		defer f.Close()
-		mwin.OpenTrace(f)
+		if followTrace {
+			mwin.FollowTrace(f)
+		} else {
+			mwin.OpenTrace(f)
+		}
 	}()
 }
 
 func usage(name string, fs *flag.FlagSet) func() {
 	return func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [trace file]\n", name)
+		fmt.Fprintf(os.Stderr, "       %s report [flags] <trace file>\n", name)
+		fmt.Fprintf(os.Stderr, "       %s validate [flags] <trace file>\n", name)
+		fmt.Fprintf(os.Stderr, "       %s chrome [flags] <trace file>\n", name)
+		fmt.Fprintf(os.Stderr, "       %s pprof [flags] <trace file>\n", name)
 
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Flags:")
@@ -1367,6 +1713,14 @@ func isZeroValue(f *flag.Flag, value string) bool {
 }
 
 func main() {
+	if ok, err := runSubcommand(os.Args[1:]); ok {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.Usage = usage("gotraceui", flag.CommandLine)
 	flag.BoolVar(&softDebug, "debug", debug, "Enable basic debug functionality")
 	flag.StringVar(&cpuprofile, "debug.cpuprofile", "", "write CPU profile to this file")
@@ -1378,6 +1732,9 @@ func main() {
 	flag.BoolVar(&exitAfterParsing, "debug.exit-after-parsing", false, "Exit after parsing trace")
 	flag.BoolVar(&measureFrameAllocs, "debug.measure-frame-allocs", false, "Measure the number of allocations per frame")
 	flag.BoolVar(&invalidateFrames, "debug.invalidate-frames", false, "Invalidate frame after drawing it")
+	flag.BoolVar(&cacheTraces, "cache", true, "Cache processed traces on disk to speed up opening them again")
+	flag.BoolVar(&followTrace, "follow", false, "Keep reading the trace as it grows, for traces that are still being written")
+	flag.StringVar(&patternsFile, "patterns", "", "Load span patterns from this file instead of the user's configuration directory")
 	fv := flag.Bool("version", false, "Print version and exit")
 	fdv := flag.Bool("debug.version", false, "Print extended version information and exit")
 	flag.Parse()
@@ -1390,6 +1747,11 @@ func main() {
 		return
 	}
 
+	if err := loadUserPatterns(patternsFile); err != nil {
+		fmt.Fprintln(os.Stderr, "couldn't load patterns:", err)
+		os.Exit(1)
+	}
+
 	go func() {
 		if cpuprofile != "" {
 			f, err := os.Create(cpuprofile)
@@ -1410,6 +1772,11 @@ func main() {
 	}()
 
 	mwin := NewMainWindow()
+	if plots, err := loadPlotConfigs(); err != nil {
+		fmt.Fprintln(os.Stderr, "couldn't load plots:", err)
+	} else {
+		mwin.plotConfigs = plots
+	}
 	mwin.win = app.NewWindow(app.Title("gotraceui"))
 	mwin.twin = theme.NewWindow(mwin.win)
 	mwin.explorer = explorer.NewExplorer(mwin.win)
@@ -1455,7 +1822,9 @@ type loadTraceResult struct {
 	trace         *Trace
 	plot          Plot
 	goroutinePlot Plot
-	timelines     []*Timeline
+	// The allocation plot is only displayed if the trace contains allocation events.
+	allocationPlot Plot
+	timelines      []*Timeline
 }
 
 type progresser interface {
@@ -1480,6 +1849,23 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 	p.SetProgressStages(names)
 
 	p.SetProgressStage(0)
+	var (
+		cacheKey []byte
+		cache    *ptrace.Cache
+	)
+	if rs, ok := f.(io.ReadSeeker); ok && cacheTraces {
+		// Caching is best effort. If we can't hash the trace or read the cache, we fall back to parsing the trace.
+		if key, err := hashTrace(rs, p.SetProgress); err == nil {
+			cacheKey = key
+			if c, err := readTraceCache(key); err == nil {
+				cache = c
+			} else if !os.IsNotExist(err) {
+				log.Printf("couldn't use cached trace: %s", err)
+			}
+		} else {
+			log.Printf("couldn't hash trace: %s", err)
+		}
+	}
 	r, err := exptrace.NewReader(f)
 	if err != nil {
 
@@ -1487,7 +1873,17 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 	}
 
 	p.SetProgressStage(1)
-	pt, err := ptrace.Parse(r, p.SetProgress)
+	var pt *ptrace.Trace
+	if cache != nil {
+		pt, err = ptrace.ParseWithCache(r, cache, p.SetProgress)
+	} else {
+		pt, err = ptrace.Parse(r, p.SetProgress)
+		if err == nil && cacheKey != nil {
+			if err := writeTraceCache(cacheKey, pt); err != nil {
+				log.Printf("couldn't cache trace: %s", err)
+			}
+		}
+	}
 	if err != nil {
 		return loadTraceResult{}, err
 	}
@@ -1495,6 +1891,15 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 		return loadTraceResult{}, errExitAfterParsing
 	}
 
+	return processTrace(&Trace{Trace: pt}, p, cv, nil), nil
+}
+
+// processTrace computes the UI's view of a processed trace, such as timelines and plots. It reports progress starting
+// at stage 2. When following a trace, it gets called again on the same *Trace each time the trace has been updated,
+// with reuse containing the existing timelines of goroutines, processors, machines and tasks that haven't changed.
+func processTrace(tr *Trace, p progresser, cv *Canvas, reuse map[any]*Timeline) loadTraceResult {
+	pt := tr.Trace
+
 	p.SetProgressStage(2)
 	// Assign GC tag to all GC spans so we can later determine their span colors cheaply.
 	for i, proc := range pt.Processors {
@@ -1520,7 +1925,6 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 	}
 
 	p.SetProgressStage(3)
-	tr := &Trace{Trace: pt}
 	if len(pt.Goroutines) != 0 {
 		tr.allGoroutineSpanLabels = make([][]string, len(pt.Goroutines))
 
@@ -1556,22 +1960,38 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 		}
 	}
 
-	timelines := make([]*Timeline, len(tr.Processors)+len(tr.Goroutines)+len(tr.Tasks))
+	timelines := make([]*Timeline, len(tr.Processors)+len(tr.Machines)+len(tr.Goroutines)+len(tr.Tasks))
 
 	p.SetProgressStage(5)
-
-	p.SetProgressStage(6)
 	for i, proc := range tr.Processors {
-		timelines[i] = NewProcessorTimeline(tr, cv, proc)
+		if tl, ok := reuse[proc]; ok {
+			timelines[i] = tl
+		} else {
+			timelines[i] = NewProcessorTimeline(tr, cv, proc)
+		}
 		p.SetProgress(float64(i+1) / float64(len(tr.Processors)))
 	}
 
+	p.SetProgressStage(6)
+	for i, m := range tr.Machines {
+		if tl, ok := reuse[m]; ok {
+			timelines[len(tr.Processors)+i] = tl
+		} else {
+			timelines[len(tr.Processors)+i] = NewMachineTimeline(tr, cv, m)
+		}
+		p.SetProgress(float64(i+1) / float64(len(tr.Machines)))
+	}
+
 	p.SetProgressStage(7)
 	goroutineTimelines := make([]*Timeline, len(tr.Goroutines))
 	var progress atomic.Uint64
 	syncutil.Distribute(tr.Goroutines, 0, func(group int, step int, subitems []*ptrace.Goroutine) error {
 		for j, g := range subitems {
-			goroutineTimelines[group*step+j] = NewGoroutineTimeline(tr, cv, g)
+			if tl, ok := reuse[g]; ok {
+				goroutineTimelines[group*step+j] = tl
+			} else {
+				goroutineTimelines[group*step+j] = NewGoroutineTimeline(tr, cv, g)
+			}
 			pr := progress.Add(1)
 			p.SetProgress(float64(pr) / float64(len(tr.Goroutines)))
 		}
@@ -1583,7 +2003,11 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 	progress.Store(0)
 	syncutil.Distribute(tr.Tasks, 0, func(group int, step int, subitems []*ptrace.Task) error {
 		for j, t := range subitems {
-			taskTimelines[group*step+j] = NewTaskTimeline(tr, cv, t)
+			if tl, ok := reuse[t]; ok {
+				taskTimelines[group*step+j] = tl
+			} else {
+				taskTimelines[group*step+j] = NewTaskTimeline(tr, cv, t)
+			}
 			pr := progress.Add(1)
 			p.SetProgress(float64(pr) / float64(len(tr.Tasks)))
 		}
@@ -1601,7 +2025,7 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 		return taskI.ID < taskJ.ID
 	})
 
-	mergeTimelines(goroutineTimelines, taskTimelines, timelines[len(pt.Processors):], tr)
+	mergeTimelines(goroutineTimelines, taskTimelines, timelines[len(pt.Processors)+len(pt.Machines):], tr)
 
 	mg := Plot{
 		Name: "Memory usage",
@@ -1647,6 +2071,25 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 		},
 	)
 
+	ag := Plot{
+		Name: "Allocation rate",
+		Unit: "bytes/s",
+	}
+	ag.AddSeries(
+		PlotSeries{
+			Name:   "Allocated",
+			Metric: pt.Metrics["/gotraceui/heap/objects/allocated:bytes-per-second"],
+			Style:  PlotStaircase,
+			Color:  oklch(70.59, 0.102, 139.64),
+		},
+		PlotSeries{
+			Name:   "Freed",
+			Metric: pt.Metrics["/gotraceui/heap/objects/freed:bytes-per-second"],
+			Style:  PlotStaircase,
+			Color:  colors[colorStateBlockedGC],
+		},
+	)
+
 	var goroot, gopath string
 	for _, fn := range tr.Functions {
 		if strings.HasPrefix(fn.Func, "runtime.") && strings.Count(fn.Func, ".") == 1 && strings.Contains(fn.File, filepath.Join("go", "src", "runtime")) && !strings.ContainsRune(fn.Func, os.PathSeparator) {
@@ -1692,11 +2135,12 @@ func loadTrace(f io.Reader, p progresser, cv *Canvas) (loadTraceResult, error) {
 	tr.TimeOffset = -tr.Start()
 
 	return loadTraceResult{
-		trace:         tr,
-		plot:          mg,
-		goroutinePlot: gg,
-		timelines:     timelines,
-	}, nil
+		trace:          tr,
+		plot:           mg,
+		goroutinePlot:  gg,
+		allocationPlot: ag,
+		timelines:      timelines,
+	}
 }
 
 func mergeTimelines(a, b, out []*Timeline, tr *Trace) {
@@ -1747,6 +2191,70 @@ func (desc Description) Layout(win *theme.Window, gtx layout.Context, txt *Text)
 	return txt.Layout(win, gtx, tb.Spans), tb.Spans
 }
 
+// layoutAnalysis lays out a component that displays the results of an analysis. It fills the component's background
+// and lays out w inset by 5 pixels.
+func layoutAnalysis(win *theme.Window, gtx layout.Context, w theme.Widget) layout.Dimensions {
+	defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+	theme.Fill(win, gtx.Ops, win.Theme.Palette.Background)
+	size := gtx.Constraints.Min
+
+	// Inset of 5 pixels on all sides. We can't use layout.Inset because it doesn't decrease the minimum constraint,
+	// which we do care about here.
+	gtx.Constraints.Min = gtx.Constraints.Min.Sub(image.Pt(2*5, 2*5))
+	gtx.Constraints.Max = gtx.Constraints.Max.Sub(image.Pt(2*5, 2*5))
+	gtx.Constraints = layout.Normalize(gtx.Constraints)
+	defer op.Offset(image.Pt(5, 5)).Push(gtx.Ops).Pop()
+	w(win, gtx)
+
+	return layout.Dimensions{Size: size}
+}
+
+// AnalysisView lays out the results of an analysis as a description followed by tabs.
+type AnalysisView struct {
+	// An optional widget to lay out above the description, such as buttons.
+	Header          layout.Widget
+	Description     Description
+	DescriptionText *Text
+	// If not nil, gets set to the spans of the description, for handling clicks on links in the next frame.
+	PrevSpans *[]TextSpan
+
+	Tabs        []string
+	TabbedState *theme.TabbedState
+	// Content lays out the contents of the current tab. Without tabs, it lays out everything below the description,
+	// with tab set to the empty string.
+	Content func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions
+}
+
+func (av AnalysisView) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	var children []layout.Widget
+	if av.Header != nil {
+		children = append(children, av.Header, layout.Spacer{Height: 10}.Layout)
+	}
+	children = append(children,
+		func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = image.Point{}
+			dims, spans := av.Description.Layout(win, gtx, av.DescriptionText)
+			if av.PrevSpans != nil {
+				*av.PrevSpans = spans
+			}
+			return dims
+		},
+
+		layout.Spacer{Height: 10}.Layout,
+		func(gtx layout.Context) layout.Dimensions {
+			if len(av.Tabs) == 0 {
+				gtx.Constraints.Min = gtx.Constraints.Max
+				return av.Content(win, gtx, "")
+			}
+			return theme.Tabbed(av.TabbedState, av.Tabs).Layout(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min = gtx.Constraints.Max
+				return av.Content(win, gtx, av.Tabs[av.TabbedState.Current])
+			})
+		},
+	)
+	return layout.Rigids(gtx, layout.Vertical, children...)
+}
+
 type ScrollToTimelineCommand struct {
 	MainWindow *theme.Window
 	Timeline   *Timeline
@@ -1768,6 +2276,10 @@ func (cmd ScrollToTimelineCommand) Layout(win *theme.Window, gtx layout.Context,
 		numSpans = item.Spans.Len()
 		start = item.Spans.AtPtr(0).Start
 		end = LastItemPtr(item.Spans).End
+	case *Range:
+		numSpans = item.Spans.Len()
+		start = item.Spans.AtPtr(0).Start
+		end = LastItemPtr(item.Spans).End
 	case *ptrace.Goroutine:
 		numSpans = len(item.Spans)
 		start = item.EffectiveStart()
@@ -1776,6 +2288,9 @@ func (cmd ScrollToTimelineCommand) Layout(win *theme.Window, gtx layout.Context,
 		numSpans = len(item.Spans)
 		start = item.Spans[0].Start
 		end = item.Spans[len(item.Spans)-1].End
+	case *ptrace.Machine:
+		numSpans = len(item.Spans) + len(item.Goroutines)
+		start, end = machineBounds(item)
 	case *ptrace.Task:
 		numSpans = len(item.Spans)
 		start = item.Spans[0].Start
@@ -1834,6 +2349,22 @@ func (cmd ScrollToTimelineCommand) Filter(input string) bool {
 					}
 				}
 			}
+			if strings.HasPrefix(f, "m") {
+				if f == "m:" {
+					if _, ok := cmd.Timeline.item.(*ptrace.Machine); ok {
+						return true
+					}
+				} else {
+					id := strings.ReplaceAll(f[len("m"):], ",", "")
+					if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+						if m, ok := cmd.Timeline.item.(*ptrace.Machine); ok {
+							if m.ID == exptrace.ThreadID(n) {
+								return true
+							}
+						}
+					}
+				}
+			}
 
 			// OPT(dh): don't repeatedly lowercase the label
 			if strings.Contains(strings.ToLower(cmd.Timeline.label), strings.ToLower(f)) {
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"os"
+	"path/filepath"
+	rtrace "runtime/trace"
+	"slices"
+	"strings"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/color"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+)
+
+// PlotConfig describes a plot the user created from runtime metrics. The configurations of all such plots are
+// stored in the user's configuration directory, so that they're shown for every trace.
+type PlotConfig struct {
+	Name   string
+	Unit   string
+	Series []PlotSeriesConfig
+}
+
+// Derivation describes how a series is derived from metrics.
+type Derivation string
+
+const (
+	// The series displays the metric as is.
+	DerivationNone                 Derivation = ""
+	DerivationRate                 Derivation = "rate"
+	DerivationDifference           Derivation = "difference"
+	DerivationRatio                Derivation = "ratio"
+	DerivationMovingAverage        Derivation = "moving-average"
+	DerivationProcessorUtilization Derivation = "processor-utilization"
+)
+
+// movingAverageWindows are the windows users can choose from for moving averages.
+var movingAverageWindows = []time.Duration{
+	time.Millisecond,
+	defaultMovingAverageWindow,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// defaultMovingAverageWindow is the window of new moving averages, and of configured ones that lack a valid window.
+const defaultMovingAverageWindow = 10 * time.Millisecond
+
+type PlotSeriesConfig struct {
+	// The name of the metric, such as "/gc/heap/goal:bytes". It is empty for series that are computed from the trace
+	// itself, such as processor utilization.
+	Metric     string
+	Derivation Derivation `json:",omitempty"`
+	// The second operand of differences and ratios.
+	Other string `json:",omitempty"`
+	// The window of moving averages.
+	Window time.Duration `json:",omitempty"`
+	Style  PlotStyle
+	Color  color.Oklch
+}
+
+func (cfg *PlotSeriesConfig) Source() MetricSource {
+	switch cfg.Derivation {
+	case DerivationRate:
+		return MetricRate{Source: NamedMetric(cfg.Metric)}
+	case DerivationDifference:
+		return MetricDifference{A: NamedMetric(cfg.Metric), B: NamedMetric(cfg.Other)}
+	case DerivationRatio:
+		return MetricRatio{A: NamedMetric(cfg.Metric), B: NamedMetric(cfg.Other), Scale: 100}
+	case DerivationMovingAverage:
+		return MovingAverage{Source: NamedMetric(cfg.Metric), Window: cfg.Window}
+	case DerivationProcessorUtilization:
+		return ProcessorUtilization{}
+	default:
+		return NamedMetric(cfg.Metric)
+	}
+}
+
+// Unit returns the unit of the series' values.
+func (cfg *PlotSeriesConfig) Unit() string {
+	_, unit := splitMetricName(cfg.Metric)
+	switch cfg.Derivation {
+	case DerivationRate:
+		return unit + "/s"
+	case DerivationRatio, DerivationProcessorUtilization:
+		return "%"
+	default:
+		return unit
+	}
+}
+
+// plotPalette are the colors users can choose from for the series of their plots.
+var plotPalette = []color.Oklch{
+	oklch(70.59, 0.102, 139.64),
+	colors[colorStateBlockedGC],
+	colors[colorStateReady],
+	colors[colorStateActive],
+	colors[colorStateBlocked],
+	colors[colorStateBlockedNet],
+	colors[colorStateBlockedSyscall],
+	colors[colorStateGC],
+}
+
+var plotStyleNames = map[PlotStyle]string{
+	0:                          "Line",
+	PlotStaircase:              "Staircase",
+	PlotFilled:                 "Filled",
+	PlotFilled | PlotStaircase: "Filled staircase",
+}
+
+// nextPlotStyle returns the style that follows s when the user cycles through styles.
+func nextPlotStyle(s PlotStyle) PlotStyle {
+	switch s {
+	case 0:
+		return PlotStaircase
+	case PlotStaircase:
+		return PlotFilled
+	case PlotFilled:
+		return PlotFilled | PlotStaircase
+	default:
+		return 0
+	}
+}
+
+// nextPlotColor returns the color of the palette that follows c when the user cycles through colors.
+func nextPlotColor(c color.Oklch) color.Oklch {
+	i := slices.Index(plotPalette, c)
+	return plotPalette[(i+1)%len(plotPalette)]
+}
+
+// splitMetricName splits a runtime metric name, such as "/gc/heap/goal:bytes", into its name and unit.
+func splitMetricName(metric string) (name, unit string) {
+	name, unit, _ = strings.Cut(metric, ":")
+	return name, unit
+}
+
+// clonePlotConfigs returns a deep copy of the configurations.
+func clonePlotConfigs(plots []PlotConfig) []PlotConfig {
+	out := slices.Clone(plots)
+	for i := range out {
+		out[i].Series = slices.Clone(out[i].Series)
+	}
+	return out
+}
+
+func plotConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gotraceui", "plots.json"), nil
+}
+
+// loadPlotConfigs loads the user's plots from the file "gotraceui/plots.json" in the user's configuration directory,
+// if it exists.
+func loadPlotConfigs() ([]PlotConfig, error) {
+	path, err := plotConfigPath()
+	if err != nil {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var plots []PlotConfig
+	if err := json.Unmarshal(b, &plots); err != nil {
+		return nil, err
+	}
+	for i := range plots {
+		for j := range plots[i].Series {
+			s := &plots[i].Series[j]
+			if s.Derivation == DerivationMovingAverage && s.Window <= 0 {
+				s.Window = defaultMovingAverageWindow
+			}
+		}
+	}
+	return plots, nil
+}
+
+func savePlotConfigs(plots []PlotConfig) error {
+	path, err := plotConfigPath()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(plots, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// newCustomPlots creates the plots described by the configurations. Their series are computed lazily. Metrics that
+// don't exist in the trace result in empty series.
+func newCustomPlots(cfgs []PlotConfig, tr *ptrace.Trace) []Plot {
+	plots := make([]Plot, len(cfgs))
+	for i, cfg := range cfgs {
+		pl := &plots[i]
+		pl.Name = cfg.Name
+		pl.Unit = cfg.Unit
+		series := make([]PlotSeries, len(cfg.Series))
+		for j, s := range cfg.Series {
+			src := s.Source()
+			series[j] = PlotSeries{
+				Name:   src.String(),
+				Source: src,
+				Style:  s.Style,
+				Color:  s.Color,
+			}
+		}
+		pl.AddSeries(series...)
+	}
+	return plots
+}
+
+type metricInfo struct {
+	Name     string
+	Unit     string
+	Samples  int
+	Min, Max uint64
+}
+
+// plotRow is a row in the table of plots, representing a single series of a plot.
+type plotRow struct {
+	plot, series int
+
+	style, color, option, up, down, remove widget.PrimaryClickable
+}
+
+// MetricsComponent lists the runtime metrics recorded in the trace and lets the user add them as plots above the
+// timelines, as well as change and remove existing plots.
+type MetricsComponent struct {
+	trace    *Trace
+	metrics  []metricInfo
+	selected []widget.Bool
+	plots    []PlotConfig
+	rows     []plotRow
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	metricsTable  *theme.Table
+	metricsScroll theme.YScrollableListState
+	plotsTable    *theme.Table
+	plotsScroll   theme.YScrollableListState
+	cellFormatter CellFormatter
+
+	buttons struct {
+		addCombined    widget.PrimaryClickable
+		addSeparate    widget.PrimaryClickable
+		addRates       widget.PrimaryClickable
+		addAverages    widget.PrimaryClickable
+		addDifference  widget.PrimaryClickable
+		addRatio       widget.PrimaryClickable
+		addUtilization widget.PrimaryClickable
+	}
+}
+
+func NewMetricsComponent(tr *Trace, plots []PlotConfig) *MetricsComponent {
+	mc := &MetricsComponent{
+		trace: tr,
+		plots: clonePlotConfigs(plots),
+	}
+	for name, m := range tr.Metrics {
+		if len(m.Values) == 0 {
+			continue
+		}
+		_, unit := splitMetricName(name)
+		info := metricInfo{
+			Name:    name,
+			Unit:    unit,
+			Samples: len(m.Values),
+			Min:     slices.Min(m.Values),
+			Max:     slices.Max(m.Values),
+		}
+		mc.metrics = append(mc.metrics, info)
+	}
+	slices.SortFunc(mc.metrics, func(a, b metricInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	mc.selected = make([]widget.Bool, len(mc.metrics))
+	mc.updateRows()
+	return mc
+}
+
+func (mc *MetricsComponent) Title() string {
+	return "Metrics"
+}
+
+func (mc *MetricsComponent) Transition(theme.ComponentState) {
+}
+
+func (mc *MetricsComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (mc *MetricsComponent) updateRows() {
+	mc.rows = mc.rows[:0]
+	for i, pl := range mc.plots {
+		for j := range pl.Series {
+			mc.rows = append(mc.rows, plotRow{plot: i, series: j})
+		}
+	}
+}
+
+// selectedMetrics returns the names of the selected metrics.
+func (mc *MetricsComponent) selectedMetrics() []string {
+	var out []string
+	for i := range mc.selected {
+		if mc.selected[i].Value {
+			out = append(out, mc.metrics[i].Name)
+		}
+	}
+	return out
+}
+
+func (mc *MetricsComponent) clearSelection() {
+	for i := range mc.selected {
+		mc.selected[i].Value = false
+	}
+}
+
+// addPlot adds a plot with the given series, assigning them colors from the palette.
+func (mc *MetricsComponent) addPlot(series ...PlotSeriesConfig) {
+	var names []string
+	unit := ""
+	for i := range series {
+		s := &series[i]
+		s.Color = plotPalette[i%len(plotPalette)]
+		if s.Style == 0 && s.Derivation != DerivationRatio && s.Derivation != DerivationProcessorUtilization {
+			// Runtime metrics change in steps.
+			s.Style = PlotStaircase
+		}
+		names = append(names, s.Source().String())
+		if i == 0 {
+			unit = s.Unit()
+		} else if s.Unit() != unit {
+			unit = ""
+		}
+	}
+	mc.plots = append(mc.plots, PlotConfig{
+		Name:   strings.Join(names, ", "),
+		Unit:   unit,
+		Series: series,
+	})
+}
+
+// addPlots handles clicks on the buttons for adding plots and reports whether plots were added.
+func (mc *MetricsComponent) addPlots(win *theme.Window, gtx layout.Context) bool {
+	n := len(mc.plots)
+	selected := mc.selectedMetrics()
+	each := func(d Derivation, window time.Duration) {
+		for _, m := range selected {
+			mc.addPlot(PlotSeriesConfig{Metric: m, Derivation: d, Window: window})
+		}
+	}
+	pair := func(d Derivation, what string) {
+		if len(selected) != 2 {
+			win.ShowNotification(gtx, "Select exactly two metrics to plot their "+what)
+			return
+		}
+		mc.addPlot(PlotSeriesConfig{Metric: selected[0], Other: selected[1], Derivation: d})
+	}
+
+	for mc.buttons.addCombined.Clicked(gtx) {
+		if len(selected) > 0 {
+			series := make([]PlotSeriesConfig, len(selected))
+			for i, m := range selected {
+				series[i] = PlotSeriesConfig{Metric: m}
+			}
+			mc.addPlot(series...)
+		}
+	}
+	for mc.buttons.addSeparate.Clicked(gtx) {
+		each(DerivationNone, 0)
+	}
+	for mc.buttons.addRates.Clicked(gtx) {
+		each(DerivationRate, 0)
+	}
+	for mc.buttons.addAverages.Clicked(gtx) {
+		each(DerivationMovingAverage, defaultMovingAverageWindow)
+	}
+	for mc.buttons.addDifference.Clicked(gtx) {
+		pair(DerivationDifference, "difference")
+	}
+	for mc.buttons.addRatio.Clicked(gtx) {
+		pair(DerivationRatio, "ratio")
+	}
+	for mc.buttons.addUtilization.Clicked(gtx) {
+		mc.addPlot(PlotSeriesConfig{Derivation: DerivationProcessorUtilization})
+	}
+
+	if len(mc.plots) == n {
+		return false
+	}
+	mc.clearSelection()
+	return true
+}
+
+// update handles clicks on the rows of the table of plots and reports whether the plots changed.
+func (mc *MetricsComponent) update(gtx layout.Context) bool {
+	changed := false
+	for i := range mc.rows {
+		row := &mc.rows[i]
+		s := &mc.plots[row.plot].Series[row.series]
+		for row.style.Clicked(gtx) {
+			s.Style = nextPlotStyle(s.Style)
+			changed = true
+		}
+		for row.color.Clicked(gtx) {
+			s.Color = nextPlotColor(s.Color)
+			changed = true
+		}
+		for row.option.Clicked(gtx) {
+			switch s.Derivation {
+			case DerivationDifference, DerivationRatio:
+				s.Metric, s.Other = s.Other, s.Metric
+			case DerivationMovingAverage:
+				i := slices.Index(movingAverageWindows, s.Window)
+				s.Window = movingAverageWindows[(i+1)%len(movingAverageWindows)]
+			}
+			changed = true
+		}
+	}
+
+	// Structural changes invalidate the rows, so we handle at most one of them per frame.
+	for i := range mc.rows {
+		row := &mc.rows[i]
+		switch {
+		case row.up.Clicked(gtx) && row.plot > 0:
+			mc.plots[row.plot-1], mc.plots[row.plot] = mc.plots[row.plot], mc.plots[row.plot-1]
+		case row.down.Clicked(gtx) && row.plot < len(mc.plots)-1:
+			mc.plots[row.plot+1], mc.plots[row.plot] = mc.plots[row.plot], mc.plots[row.plot+1]
+		case row.remove.Clicked(gtx):
+			pl := &mc.plots[row.plot]
+			pl.Series = slices.Delete(pl.Series, row.series, row.series+1)
+			if len(pl.Series) == 0 {
+				mc.plots = slices.Delete(mc.plots, row.plot, row.plot+1)
+			}
+		default:
+			continue
+		}
+		mc.updateRows()
+		return true
+	}
+	return changed
+}
+
+func (mc *MetricsComponent) buildDescription(win *theme.Window) Description {
+	tb := TextBuilder{Window: win}
+	attrs := []DescriptionAttribute{
+		{Key: "Metrics", Value: *tb.Span(local.Sprintf("%d", len(mc.metrics)))},
+		{Key: "Custom plots", Value: *tb.Span(local.Sprintf("%d", len(mc.plots)))},
+	}
+	if path, err := plotConfigPath(); err == nil {
+		attrs = append(attrs, DescriptionAttribute{Key: "Note", Value: *tb.Span("Custom plots are shown for all traces and are saved in " + path + ".")})
+	}
+	return Description{Attributes: attrs}
+}
+
+func (mc *MetricsComponent) layoutMetrics(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	if mc.metricsTable == nil {
+		mc.metricsTable = &theme.Table{}
+		mc.metricsTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Metric", Alignment: text.Start},
+			{Name: "Unit", Alignment: text.Start},
+			{Name: "Samples", Alignment: text.End},
+			{Name: "Min", Alignment: text.End},
+			{Name: "Max", Alignment: text.End},
+		})
+	}
+	mc.metricsTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		m := &mc.metrics[row]
+		switch colName := mc.metricsTable.Columns[col].Name; colName {
+		case "Metric":
+			name, _ := splitMetricName(m.Name)
+			style := theme.CheckBox(win.Theme, &mc.selected[row], name)
+			style.TextSize = 12
+			return style.Layout(win, gtx)
+		case "Unit":
+			return mc.cellFormatter.Text(win, gtx, m.Unit)
+		case "Samples":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return mc.cellFormatter.Number(win, gtx, m.Samples)
+			})
+		case "Min":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return mc.cellFormatter.Text(win, gtx, local.Sprintf("%d", m.Min))
+			})
+		case "Max":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return mc.cellFormatter.Text(win, gtx, local.Sprintf("%d", m.Max))
+			})
+		default:
+			panic(colName)
+		}
+	}
+
+	return layout.Rigids(gtx, layout.Vertical,
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.Rigids(gtx, layout.Horizontal,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addCombined.Clickable, "Add selected metrics as one plot").Layout),
+				layout.Spacer{Width: 5}.Layout,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addSeparate.Clickable, "Add selected metrics as separate plots").Layout),
+				layout.Spacer{Width: 5}.Layout,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addUtilization.Clickable, "Add processor utilization").Layout),
+			)
+		},
+		layout.Spacer{Height: 5}.Layout,
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.Rigids(gtx, layout.Horizontal,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addRates.Clickable, "Add rates").Layout),
+				layout.Spacer{Width: 5}.Layout,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addAverages.Clickable, "Add moving averages").Layout),
+				layout.Spacer{Width: 5}.Layout,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addDifference.Clickable, "Add difference").Layout),
+				layout.Spacer{Width: 5}.Layout,
+				theme.Dumb(win, theme.Button(win.Theme, &mc.buttons.addRatio.Clickable, "Add ratio").Layout),
+			)
+		},
+		layout.Spacer{Height: 5}.Layout,
+		func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = gtx.Constraints.Max
+			return theme.SimpleTable(win, gtx, mc.metricsTable, &mc.metricsScroll, len(mc.metrics), cellFn)
+		},
+	)
+}
+
+func (mc *MetricsComponent) layoutPlots(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	if len(mc.plots) == 0 {
+		return theme.Label(win.Theme, "There are no custom plots. Add them in the \"Metrics\" tab.").Layout(win, gtx)
+	}
+
+	if mc.plotsTable == nil {
+		mc.plotsTable = &theme.Table{}
+		mc.plotsTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Plot", Alignment: text.Start},
+			{Name: "Series", Alignment: text.Start},
+			{Name: "Options", Alignment: text.Start},
+			{Name: "Style", Alignment: text.Start},
+			{Name: "Color", Alignment: text.Start},
+			{Name: "Actions", Alignment: text.Start},
+		})
+	}
+	mc.plotsTable.Update(gtx)
+
+	link := func(gtx layout.Context, clk *widget.PrimaryClickable, label string) layout.Dimensions {
+		return clk.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{MaxLines: 1}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, label, win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+		})
+	}
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		r := &mc.rows[row]
+		pl := &mc.plots[r.plot]
+		s := &pl.Series[r.series]
+		switch colName := mc.plotsTable.Columns[col].Name; colName {
+		case "Plot":
+			if r.series != 0 {
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}
+			return mc.cellFormatter.Text(win, gtx, local.Sprintf("%d: %s", r.plot+1, pl.Name))
+		case "Series":
+			return mc.cellFormatter.Text(win, gtx, s.Source().String())
+		case "Options":
+			switch s.Derivation {
+			case DerivationDifference, DerivationRatio:
+				return link(gtx, &r.option, "Swap operands")
+			case DerivationMovingAverage:
+				return link(gtx, &r.option, local.Sprintf("Window: %s", s.Window))
+			default:
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}
+		case "Style":
+			return link(gtx, &r.style, plotStyleNames[s.Style])
+		case "Color":
+			return r.color.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				size := gtx.Dp(12)
+				theme.FillShape(win, gtx.Ops, s.Color, clip.Rect{Max: image.Pt(size, size)}.Op())
+				return layout.Dimensions{Size: image.Pt(size, size)}
+			})
+		case "Actions":
+			remove := func(gtx layout.Context) layout.Dimensions { return link(gtx, &r.remove, "Remove") }
+			if r.series != 0 {
+				// Plots can only be moved as a whole, via their first row.
+				return remove(gtx)
+			}
+			return layout.Rigids(gtx, layout.Horizontal,
+				func(gtx layout.Context) layout.Dimensions { return link(gtx, &r.up, "Move up") },
+				layout.Spacer{Width: 10}.Layout,
+				func(gtx layout.Context) layout.Dimensions { return link(gtx, &r.down, "Move down") },
+				layout.Spacer{Width: 10}.Layout,
+				remove,
+			)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, mc.plotsTable, &mc.plotsScroll, len(mc.rows), cellFn)
+}
+
+func (mc *MetricsComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.MetricsComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		changed := mc.update(gtx)
+		if mc.addPlots(win, gtx) {
+			changed = true
+			mc.tabbedState.Current = 1
+		}
+		if changed {
+			mc.updateRows()
+			win.EmitAction(&SetPlotsAction{Plots: clonePlotConfigs(mc.plots)})
+		}
+
+		mc.cellFormatter.Update(win, gtx)
+		for _, ev := range mc.descriptionText.Update(gtx, mc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     mc.buildDescription(win),
+			DescriptionText: &mc.descriptionText,
+			PrevSpans:       &mc.prevSpans,
+			Tabs:            []string{"Metrics", "Plots"},
+			TabbedState:     &mc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "Metrics":
+					return mc.layoutMetrics(win, gtx)
+				case "Plots":
+					return mc.layoutPlots(win, gtx)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
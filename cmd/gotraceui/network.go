@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	rtrace "runtime/trace"
+	"strings"
+	"time"
+
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+)
+
+// networkCategoryLabel describes the tags of a network category, such as "read, network, TCP".
+func networkCategoryLabel(tags ptrace.SpanTags) string {
+	if l := spanTagStrings(tags); len(l) > 0 {
+		return strings.Join(l, ", ")
+	}
+	return "untagged"
+}
+
+// NetworkSite is a place that blocked on the network, for one category of network waits.
+type NetworkSite struct {
+	*ptrace.NetworkSite
+	// The function containing the site, or nil if the trace didn't record stacks.
+	Function *ptrace.Function
+	Location string
+}
+
+// NetworkAnalysis describes the time goroutines spent blocked on the network.
+type NetworkAnalysis struct {
+	*ptrace.NetworkWaits
+	Sites []*NetworkSite
+}
+
+func NewNetworkAnalysis(tr *ptrace.Trace) *NetworkAnalysis {
+	a := &NetworkAnalysis{NetworkWaits: ptrace.ComputeNetworkWaits(tr)}
+	a.Sites = make([]*NetworkSite, len(a.NetworkWaits.Sites))
+	for i, ns := range a.NetworkWaits.Sites {
+		site := &NetworkSite{NetworkSite: ns, Location: "<unknown>"}
+		if ns.PC != 0 {
+			frame := tr.PCs[ns.PC]
+			site.Function = tr.Functions[frame.Func]
+			site.Location = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		a.Sites[i] = site
+	}
+	return a
+}
+
+// NetworkComponent displays the time goroutines spent blocked on the network, aggregated by the tags of the spans and
+// by where they blocked.
+type NetworkComponent struct {
+	trace    *Trace
+	analysis *theme.Future[*NetworkAnalysis]
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	categoriesTable  *theme.Table
+	categoriesScroll theme.YScrollableListState
+	sitesTable       *theme.Table
+	sitesScroll      theme.YScrollableListState
+	cellFormatter    CellFormatter
+
+	chart           ConcurrencyChart
+	histTabbedState theme.TabbedState
+	histLabels      []string
+	hists           []InteractiveHistogram
+	initialized     bool
+}
+
+func NewNetworkComponent(win *theme.Window, tr *Trace) *NetworkComponent {
+	return &NetworkComponent{
+		trace: tr,
+		analysis: theme.NewFuture(win, func(cancelled <-chan struct{}) *NetworkAnalysis {
+			return NewNetworkAnalysis(tr.Trace)
+		}),
+	}
+}
+
+func (nc *NetworkComponent) Title() string {
+	return "Network waits"
+}
+
+func (nc *NetworkComponent) Transition(theme.ComponentState) {
+}
+
+func (nc *NetworkComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (nc *NetworkComponent) buildDescription(win *theme.Window, a *NetworkAnalysis) Description {
+	tb := TextBuilder{Window: win}
+	var n int
+	for _, cat := range a.Categories {
+		n += len(cat.Waits)
+	}
+	attrs := []DescriptionAttribute{
+		{Key: "Waits", Value: *tb.Span(local.Sprintf("%d", n))},
+		{Key: "Time spent waiting", Value: *tb.Span(roundDuration(a.Total).String())},
+		{Key: "Categories", Value: *tb.Span(local.Sprintf("%d", len(a.Categories)))},
+	}
+	if peak, ts := a.Concurrency.Peak(); peak > 0 {
+		attrs = append(attrs, DescriptionAttribute{
+			Key:   "Peak concurrent waits",
+			Value: *tb.DefaultLink(local.Sprintf("%d", peak), "Peak of concurrent network waits", ts),
+		})
+	}
+	return Description{Attributes: attrs}
+}
+
+// waitsLink lays out a link that opens the spans of a list of network waits.
+func (nc *NetworkComponent) waitsLink(win *theme.Window, gtx layout.Context, waits []ptrace.NetworkWait, title string) layout.Dimensions {
+	return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+		link := nc.cellFormatter.Clicks.Grow()
+		link.Link = &NetworkWaitsObjectLink{Waits: waits, Title: title}
+		return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{
+				MaxLines:  1,
+				Alignment: text.Start,
+			}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, local.Sprintf("%d", len(waits)), win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+		})
+	})
+}
+
+func (nc *NetworkComponent) layoutCategories(win *theme.Window, gtx layout.Context, a *NetworkAnalysis) layout.Dimensions {
+	if nc.categoriesTable == nil {
+		nc.categoriesTable = &theme.Table{}
+		nc.categoriesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Category", Alignment: text.Start},
+			{Name: "Waits", Alignment: text.End},
+			{Name: "Total", Alignment: text.End},
+			{Name: "Mean", Alignment: text.End},
+			{Name: "P50", Alignment: text.End},
+			{Name: "P99", Alignment: text.End},
+			{Name: "Max", Alignment: text.End},
+		})
+	}
+	nc.categoriesTable.Update(gtx)
+
+	percentile := func(cat *ptrace.NetworkCategory, p float64) time.Duration {
+		return cat.Durations[int(float64(len(cat.Durations)-1)*p)]
+	}
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		cat := a.Categories[row]
+		switch colName := nc.categoriesTable.Columns[col].Name; colName {
+		case "Category":
+			return nc.cellFormatter.Text(win, gtx, networkCategoryLabel(cat.Tags))
+		case "Waits":
+			return nc.waitsLink(win, gtx, cat.Waits, local.Sprintf("Network waits (%s)", networkCategoryLabel(cat.Tags)))
+		case "Total":
+			return nc.cellFormatter.Duration(win, gtx, cat.Total, false)
+		case "Mean":
+			return nc.cellFormatter.Duration(win, gtx, cat.Total/time.Duration(len(cat.Waits)), false)
+		case "P50":
+			return nc.cellFormatter.Duration(win, gtx, percentile(cat, 0.5), false)
+		case "P99":
+			return nc.cellFormatter.Duration(win, gtx, percentile(cat, 0.99), false)
+		case "Max":
+			return nc.cellFormatter.Duration(win, gtx, percentile(cat, 1), false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, nc.categoriesTable, &nc.categoriesScroll, len(a.Categories), cellFn)
+}
+
+func (nc *NetworkComponent) layoutSites(win *theme.Window, gtx layout.Context, a *NetworkAnalysis) layout.Dimensions {
+	if nc.sitesTable == nil {
+		nc.sitesTable = &theme.Table{}
+		nc.sitesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Category", Alignment: text.Start},
+			{Name: "Function", Alignment: text.Start},
+			{Name: "Location", Alignment: text.Start},
+			{Name: "Waits", Alignment: text.End},
+			{Name: "Total", Alignment: text.End},
+			{Name: "Mean", Alignment: text.End},
+			{Name: "Max", Alignment: text.End},
+		})
+	}
+	nc.sitesTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		site := a.Sites[row]
+		switch colName := nc.sitesTable.Columns[col].Name; colName {
+		case "Category":
+			return nc.cellFormatter.Text(win, gtx, networkCategoryLabel(site.Category.Tags))
+		case "Function":
+			return nc.cellFormatter.Function(win, gtx, site.Function)
+		case "Location":
+			return nc.cellFormatter.Text(win, gtx, site.Location)
+		case "Waits":
+			return nc.waitsLink(win, gtx, site.Waits, local.Sprintf("Network waits in %s", site.Location))
+		case "Total":
+			return nc.cellFormatter.Duration(win, gtx, site.Total, false)
+		case "Mean":
+			return nc.cellFormatter.Duration(win, gtx, site.Total/time.Duration(len(site.Waits)), false)
+		case "Max":
+			return nc.cellFormatter.Duration(win, gtx, site.Max, false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, nc.sitesTable, &nc.sitesScroll, len(a.Sites), cellFn)
+}
+
+func (nc *NetworkComponent) layoutHistograms(win *theme.Window, gtx layout.Context, a *NetworkAnalysis) layout.Dimensions {
+	if len(a.Categories) == 0 {
+		return theme.Label(win.Theme, "The trace contains no network waits.").Layout(win, gtx)
+	}
+	return theme.Tabbed(&nc.histTabbedState, nc.histLabels).Layout(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min = gtx.Constraints.Max
+		return nc.hists[nc.histTabbedState.Current].Layout(win, gtx)
+	})
+}
+
+func (nc *NetworkComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.NetworkComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		a, ok := nc.analysis.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		if !nc.initialized {
+			nc.initialized = true
+			nc.chart = ConcurrencyChart{
+				Metric: a.Concurrency,
+				Start:  nc.trace.Start(),
+				End:    nc.trace.End(),
+				Unit:   "goroutines",
+				Color:  colors[colorStateBlockedNet],
+			}
+			nc.histLabels = make([]string, len(a.Categories))
+			nc.hists = make([]InteractiveHistogram, len(a.Categories))
+			for i, cat := range a.Categories {
+				nc.histLabels[i] = networkCategoryLabel(cat.Tags)
+				nc.hists[i].Config = widget.HistogramConfig{RejectOutliers: true, Bins: widget.DefaultHistogramBins}
+				nc.hists[i].Set(win, cat.Durations)
+			}
+		}
+		for i := range nc.hists {
+			if nc.hists[i].Update(gtx) {
+				nc.hists[i].Set(win, a.Categories[i].Durations)
+			}
+		}
+		nc.cellFormatter.Update(win, gtx)
+		for _, ev := range nc.descriptionText.Update(gtx, nc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     nc.buildDescription(win, a),
+			DescriptionText: &nc.descriptionText,
+			PrevSpans:       &nc.prevSpans,
+			Tabs:            []string{"By category", "By call site", "Concurrent waits", "Histograms"},
+			TabbedState:     &nc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "By category":
+					return nc.layoutCategories(win, gtx, a)
+				case "By call site":
+					return nc.layoutSites(win, gtx, a)
+				case "Concurrent waits":
+					return nc.chart.Layout(win, gtx)
+				case "Histograms":
+					return nc.layoutHistograms(win, gtx, a)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// networkSpans returns the spans of a list of network waits.
+func networkSpans(waits []ptrace.NetworkWait, timelines map[any]*Timeline) Items[ptrace.Span] {
+	return goroutineSpans(func(yield func(*ptrace.Goroutine, int) bool) {
+		for _, w := range waits {
+			if !yield(w.Goroutine, w.Span) {
+				return
+			}
+		}
+	}, timelines)
+}
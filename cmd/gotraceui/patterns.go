@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+)
+
+// userPatternsSource is the source of the patterns loaded by loadUserPatterns. Patterns affect the processing of
+// traces, which is why they're part of the key of cached traces.
+var userPatternsSource []byte
+
+// loadUserPatterns loads span patterns from path, in addition to ptrace's built-in patterns. If path is empty, patterns
+// are loaded from the file "gotraceui/patterns" in the user's configuration directory, if it exists.
+func loadUserPatterns(path string) error {
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, "gotraceui", "patterns")
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	ps, err := ptrace.ParsePatterns(path, src)
+	if err != nil {
+		return err
+	}
+	ptrace.AddPatterns(ps)
+	userPatternsSource = src
+	return nil
+}
@@ -7,17 +7,20 @@ import (
 	"iter"
 	"math"
 	rtrace "runtime/trace"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"honnef.co/go/curve"
+	myclip "honnef.co/go/gotraceui/clip"
 	"honnef.co/go/gotraceui/color"
 	"honnef.co/go/gotraceui/gesture"
 	"honnef.co/go/gotraceui/layout"
 	"honnef.co/go/gotraceui/mem"
 	"honnef.co/go/gotraceui/theme"
 	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/stuff/math/math32"
 	"honnef.co/go/stuff/math/mathutil"
 
 	"gioui.org/f32"
@@ -46,6 +49,9 @@ const (
 type PlotSeries struct {
 	Name   string
 	Metric ptrace.Metric
+	// If set, Source computes the points of the series, replacing Metric. Sources are computed lazily, in the
+	// background, when the plot is first displayed.
+	Source MetricSource
 	Style  PlotStyle
 	Color  color.Oklch
 
@@ -70,6 +76,9 @@ type Plot struct {
 	hideLegends bool
 	autoScale   bool
 
+	// The series with computed sources, if we're computing them.
+	computedSeries *theme.Future[[]PlotSeries]
+
 	prevFrame struct {
 		constraints layout.Constraints
 		hideLegends bool
@@ -83,31 +92,36 @@ type Plot struct {
 	}
 }
 
+func (s *PlotSeries) decimate() {
+	points := s.Metric
+	if len(points.Timestamps) == 0 {
+		return
+	}
+	// nsPerPx is an approximation of Canvas.nsPerPx. Similarly,
+	// points.Timestamps[0] is an approximation of Canvas.start. This
+	// approximation is acceptable because the cached decimation is already
+	// used at multiple zoom levels, which means it was never perfectly
+	// pixel-aligned to begin with.
+	duration := float64(points.Timestamps[len(points.Timestamps)-1] - points.Timestamps[0])
+	nsPerPx := duration / float64(zoom1Pixels)
+	// TODO make pixel count proportional to length of trace (or number of samples?)
+	indices := downsample(points, points.Timestamps[0], zoom1Pixels, nsPerPx, nil)
+	decimation := ptrace.Metric{
+		Timestamps: make([]exptrace.Time, len(indices)),
+		Values:     make([]uint64, len(indices)),
+	}
+	for i, idx := range indices {
+		decimation.Timestamps[i] = s.Metric.Timestamps[idx]
+		decimation.Values[i] = s.Metric.Values[idx]
+	}
+	s.cachedDecimation = decimation
+}
+
 func (pl *Plot) AddSeries(series ...PlotSeries) {
 	for i := range series {
-		s := &series[i]
-		points := s.Metric
-		if len(points.Timestamps) == 0 {
-			continue
+		if series[i].Source == nil {
+			series[i].decimate()
 		}
-		// nsPerPx is an approximation of Canvas.nsPerPx. Similarly,
-		// points.Timestamps[0] is an approximation of Canvas.start. This
-		// approximation is acceptable because the cached decimation is already
-		// used at multiple zoom levels, which means it was never perfectly
-		// pixel-aligned to begin with.
-		duration := float64(points.Timestamps[len(points.Timestamps)-1] - points.Timestamps[0])
-		nsPerPx := duration / float64(zoom1Pixels)
-		// TODO make pixel count proportional to length of trace (or number of samples?)
-		indices := downsample(points, points.Timestamps[0], zoom1Pixels, nsPerPx, nil)
-		decimation := ptrace.Metric{
-			Timestamps: make([]exptrace.Time, len(indices)),
-			Values:     make([]uint64, len(indices)),
-		}
-		for i, idx := range indices {
-			decimation.Timestamps[i] = s.Metric.Timestamps[idx]
-			decimation.Values[i] = s.Metric.Values[idx]
-		}
-		s.cachedDecimation = decimation
 	}
 	pl.series = append(pl.series, series...)
 	_, max := pl.computeExtents(0, math.MaxInt64)
@@ -115,6 +129,61 @@ func (pl *Plot) AddSeries(series ...PlotSeries) {
 	pl.max = max
 }
 
+// computeSources computes the points of series that have sources. It starts the computation on the first call and
+// updates the series once the computation has finished.
+func (pl *Plot) computeSources(win *theme.Window, tr *ptrace.Trace) {
+	if pl.computedSeries == nil {
+		if !slices.ContainsFunc(pl.series, func(s PlotSeries) bool { return s.Source != nil }) {
+			return
+		}
+		series := slices.Clone(pl.series)
+		pl.computedSeries = theme.NewFuture(win, func(cancelled <-chan struct{}) []PlotSeries {
+			for i := range series {
+				s := &series[i]
+				if s.Source == nil {
+					continue
+				}
+				s.Metric = s.Source.Compute(tr)
+				s.decimate()
+			}
+			return series
+		})
+	}
+
+	series, ok := pl.computedSeries.ResultNoWait()
+	if !ok {
+		return
+	}
+	pl.computedSeries = nil
+	for i := range pl.series {
+		if s := &pl.series[i]; s.Source != nil {
+			s.Metric = series[i].Metric
+			s.cachedDecimation = series[i].cachedDecimation
+			s.Source = nil
+		}
+	}
+	pl.min = 0
+	_, pl.max = pl.computeExtents(0, math.MaxInt64)
+	// Invalidate the cached frame.
+	pl.prevFrame.constraints = layout.Constraints{}
+}
+
+// computeSourcesNow is like computeSources, but computes the series synchronously. It is used while following a
+// trace, which gets updated on the UI goroutine and thus can't be read from other goroutines.
+func (pl *Plot) computeSourcesNow(tr *ptrace.Trace) {
+	for i := range pl.series {
+		s := &pl.series[i]
+		if s.Source == nil {
+			continue
+		}
+		s.Metric = s.Source.Compute(tr)
+		s.decimate()
+		s.Source = nil
+	}
+	pl.min = 0
+	_, pl.max = pl.computeExtents(0, math.MaxInt64)
+}
+
 func (pl *Plot) computeExtents(start, end exptrace.Time) (min, max uint64) {
 	min = math.MaxUint64
 	max = 0
@@ -168,6 +237,8 @@ func (pl *Plot) Layout(win *theme.Window, gtx layout.Context, cv *Canvas) layout
 	defer rtrace.StartRegion(context.Background(), "main.Plot.Layout").End()
 	defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
 
+	pl.computeSources(win, cv.trace.Trace)
+
 	pl.hover.Update(gtx.Queue)
 	pl.click.Add(gtx.Ops)
 	pl.hover.Add(gtx.Ops)
@@ -659,3 +730,124 @@ func curveToGio(p iter.Seq[curve.PathElement], giopath *clip.Path) curve.Point {
 	}
 	return start
 }
+
+// ConcurrencyChart plots a metric, such as the number of goroutines blocked on the network, over a span of time. Each
+// column of pixels shows the highest value during its interval.
+type ConcurrencyChart struct {
+	Metric     ptrace.Metric
+	Start, End exptrace.Time
+	Unit       string
+	Color      color.Oklch
+
+	click gesture.Click
+	hover gesture.Hover
+
+	columns     []uint64
+	cacheSize   image.Point
+	cachedOps   op.Ops
+	cachedMacro op.CallOp
+}
+
+func (cc *ConcurrencyChart) computeColumns(n int) {
+	cc.columns = slices.Grow(cc.columns[:0], n)[:n]
+	nsPerPx := float64(cc.End-cc.Start) / float64(n)
+	m := cc.Metric
+	var idx int
+	var cur uint64
+	for x := range n {
+		end := cc.Start + exptrace.Time(float64(x+1)*nsPerPx)
+		v := cur
+		for idx < len(m.Timestamps) && m.Timestamps[idx] < end {
+			cur = m.Values[idx]
+			v = max(v, cur)
+			idx++
+		}
+		cc.columns[x] = v
+	}
+}
+
+// column returns the interval covered by column x, given the chart's width.
+func (cc *ConcurrencyChart) column(x, width int) (start, end exptrace.Time) {
+	nsPerPx := float64(cc.End-cc.Start) / float64(width)
+	return cc.Start + exptrace.Time(float64(x)*nsPerPx), cc.Start + exptrace.Time(float64(x+1)*nsPerPx)
+}
+
+func (cc *ConcurrencyChart) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.ConcurrencyChart.Layout").End()
+
+	dims := gtx.Constraints.Max
+	if dims.X <= 0 || dims.Y <= 0 || cc.End <= cc.Start {
+		return layout.Dimensions{Size: dims}
+	}
+	defer clip.Rect{Max: dims}.Push(gtx.Ops).Pop()
+
+	cc.hover.Update(gtx.Queue)
+	var clicked bool
+	for _, ev := range cc.click.Update(gtx.Queue) {
+		if ev.Kind == gesture.KindClick && ev.Button == pointer.ButtonPrimary {
+			clicked = true
+		}
+	}
+	cc.click.Add(gtx.Ops)
+	cc.hover.Add(gtx.Ops)
+
+	if cc.cacheSize == dims {
+		cc.cachedMacro.Add(gtx.Ops)
+	} else {
+		cc.cacheSize = dims
+		cc.computeColumns(dims.X)
+		cc.cachedOps.Reset()
+		m := op.Record(&cc.cachedOps)
+
+		// Use a white background, like the heatmap, to improve contrast.
+		theme.Fill(win, &cc.cachedOps, oklch(100, 0, 0))
+
+		peak := slices.Max(cc.columns)
+		if peak > 0 {
+			padding := float32(gtx.Dp(plotPaddingDp))
+			height := float32(dims.Y) - padding
+
+			// Draw all columns as a single path.
+			var p clip.Path
+			p.Begin(&cc.cachedOps)
+			for x, v := range cc.columns {
+				if v == 0 {
+					continue
+				}
+				top := math32.Round(float32(dims.Y) - height*float32(v)/float32(peak))
+				p.MoveTo(f32.Pt(float32(x), top))
+				p.LineTo(f32.Pt(float32(x+1), top))
+				p.LineTo(f32.Pt(float32(x+1), float32(dims.Y)))
+				p.LineTo(f32.Pt(float32(x), float32(dims.Y)))
+				p.Close()
+			}
+			theme.FillShape(win, &cc.cachedOps, cc.Color, clip.Outline{Path: p.End()}.Op())
+		}
+
+		cc.cachedMacro = m.Stop()
+		cc.cachedMacro.Add(gtx.Ops)
+	}
+
+	if cc.click.Hovered() {
+		x := int(cc.hover.Pointer().X)
+		if x >= 0 && x < len(cc.columns) {
+			outline := myclip.RectangularOutline{
+				Rect:  myclip.FRect{Min: f32.Pt(float32(x), 0), Max: f32.Pt(float32(x+1), float32(dims.Y))},
+				Width: float32(gtx.Dp(1)),
+			}.Op(gtx.Ops)
+			theme.FillShape(win, gtx.Ops, oklch(45.201, 0.31321, 264.05203), outline)
+
+			start, end := cc.column(x, dims.X)
+			label := local.Sprintf("[%s, %s): at most %d %s", time.Duration(start-cc.Start), time.Duration(end-cc.Start), cc.columns[x], cc.Unit)
+			win.SetTooltip(func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+				return theme.Tooltip(win.Theme, label).Layout(win, gtx)
+			})
+
+			if clicked {
+				win.EmitAction(ScrollToTimestampAction(start))
+			}
+		}
+	}
+
+	return layout.Dimensions{Size: dims}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"debug/macho"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"honnef.co/go/gotraceui/trace/pprof"
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+var errNoCPUSamples = errors.New("the trace contains no CPU samples")
+
+// CPUProfile returns a CPU profile of the samples of the goroutines in gs, or of all samples if gs is nil, that were
+// taken between start and end. If end is zero, the profile extends to the end of the trace. Samples are labeled with
+// the IDs of their goroutines.
+func CPUProfile(tr *ptrace.Trace, gs []*ptrace.Goroutine, start, end exptrace.Time) *pprof.Profile {
+	if end == 0 {
+		end = tr.End()
+	}
+	start = max(start, tr.Start())
+
+	sampleDuration := int64(cpuSampleDuration(tr))
+	p := pprof.NewProfile(tr.PCs,
+		pprof.ValueType{Type: "samples", Unit: "count"},
+		pprof.ValueType{Type: "cpu", Unit: "nanoseconds"})
+	p.PeriodType = pprof.ValueType{Type: "cpu", Unit: "nanoseconds"}
+	p.Period = sampleDuration
+	p.DurationNanos = int64(end - start)
+
+	add := func(samples []ptrace.EventID) {
+		for _, sample := range samples {
+			ev := tr.Event(sample)
+			if t := ev.Time(); t < start || t > end {
+				continue
+			}
+			pcs := tr.Stacks[ev.Stack()]
+			if len(pcs) == 0 {
+				continue
+			}
+			var labels []pprof.Label
+			if gid := ev.Goroutine(); gid != exptrace.NoGoroutine {
+				labels = []pprof.Label{{Key: "goroutine", Value: strconv.FormatInt(int64(gid), 10)}}
+			}
+			p.AddSample(pcs, []int64{1, sampleDuration}, labels...)
+		}
+	}
+	if gs == nil {
+		add(tr.CPUSamples)
+	} else {
+		for _, g := range gs {
+			add(tr.CPUSamplesByG[g.ID])
+		}
+	}
+	return p
+}
+
+// OffCPUProfile returns a profile of the time that the goroutines in gs, or all goroutines if gs is nil, spent blocked
+// between start and end. If end is zero, the profile extends to the end of the trace. The profile has one sample type
+// per kind of blocking, followed by the total time spent blocked.
+func OffCPUProfile(tr *ptrace.Trace, gs []*ptrace.Goroutine, start, end exptrace.Time) *pprof.Profile {
+	if end == 0 {
+		end = tr.End()
+	}
+	start = max(start, tr.Start())
+
+	var sampleTypes []pprof.ValueType
+	var indices [ptrace.StateLast]int
+	for state, typ := range offCPUSampleTypes {
+		if typ == "" {
+			continue
+		}
+		indices[state] = len(sampleTypes)
+		sampleTypes = append(sampleTypes, pprof.ValueType{Type: typ, Unit: "nanoseconds"})
+	}
+	sampleTypes = append(sampleTypes, pprof.ValueType{Type: "off_cpu", Unit: "nanoseconds"})
+
+	p := pprof.NewProfile(tr.PCs, sampleTypes...)
+	p.DurationNanos = int64(end - start)
+	for _, sample := range ptrace.ComputeOffCPU(tr, gs, start, end) {
+		values := make([]int64, len(sampleTypes))
+		values[indices[sample.State]] = int64(sample.Duration)
+		values[len(values)-1] = int64(sample.Duration)
+		p.AddSample(tr.Stacks[sample.Stack], values)
+	}
+	return p
+}
+
+// binaryStartLines returns a function that looks up the lines on which functions start in the line table of the
+// executable at path, which has to be the one that produced the trace. It supports ELF and Mach-O executables.
+func binaryStartLines(path string) (func(fn string) int64, error) {
+	var pclntab []byte
+	var text uint64
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		sect := f.Section(".gopclntab")
+		if sect == nil {
+			return nil, errors.New("executable has no .gopclntab section")
+		}
+		if pclntab, err = sect.Data(); err != nil {
+			return nil, err
+		}
+		if sect := f.Section(".text"); sect != nil {
+			text = sect.Addr
+		}
+		// The start of the text segment differs from runtime.text in binaries that use cgo.
+		if syms, err := f.Symbols(); err == nil {
+			for _, sym := range syms {
+				if sym.Name == "runtime.text" {
+					text = sym.Value
+					break
+				}
+			}
+		}
+	} else if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		sect := f.Section("__gopclntab")
+		if sect == nil {
+			return nil, errors.New("executable has no __gopclntab section")
+		}
+		if pclntab, err = sect.Data(); err != nil {
+			return nil, err
+		}
+		if sect := f.Section("__text"); sect != nil {
+			text = sect.Addr
+		}
+		if f.Symtab != nil {
+			for _, sym := range f.Symtab.Syms {
+				if sym.Name == "runtime.text" {
+					text = sym.Value
+					break
+				}
+			}
+		}
+	} else {
+		return nil, errors.New("unsupported executable format")
+	}
+
+	tab, err := gosym.NewTable(nil, gosym.NewLineTable(pclntab, text))
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) int64 {
+		// Functions that have been inlined everywhere don't have an entry in the table, and we can't find their
+		// start lines.
+		fn := tab.LookupFunc(name)
+		if fn == nil {
+			return 0
+		}
+		// The function's prologue is attributed to the line of its declaration.
+		_, line, _ := tab.PCToLine(fn.Entry)
+		return int64(line)
+	}, nil
+}
+
+func pprofUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "Usage: gotraceui pprof [flags] <trace file>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Pprof writes a profile that can be used with go tool pprof. With -type cpu, it writes a CPU profile of")
+		fmt.Fprintln(os.Stderr, "the trace's CPU samples, which can also be used for profile-guided optimization. The trace has to have")
+		fmt.Fprintln(os.Stderr, "been recorded with CPU profiling enabled. With -type offcpu, it writes a profile of the time goroutines")
+		fmt.Fprintln(os.Stderr, "spent blocked, with one sample type per kind of blocking.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Traces don't record the lines on which functions start, which profile-guided optimization requires.")
+		fmt.Fprintln(os.Stderr, "Use -binary to read them from the executable that produced the trace.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		printDefaults(fs)
+	}
+}
+
+// runPprof implements the pprof subcommand.
+func runPprof(args []string) error {
+	fs := flag.NewFlagSet("pprof", flag.ExitOnError)
+	fs.Usage = pprofUsage(fs)
+	typ := fs.String("type", "cpu", "The kind of profile to write, either cpu or offcpu")
+	output := fs.String("o", "", "Write the profile to this file instead of stdout")
+	start := fs.Duration("start", 0, "Only include samples taken at or after this offset from the start of the trace")
+	end := fs.Duration("end", 0, "Only include samples taken at or before this offset from the start of the trace")
+	goroutine := fs.Uint64("goroutine", 0, "Only include samples of the goroutine with this ID")
+	function := fs.String("function", "", "Only include samples of goroutines started by this function")
+	binary := fs.String("binary", "", "Read the start lines of functions from this executable")
+	patterns := fs.String("patterns", "", "Load span patterns from this file instead of the user's configuration directory")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var kind profileKind
+	switch *typ {
+	case "cpu":
+		kind = profileCPU
+	case "offcpu":
+		kind = profileOffCPU
+	default:
+		return fmt.Errorf("unknown profile type %q", *typ)
+	}
+
+	if err := loadUserPatterns(*patterns); err != nil {
+		return fmt.Errorf("couldn't load patterns: %w", err)
+	}
+
+	tr, err := parseTraceFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if kind == profileCPU && len(tr.CPUSamples) == 0 {
+		return errNoCPUSamples
+	}
+
+	var gs []*ptrace.Goroutine
+	if *goroutine != 0 {
+		// Trace.G panics for unknown goroutines.
+		i := slices.IndexFunc(tr.Goroutines, func(g *ptrace.Goroutine) bool {
+			return g.ID == exptrace.GoID(*goroutine)
+		})
+		if i == -1 {
+			return fmt.Errorf("the trace contains no goroutine %d", *goroutine)
+		}
+		gs = append(gs, tr.Goroutines[i])
+	}
+	if *function != "" {
+		fn, ok := tr.Functions[*function]
+		if !ok {
+			return fmt.Errorf("the trace contains no goroutines started by function %s", *function)
+		}
+		gs = append(gs, fn.Goroutines...)
+	}
+
+	windowStart := tr.Start() + exptrace.Time(*start)
+	var windowEnd exptrace.Time
+	if *end != 0 {
+		windowEnd = tr.Start() + exptrace.Time(*end)
+	}
+	p := kind.profile(tr, gs, windowStart, windowEnd)
+	if *binary != "" {
+		startLine, err := binaryStartLines(*binary)
+		if err != nil {
+			return fmt.Errorf("couldn't read %s: %w", *binary, err)
+		}
+		p.StartLine = startLine
+	}
+	if *output == "" {
+		return p.Write(os.Stdout)
+	}
+	of, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(of); err != nil {
+		of.Close()
+		return err
+	}
+	return of.Close()
+}
+
+type profileKind uint8
+
+const (
+	profileCPU profileKind = iota
+	profileOffCPU
+)
+
+func (kind profileKind) String() string {
+	switch kind {
+	case profileCPU:
+		return "CPU profile"
+	case profileOffCPU:
+		return "off-CPU profile"
+	default:
+		panic(fmt.Sprintf("unhandled profile kind %d", kind))
+	}
+}
+
+func (kind profileKind) profile(tr *ptrace.Trace, gs []*ptrace.Goroutine, start, end exptrace.Time) *pprof.Profile {
+	switch kind {
+	case profileCPU:
+		return CPUProfile(tr, gs, start, end)
+	case profileOffCPU:
+		return OffCPUProfile(tr, gs, start, end)
+	default:
+		panic(fmt.Sprintf("unhandled profile kind %d", kind))
+	}
+}
+
+// exportProfile lets the user choose a file and writes a profile of the goroutines in gs, or of all goroutines if gs
+// is nil, between start and end to it.
+func (mwin *MainWindow) exportProfile(kind profileKind, gs []*ptrace.Goroutine, start, end exptrace.Time) {
+	if mwin.following != nil {
+		mwin.showNotification(fmt.Sprintf("Couldn't export %s while following a trace", kind))
+		return
+	}
+	tr := mwin.trace.Trace
+	if kind == profileCPU && len(tr.CPUSamples) == 0 {
+		mwin.showNotification("Couldn't export CPU profile: " + errNoCPUSamples.Error())
+		return
+	}
+	p := kind.profile(tr, gs, start, end)
+	if p.Len() == 0 {
+		mwin.showNotification(fmt.Sprintf("Couldn't export %s: there are no samples in the selection", kind))
+		return
+	}
+	name := "cpu.pprof"
+	if kind == profileOffCPU {
+		name = "offcpu.pprof"
+	}
+	mwin.showFileSaveDialog(name, func(w io.Writer) error {
+		if err := p.Write(w); err != nil {
+			return err
+		}
+		mwin.showNotification(local.Sprintf("Exported %s with %d samples", kind, p.Len()))
+		return nil
+	})
+}
@@ -160,6 +160,7 @@ func NewProcessorTimeline(tr *Trace, cv *Canvas, p *ptrace.Processor) *Timeline
 	tl.tracks[0].spanTooltip = processorTrackSpanTooltip
 	tl.tracks[0].spanContextMenu = processorTrackSpanContextMenu
 
+	addRangeTracks(tl, p.Ranges)
 	addStackTracks(tl, p, tr)
 
 	return tl
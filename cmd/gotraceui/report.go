@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// Report is a machine-readable summary of a trace, as emitted by the report subcommand. All durations are in
+// nanoseconds. Start and End are the trace's raw timestamps; all other timestamps are relative to Start.
+type Report struct {
+	Start      exptrace.Time              `json:"start"`
+	End        exptrace.Time              `json:"end"`
+	Duration   time.Duration              `json:"duration"`
+	Goroutines []GoroutineReport          `json:"goroutines"`
+	Functions  []FunctionReport           `json:"functions"`
+	GC         DurationSummary            `json:"gc"`
+	STW        DurationSummary            `json:"stw"`
+	STWReasons map[string]DurationSummary `json:"stw_reasons"`
+	Tasks      TaskReport                 `json:"tasks"`
+}
+
+type GoroutineReport struct {
+	ID       exptrace.GoID `json:"id"`
+	Parent   exptrace.GoID `json:"parent,omitempty"`
+	Function string        `json:"function,omitempty"`
+	// Start and End are only set if the goroutine was created or ended during the trace.
+	Start      *time.Duration             `json:"start,omitempty"`
+	End        *time.Duration             `json:"end,omitempty"`
+	Statistics map[string]ReportStatistic `json:"statistics"`
+}
+
+type FunctionReport struct {
+	Function   string `json:"function"`
+	File       string `json:"file,omitempty"`
+	Line       uint64 `json:"line,omitempty"`
+	Goroutines int    `json:"goroutines"`
+	// TotalTime is the sum of the observed lifetimes of all goroutines running the function.
+	TotalTime  time.Duration              `json:"total_time"`
+	Statistics map[string]ReportStatistic `json:"statistics"`
+}
+
+type TaskReport struct {
+	Count int `json:"count"`
+	// Completed counts the tasks whose end was observed in the trace.
+	Completed int            `json:"completed"`
+	ByName    map[string]int `json:"by_name"`
+}
+
+// ReportStatistic mirrors ptrace.Statistic, using names that are stable for consumers of the JSON output.
+type ReportStatistic struct {
+	Count   int           `json:"count"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+	Total   time.Duration `json:"total"`
+	Average float64       `json:"average"`
+	Median  float64       `json:"median"`
+}
+
+type DurationSummary struct {
+	Count int           `json:"count"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Total time.Duration `json:"total"`
+}
+
+func (s *DurationSummary) add(d time.Duration) {
+	s.Count++
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+	if d < s.Min || s.Count == 1 {
+		s.Min = d
+	}
+}
+
+func reportStatistics(stats *ptrace.Statistics) map[string]ReportStatistic {
+	out := map[string]ReportStatistic{}
+	for state := range stats {
+		stat := &stats[state]
+		if stat.Count == 0 || stateNames[state] == "" {
+			continue
+		}
+		out[stateNames[state]] = ReportStatistic(*stat)
+	}
+	return out
+}
+
+func NewReport(tr *ptrace.Trace) *Report {
+	start := tr.Start()
+	rel := func(ts exptrace.Time) *time.Duration {
+		d := time.Duration(ts - start)
+		return &d
+	}
+
+	rep := &Report{
+		Start:      start,
+		End:        tr.End(),
+		Duration:   tr.Duration(),
+		Goroutines: make([]GoroutineReport, 0, len(tr.Goroutines)),
+		STWReasons: map[string]DurationSummary{},
+		Tasks: TaskReport{
+			ByName: map[string]int{},
+		},
+	}
+
+	for _, g := range tr.Goroutines {
+		stats := ptrace.ComputeStatistics(ptrace.ToSpans(g.Spans))
+		gr := GoroutineReport{
+			ID:         g.ID,
+			Statistics: reportStatistics(&stats),
+		}
+		if g.Parent != exptrace.NoGoroutine {
+			gr.Parent = g.Parent
+		}
+		if g.Function != nil {
+			gr.Function = g.Function.Func
+		}
+		if ts, ok := g.Start.Get(); ok {
+			gr.Start = rel(ts)
+		}
+		if ts, ok := g.End.Get(); ok {
+			gr.End = rel(ts)
+		}
+		rep.Goroutines = append(rep.Goroutines, gr)
+	}
+
+	fns := make([]*ptrace.Function, 0, len(tr.Functions))
+	for _, fn := range tr.Functions {
+		fns = append(fns, fn)
+	}
+	slices.SortFunc(fns, func(a, b *ptrace.Function) int {
+		return cmp(a.SeqID, b.SeqID, false)
+	})
+	rep.Functions = make([]FunctionReport, 0, len(fns))
+	for _, fn := range fns {
+		var (
+			spans []ptrace.Span
+			total time.Duration
+		)
+		for _, g := range fn.Goroutines {
+			spans = append(spans, g.Spans...)
+			total += time.Duration(g.EffectiveEnd() - g.EffectiveStart())
+		}
+		stats := ptrace.ComputeStatistics(ptrace.ToSpans(spans))
+		rep.Functions = append(rep.Functions, FunctionReport{
+			Function:   fn.Func,
+			File:       fn.File,
+			Line:       fn.Line,
+			Goroutines: len(fn.Goroutines),
+			TotalTime:  total,
+			Statistics: reportStatistics(&stats),
+		})
+	}
+
+	for _, s := range tr.GC {
+		rep.GC.add(s.Duration())
+	}
+	for _, s := range tr.STW {
+		rep.STW.add(s.Duration())
+		reason := tr.Event(s.StartEvent).Range().Name
+		sum := rep.STWReasons[reason]
+		sum.add(s.Duration())
+		rep.STWReasons[reason] = sum
+	}
+
+	for _, t := range tr.Tasks {
+		if t.Stub() {
+			// We never saw the task being created, only child tasks referring to it as their parent.
+			continue
+		}
+		rep.Tasks.Count++
+		if t.End.Set() {
+			rep.Tasks.Completed++
+		}
+		rep.Tasks.ByName[t.Name]++
+	}
+
+	return rep
+}
+
+func reportUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "Usage: gotraceui report [flags] <trace file>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Report parses a trace and prints a JSON summary of its goroutines, functions, GC, STW and tasks.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		printDefaults(fs)
+	}
+}
+
+// runReport implements the report subcommand.
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	fs.Usage = reportUsage(fs)
+	output := fs.String("o", "", "Write report to this file instead of stdout")
+	compact := fs.Bool("compact", false, "Don't indent JSON output")
+	patterns := fs.String("patterns", "", "Load span patterns from this file instead of the user's configuration directory")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := loadUserPatterns(*patterns); err != nil {
+		return fmt.Errorf("couldn't load patterns: %w", err)
+	}
+
+	tr, err := parseTraceFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rep := NewReport(tr)
+	if *output == "" {
+		return writeReport(os.Stdout, rep, !*compact)
+	}
+	of, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := writeReport(of, rep, !*compact); err != nil {
+		of.Close()
+		return err
+	}
+	return of.Close()
+}
+
+// parseTraceFile reads and parses the trace in the named file, for subcommands that don't display the trace.
+func parseTraceFile(name string) (*ptrace.Trace, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open trace: %w", err)
+	}
+	defer f.Close()
+
+	r, err := exptrace.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read trace: %w", err)
+	}
+	tr, err := ptrace.Parse(r, func(float64) {})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse trace: %w", err)
+	}
+	return tr, nil
+}
+
+func writeReport(w io.Writer, rep *Report, indent bool) error {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "\t")
+	}
+	return enc.Encode(rep)
+}
+
+// runSubcommand runs the subcommand named by args[0], if there is one, and reports whether it did. An existing regular
+// file takes precedence over a subcommand of the same name, so that traces named, say, "report" can still be opened.
+func runSubcommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	if fi, err := os.Stat(args[0]); err == nil && fi.Mode().IsRegular() {
+		return false, nil
+	}
+	switch args[0] {
+	case "report":
+		return true, runReport(args[1:])
+	case "validate":
+		return true, runValidate(args[1:])
+	case "chrome":
+		return true, runChrome(args[1:])
+	case "pprof":
+		return true, runPprof(args[1:])
+	default:
+		return false, nil
+	}
+}
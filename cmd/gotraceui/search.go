@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/io/key"
+	"gioui.org/text"
+	exptrace "golang.org/x/exp/trace"
+)
+
+type SearchResultKind uint8
+
+const (
+	SearchResultFunction SearchResultKind = iota
+	SearchResultGoroutine
+	SearchResultTask
+	SearchResultRegion
+	SearchResultLog
+	SearchResultSTW
+)
+
+var searchResultKindNames = [...]string{
+	SearchResultFunction:  "Function",
+	SearchResultGoroutine: "Goroutine",
+	SearchResultTask:      "Task",
+	SearchResultRegion:    "Region",
+	SearchResultLog:       "Log message",
+	SearchResultSTW:       "STW",
+}
+
+func (k SearchResultKind) String() string {
+	return searchResultKindNames[k]
+}
+
+// searchSpanRef refers to a span that matched a search.
+type searchSpanRef struct {
+	// The goroutine the span belongs to, or nil for STW pauses.
+	Goroutine *ptrace.Goroutine
+	// The goroutine's track the span belongs to. Zero is the goroutine's own track, i+1 is its i-th user region track.
+	Track int
+	Span  int
+}
+
+// SearchResult is a single result of a search. Matching functions, goroutines, and tasks result in one result each,
+// while matching regions, log messages, and STW pauses are grouped by their names.
+type SearchResult struct {
+	Kind  SearchResultKind
+	Label string
+	// Additional information, such as the function of a goroutine, or the category of a log message.
+	Details string
+	// The number of goroutines, spans, or events that matched.
+	Count int
+
+	Function  *ptrace.Function
+	Goroutine *ptrace.Goroutine
+	Task      *ptrace.Task
+	// The matching regions, the spans of goroutines at the time of matching log messages, or the matching STW pauses.
+	Spans []searchSpanRef
+
+	link ObjectLink
+}
+
+// Link returns the link for opening the result in the panel that already exists for its kind.
+func (r *SearchResult) Link(timelines []*Timeline, itemToTimeline map[any]*Timeline) ObjectLink {
+	if r.link != nil {
+		return r.link
+	}
+	switch r.Kind {
+	case SearchResultFunction:
+		r.link = &FunctionObjectLink{Function: r.Function, Provenance: "search"}
+	case SearchResultGoroutine:
+		r.link = &GoroutineObjectLink{Goroutine: r.Goroutine, Provenance: "search"}
+	case SearchResultTask:
+		r.link = &TaskObjectLink{Task: r.Task, Provenance: "search"}
+	case SearchResultRegion, SearchResultLog, SearchResultSTW:
+		r.link = &SpansObjectLink{Spans: searchSpans(r.Spans, timelines, itemToTimeline)}
+	default:
+		panic(r.Kind)
+	}
+	return r.link
+}
+
+// searchSpans turns span references into items that belong to the timelines' tracks.
+func searchSpans(refs []searchSpanRef, timelines []*Timeline, itemToTimeline map[any]*Timeline) Items[ptrace.Span] {
+	type key struct {
+		g     *ptrace.Goroutine
+		track int
+	}
+	var keys []key
+	byTrack := map[key][]int{}
+	for _, ref := range refs {
+		k := key{ref.Goroutine, ref.Track}
+		if _, ok := byTrack[k]; !ok {
+			keys = append(keys, k)
+		}
+		byTrack[k] = append(byTrack[k], ref.Span)
+	}
+
+	var bases []Items[ptrace.Span]
+	for _, k := range keys {
+		var base Items[ptrace.Span]
+		if k.g == nil {
+			for _, tl := range timelines {
+				if stw, ok := tl.item.(*STW); ok {
+					base = stw.Spans
+					break
+				}
+			}
+		} else if tl := itemToTimeline[k.g]; tl != nil {
+			var spans []ptrace.Span
+			if k.track == 0 {
+				spans = k.g.Spans
+			} else {
+				spans = k.g.UserRegions[k.track-1]
+			}
+			base = SimpleItems[ptrace.Span, any]{
+				items: spans,
+				container: ItemContainer{
+					Timeline: tl,
+					Track:    tl.tracks[k.track],
+				},
+				contiguous: k.track == 0,
+				subslice:   true,
+			}
+		}
+		if base == nil {
+			continue
+		}
+		bases = append(bases, ItemsSubset[ptrace.Span]{
+			Base:   base,
+			Subset: byTrack[k],
+		})
+	}
+	return MergeItems(bases, func(a, b *ptrace.Span) bool {
+		return a.Start < b.Start
+	})
+}
+
+// SearchMatches are the goroutines, tasks, and spans that matched a search, for highlighting them in the timelines.
+type SearchMatches struct {
+	Goroutines map[*ptrace.Goroutine]struct{}
+	Tasks      map[*ptrace.Task]struct{}
+	Spans      map[*ptrace.Span]struct{}
+}
+
+// Match reports whether any of the spans belong to a matching goroutine or task, or are matching spans themselves.
+func (sm *SearchMatches) Match(spans Items[ptrace.Span], container ItemContainer) bool {
+	switch item := container.Timeline.item.(type) {
+	case *ptrace.Goroutine:
+		if _, ok := sm.Goroutines[item]; ok && container.Track.kind == TrackKindUnspecified {
+			return true
+		}
+	case *ptrace.Task:
+		if _, ok := sm.Tasks[item]; ok {
+			return true
+		}
+	}
+	if len(sm.Spans) == 0 {
+		return false
+	}
+	for i := range spans.Len() {
+		if _, ok := sm.Spans[spans.AtPtr(i)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Search finds the functions, goroutines, tasks, user regions, log messages, and STW pauses that match the query.
+// Names are matched case-insensitively by substring, goroutines by their IDs, optionally prefixed with "g". Search
+// returns early, with partial results, if cancelled gets closed.
+func Search(tr *ptrace.Trace, query string, cancelled <-chan struct{}) ([]SearchResult, *SearchMatches) {
+	sm := &SearchMatches{
+		Goroutines: map[*ptrace.Goroutine]struct{}{},
+		Tasks:      map[*ptrace.Task]struct{}{},
+		Spans:      map[*ptrace.Span]struct{}{},
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil, sm
+	}
+	isCancelled := func() bool {
+		select {
+		case <-cancelled:
+			return true
+		default:
+			return false
+		}
+	}
+	// Many regions, log messages, and STW pauses share names, so we cache whether names match.
+	matched := map[string]bool{}
+	matches := func(s string) bool {
+		b, ok := matched[s]
+		if !ok {
+			b = strings.Contains(strings.ToLower(s), q)
+			matched[s] = b
+		}
+		return b
+	}
+
+	var out []SearchResult
+
+	for _, fn := range tr.Functions {
+		if !matches(fn.Func) {
+			continue
+		}
+		out = append(out, SearchResult{
+			Kind:     SearchResultFunction,
+			Label:    fn.Func,
+			Count:    len(fn.Goroutines),
+			Function: fn,
+		})
+		for _, g := range fn.Goroutines {
+			sm.Goroutines[g] = struct{}{}
+		}
+	}
+
+	if id, err := strconv.ParseUint(strings.ReplaceAll(strings.TrimPrefix(q, "g"), ",", ""), 10, 64); err == nil {
+		i := slices.IndexFunc(tr.Goroutines, func(g *ptrace.Goroutine) bool {
+			return g.ID == exptrace.GoID(id)
+		})
+		if i != -1 {
+			g := tr.Goroutines[i]
+			var details string
+			if g.Function != nil {
+				details = g.Function.Func
+			}
+			out = append(out, SearchResult{
+				Kind:      SearchResultGoroutine,
+				Label:     local.Sprintf("goroutine %d", g.ID),
+				Details:   details,
+				Count:     1,
+				Goroutine: g,
+			})
+			sm.Goroutines[g] = struct{}{}
+		}
+	}
+
+	for _, t := range tr.Tasks {
+		if t.Stub() || !matches(t.Name) {
+			continue
+		}
+		out = append(out, SearchResult{
+			Kind:    SearchResultTask,
+			Label:   t.Name,
+			Details: local.Sprintf("task %d", t.ID),
+			Count:   1,
+			Task:    t,
+		})
+		sm.Tasks[t] = struct{}{}
+	}
+
+	if isCancelled() {
+		return out, sm
+	}
+
+	regions := map[string]*SearchResult{}
+	type logKey struct{ category, message string }
+	logs := map[logKey]*SearchResult{}
+	for _, g := range tr.Goroutines {
+		if isCancelled() {
+			return out, sm
+		}
+
+		for i, spans := range g.UserRegions {
+			for j := range spans {
+				s := &spans[j]
+				name := tr.Event(s.StartEvent).Region().Type
+				if !matches(name) {
+					continue
+				}
+				r, ok := regions[name]
+				if !ok {
+					r = &SearchResult{Kind: SearchResultRegion, Label: name}
+					regions[name] = r
+				}
+				r.Count++
+				r.Spans = append(r.Spans, searchSpanRef{Goroutine: g, Track: i + 1, Span: j})
+				sm.Spans[s] = struct{}{}
+			}
+		}
+
+		for _, id := range g.Events {
+			ev := tr.Event(id)
+			if ev.Kind() != exptrace.EventLog {
+				continue
+			}
+			l := ev.Log()
+			if !matches(l.Message) && !matches(l.Category) {
+				continue
+			}
+			k := logKey{l.Category, l.Message}
+			r, ok := logs[k]
+			if !ok {
+				r = &SearchResult{Kind: SearchResultLog, Label: l.Message, Details: l.Category}
+				logs[k] = r
+			}
+			r.Count++
+			// Link to the span the goroutine was in when it logged the message.
+			j := sort.Search(len(g.Spans), func(j int) bool {
+				return g.Spans[j].End > ev.Time()
+			})
+			if j == len(g.Spans) {
+				continue
+			}
+			if n := len(r.Spans); n == 0 || r.Spans[n-1] != (searchSpanRef{Goroutine: g, Span: j}) {
+				r.Spans = append(r.Spans, searchSpanRef{Goroutine: g, Span: j})
+			}
+			sm.Spans[&g.Spans[j]] = struct{}{}
+		}
+	}
+
+	stws := map[string]*SearchResult{}
+	for i := range tr.STW {
+		s := &tr.STW[i]
+		reason := ptrace.STWReason(tr, s)
+		if !matches(reason) {
+			continue
+		}
+		r, ok := stws[reason]
+		if !ok {
+			r = &SearchResult{Kind: SearchResultSTW, Label: reason}
+			stws[reason] = r
+		}
+		r.Count++
+		r.Spans = append(r.Spans, searchSpanRef{Span: i})
+		sm.Spans[s] = struct{}{}
+	}
+
+	for _, r := range regions {
+		out = append(out, *r)
+	}
+	for _, r := range logs {
+		out = append(out, *r)
+	}
+	for _, r := range stws {
+		out = append(out, *r)
+	}
+
+	slices.SortFunc(out, func(a, b SearchResult) int {
+		if a.Kind != b.Kind {
+			return int(a.Kind) - int(b.Kind)
+		}
+		return strings.Compare(a.Label, b.Label)
+	})
+	return out, sm
+}
+
+type searchOutcome struct {
+	results []SearchResult
+	matches *SearchMatches
+}
+
+// Don't bubble up normal key presses, so that typing in the search box doesn't trigger our single-key shortcuts. See
+// the identical workaround in theme.ListWindow.
+var searchKeyset = key.Set("A|B|C|D|E|F|G|H|I|J|K|L|M|N|O|P|Q|R|S|T|U|V|W|X|Y|Z")
+
+// SearchComponent searches the trace for functions, goroutines, tasks, user regions, log messages, and STW pauses,
+// and highlights the matches in the timelines.
+type SearchComponent struct {
+	trace          *Trace
+	timelines      []*Timeline
+	itemToTimeline map[any]*Timeline
+
+	editor      widget.Editor
+	initialized bool
+	query       string
+	outcome     *theme.Future[searchOutcome]
+
+	buttons struct {
+		highlight widget.PrimaryClickable
+		clear     widget.PrimaryClickable
+	}
+
+	table         *theme.Table
+	scroll        theme.YScrollableListState
+	cellFormatter CellFormatter
+}
+
+func NewSearchComponent(tr *Trace, timelines []*Timeline, itemToTimeline map[any]*Timeline) *SearchComponent {
+	return &SearchComponent{
+		trace:          tr,
+		timelines:      timelines,
+		itemToTimeline: itemToTimeline,
+		editor: widget.Editor{
+			SingleLine: true,
+			Submit:     true,
+		},
+	}
+}
+
+func (sc *SearchComponent) Title() string {
+	if sc.query == "" {
+		return "Search"
+	}
+	return "Search: " + sc.query
+}
+
+func (sc *SearchComponent) Transition(theme.ComponentState) {
+}
+
+func (sc *SearchComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (sc *SearchComponent) layoutResults(win *theme.Window, gtx layout.Context, results []SearchResult) layout.Dimensions {
+	if sc.table == nil {
+		sc.table = &theme.Table{}
+		sc.table.SetColumns(win, gtx, []theme.Column{
+			{Name: "Kind", Alignment: text.Start},
+			{Name: "Match", Alignment: text.Start},
+			{Name: "Details", Alignment: text.Start},
+			{Name: "Count", Alignment: text.End},
+		})
+	}
+	sc.table.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		r := &results[row]
+		switch colName := sc.table.Columns[col].Name; colName {
+		case "Kind":
+			return sc.cellFormatter.Text(win, gtx, r.Kind.String())
+		case "Match":
+			link := sc.cellFormatter.Clicks.Grow()
+			link.Link = r.Link(sc.timelines, sc.itemToTimeline)
+			return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return widget.Label{
+					MaxLines:  1,
+					Alignment: text.Start,
+				}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, r.Label, win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+			})
+		case "Details":
+			return sc.cellFormatter.Text(win, gtx, r.Details)
+		case "Count":
+			return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+				return sc.cellFormatter.Number(win, gtx, r.Count)
+			})
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, sc.table, &sc.scroll, len(results), cellFn)
+}
+
+func (sc *SearchComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.SearchComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		if !sc.initialized {
+			sc.initialized = true
+			sc.editor.Focus()
+		}
+		for _, ev := range sc.editor.Events() {
+			switch ev.(type) {
+			case widget.ChangeEvent, widget.SubmitEvent:
+				if q := strings.TrimSpace(sc.editor.Text()); q != sc.query {
+					sc.query = q
+					sc.outcome = nil
+					if q != "" {
+						sc.outcome = theme.NewFuture(win, func(cancelled <-chan struct{}) searchOutcome {
+							results, matches := Search(sc.trace.Trace, q, cancelled)
+							return searchOutcome{results, matches}
+						})
+					}
+				}
+			}
+		}
+
+		var (
+			outcome searchOutcome
+			done    bool
+		)
+		if sc.outcome != nil {
+			outcome, done = sc.outcome.Result()
+		}
+		for sc.buttons.highlight.Clicked(gtx) {
+			if done {
+				win.EmitAction(&HighlightSearchMatchesAction{Matches: outcome.matches})
+			}
+		}
+		for sc.buttons.clear.Clicked(gtx) {
+			win.EmitAction(&HighlightSearchMatchesAction{})
+		}
+		sc.cellFormatter.Update(win, gtx)
+
+		return layout.Rigids(gtx, layout.Vertical,
+			func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+				if sc.editor.Focused() {
+					// Without focus, key presses don't bubble up through the editor, and we'd swallow them instead of
+					// the window.
+					key.InputOp{Tag: sc, Keys: searchKeyset}.Add(gtx.Ops)
+				}
+				return theme.TextBox(win.Theme, &sc.editor, "Search functions, goroutines, tasks, regions, logs, and STW reasons").Layout(win, gtx)
+			},
+			layout.Spacer{Height: 5}.Layout,
+			func(gtx layout.Context) layout.Dimensions {
+				return layout.Rigids(gtx, layout.Horizontal,
+					theme.Dumb(win, theme.Button(win.Theme, &sc.buttons.highlight.Clickable, "Highlight matches in timelines").Layout),
+					layout.Spacer{Width: 5}.Layout,
+					theme.Dumb(win, theme.Button(win.Theme, &sc.buttons.clear.Clickable, "Clear highlighting").Layout),
+				)
+			},
+			layout.Spacer{Height: 10}.Layout,
+			func(gtx layout.Context) layout.Dimensions {
+				switch {
+				case sc.query == "":
+					return theme.Label(win.Theme, "Enter a search query. Goroutines can be found by their IDs, such as \"g123\".").Layout(win, gtx)
+				case !done:
+					return theme.Label(win.Theme, "Searching…").Layout(win, gtx)
+				case len(outcome.results) == 0:
+					return theme.Label(win.Theme, "Nothing matched the query.").Layout(win, gtx)
+				default:
+					gtx.Constraints.Min = gtx.Constraints.Max
+					return sc.layoutResults(win, gtx, outcome.results)
+				}
+			},
+		)
+	})
+}
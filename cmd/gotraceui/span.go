@@ -609,26 +609,10 @@ func spanTagStrings(tags ptrace.SpanTags) []string {
 	}
 
 	out := make([]string, 0, 4)
-	if tags&ptrace.SpanTagRead != 0 {
-		out = append(out, "read")
-	}
-	if tags&ptrace.SpanTagAccept != 0 {
-		out = append(out, "accept")
-	}
-	if tags&ptrace.SpanTagDial != 0 {
-		out = append(out, "dial")
-	}
-	if tags&ptrace.SpanTagNetwork != 0 {
-		out = append(out, "network")
-	}
-	if tags&ptrace.SpanTagTCP != 0 {
-		out = append(out, "TCP")
-	}
-	if tags&ptrace.SpanTagTLS != 0 {
-		out = append(out, "TLS")
-	}
-	if tags&ptrace.SpanTagHTTP != 0 {
-		out = append(out, "HTTP")
+	for _, info := range ptrace.SpanTagInfos() {
+		if tags&info.Tag != 0 && info.Label != "" {
+			out = append(out, info.Label)
+		}
 	}
 	return out
 }
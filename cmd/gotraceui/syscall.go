@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	rtrace "runtime/trace"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+)
+
+// SyscallSite is a place that made syscalls, together with where it is in the source code.
+type SyscallSite struct {
+	*ptrace.SyscallSite
+	// The function containing the site, or nil if the trace didn't record stacks.
+	Function *ptrace.Function
+	Location string
+}
+
+// Percentile returns the p-th percentile of the site's syscall durations, with p in [0, 1].
+func (ss *SyscallSite) Percentile(p float64) time.Duration {
+	return ss.Durations[int(float64(len(ss.Durations)-1)*p)]
+}
+
+// SyscallAnalysis describes the time goroutines and threads spent blocked in syscalls.
+type SyscallAnalysis struct {
+	*ptrace.Syscalls
+	Sites []*SyscallSite
+	// The durations of all syscalls.
+	Durations []time.Duration
+}
+
+func NewSyscallAnalysis(tr *ptrace.Trace) *SyscallAnalysis {
+	a := &SyscallAnalysis{Syscalls: ptrace.ComputeSyscalls(tr)}
+	a.Sites = make([]*SyscallSite, len(a.Syscalls.Sites))
+	for i, ss := range a.Syscalls.Sites {
+		site := &SyscallSite{SyscallSite: ss, Location: "<unknown>"}
+		if ss.PC != 0 {
+			frame := tr.PCs[ss.PC]
+			site.Function = tr.Functions[frame.Func]
+			site.Location = fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if site.Syscall == "" {
+			site.Syscall = "<unknown>"
+		}
+		a.Sites[i] = site
+		a.Durations = append(a.Durations, ss.Durations...)
+	}
+	return a
+}
+
+// SyscallComponent displays which syscalls blocked, for how long, and whether their processors were handed off to
+// other threads.
+type SyscallComponent struct {
+	trace    *Trace
+	analysis *theme.Future[*SyscallAnalysis]
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	sites         []*SyscallSite
+	sitesTable    *theme.Table
+	sitesScroll   theme.YScrollableListState
+	cellFormatter CellFormatter
+
+	hist        InteractiveHistogram
+	chart       ConcurrencyChart
+	initialized bool
+}
+
+func NewSyscallComponent(win *theme.Window, tr *Trace) *SyscallComponent {
+	return &SyscallComponent{
+		trace: tr,
+		analysis: theme.NewFuture(win, func(cancelled <-chan struct{}) *SyscallAnalysis {
+			return NewSyscallAnalysis(tr.Trace)
+		}),
+	}
+}
+
+func (sc *SyscallComponent) Title() string {
+	return "Syscalls"
+}
+
+func (sc *SyscallComponent) Transition(theme.ComponentState) {
+}
+
+func (sc *SyscallComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (sc *SyscallComponent) buildDescription(win *theme.Window, a *SyscallAnalysis) Description {
+	tb := TextBuilder{Window: win}
+	n := len(a.Durations)
+	percent := func(m int) string {
+		if n == 0 {
+			return local.Sprintf("%d", m)
+		}
+		return local.Sprintf("%d (%.2f%%)", m, float64(m)/float64(n)*100)
+	}
+	attrs := []DescriptionAttribute{
+		{Key: "Syscalls", Value: *tb.Span(local.Sprintf("%d", n))},
+		{Key: "Time in syscalls", Value: *tb.Span(roundDuration(a.Total).String())},
+		{Key: "Retained P", Value: *tb.Span(percent(a.Outcomes[ptrace.SyscallRetainedP]))},
+		{Key: "Handed off P", Value: *tb.Span(percent(a.Outcomes[ptrace.SyscallHandedOffP]))},
+		{Key: "Unfinished", Value: *tb.Span(percent(a.Outcomes[ptrace.SyscallUnfinished]))},
+	}
+	if peak, ts := a.Threads.Peak(); peak > 0 {
+		attrs = append(attrs, DescriptionAttribute{
+			Key:   "Peak threads in syscalls",
+			Value: *tb.DefaultLink(local.Sprintf("%d", peak), "Peak of threads in syscalls", ts),
+		})
+	}
+	return Description{Attributes: attrs}
+}
+
+// callsLink lays out a link that opens the spans of a site's syscalls.
+func (sc *SyscallComponent) callsLink(win *theme.Window, gtx layout.Context, site *SyscallSite) layout.Dimensions {
+	return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+		link := sc.cellFormatter.Clicks.Grow()
+		link.Link = &SyscallsObjectLink{Calls: site.Calls, Title: local.Sprintf("%s in %s", site.Syscall, site.Location)}
+		return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{
+				MaxLines:  1,
+				Alignment: text.Start,
+			}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, local.Sprintf("%d", len(site.Calls)), win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+		})
+	})
+}
+
+func (sc *SyscallComponent) sortSites() {
+	desc := sc.sitesTable.SortOrder == theme.SortDescending
+	switch sc.sitesTable.Columns[sc.sitesTable.SortedBy].Name {
+	case "Syscall":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Syscall, b.Syscall, desc)
+		})
+	case "Function":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(unblockFunctionName(a.Function), unblockFunctionName(b.Function), desc)
+		})
+	case "Location":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Location, b.Location, desc)
+		})
+	case "Calls":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(len(a.Calls), len(b.Calls), desc)
+		})
+	case "Retained P":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Outcomes[ptrace.SyscallRetainedP], b.Outcomes[ptrace.SyscallRetainedP], desc)
+		})
+	case "Handed off P":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Outcomes[ptrace.SyscallHandedOffP], b.Outcomes[ptrace.SyscallHandedOffP], desc)
+		})
+	case "Total":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Total, b.Total, desc)
+		})
+	case "P50":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Percentile(0.5), b.Percentile(0.5), desc)
+		})
+	case "P99":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Percentile(0.99), b.Percentile(0.99), desc)
+		})
+	case "Max":
+		slices.SortStableFunc(sc.sites, func(a, b *SyscallSite) int {
+			return cmp(a.Percentile(1), b.Percentile(1), desc)
+		})
+	}
+}
+
+func (sc *SyscallComponent) layoutSites(win *theme.Window, gtx layout.Context, a *SyscallAnalysis) layout.Dimensions {
+	if sc.sitesTable == nil {
+		sc.sitesTable = &theme.Table{}
+		sc.sitesTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Syscall", Alignment: text.Start, Clickable: true},
+			{Name: "Function", Alignment: text.Start, Clickable: true},
+			{Name: "Location", Alignment: text.Start, Clickable: true},
+			{Name: "Calls", Alignment: text.End, Clickable: true},
+			{Name: "Retained P", Alignment: text.End, Clickable: true},
+			{Name: "Handed off P", Alignment: text.End, Clickable: true},
+			{Name: "Total", Alignment: text.End, Clickable: true},
+			{Name: "P50", Alignment: text.End, Clickable: true},
+			{Name: "P99", Alignment: text.End, Clickable: true},
+			{Name: "Max", Alignment: text.End, Clickable: true},
+		})
+		sc.sitesTable.SortedBy = 6
+		sc.sitesTable.SortOrder = theme.SortDescending
+		sc.sites = slices.Clone(a.Sites)
+	}
+	sc.sitesTable.Update(gtx)
+	if _, ok := sc.sitesTable.SortByClickedColumn(); ok {
+		sc.sortSites()
+	}
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		site := sc.sites[row]
+		switch colName := sc.sitesTable.Columns[col].Name; colName {
+		case "Syscall":
+			return sc.cellFormatter.Text(win, gtx, site.Syscall)
+		case "Function":
+			return sc.cellFormatter.Function(win, gtx, site.Function)
+		case "Location":
+			return sc.cellFormatter.Text(win, gtx, site.Location)
+		case "Calls":
+			return sc.callsLink(win, gtx, site)
+		case "Retained P":
+			return sc.cellFormatter.Number(win, gtx, site.Outcomes[ptrace.SyscallRetainedP])
+		case "Handed off P":
+			return sc.cellFormatter.Number(win, gtx, site.Outcomes[ptrace.SyscallHandedOffP])
+		case "Total":
+			return sc.cellFormatter.Duration(win, gtx, site.Total, false)
+		case "P50":
+			return sc.cellFormatter.Duration(win, gtx, site.Percentile(0.5), false)
+		case "P99":
+			return sc.cellFormatter.Duration(win, gtx, site.Percentile(0.99), false)
+		case "Max":
+			return sc.cellFormatter.Duration(win, gtx, site.Percentile(1), false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, sc.sitesTable, &sc.sitesScroll, len(sc.sites), cellFn)
+}
+
+func (sc *SyscallComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.SyscallComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		a, ok := sc.analysis.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		if !sc.initialized {
+			sc.initialized = true
+			sc.chart = ConcurrencyChart{
+				Metric: a.Threads,
+				Start:  sc.trace.Start(),
+				End:    sc.trace.End(),
+				Unit:   "threads",
+				Color:  colors[colorStateBlockedSyscall],
+			}
+			if len(a.Durations) > 0 {
+				sc.hist.Config = widget.HistogramConfig{RejectOutliers: true, Bins: widget.DefaultHistogramBins}
+				sc.hist.Set(win, a.Durations)
+			}
+		}
+		if len(a.Durations) > 0 && sc.hist.Update(gtx) {
+			sc.hist.Set(win, a.Durations)
+		}
+		sc.cellFormatter.Update(win, gtx)
+		for _, ev := range sc.descriptionText.Update(gtx, sc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     sc.buildDescription(win, a),
+			DescriptionText: &sc.descriptionText,
+			PrevSpans:       &sc.prevSpans,
+			Tabs:            []string{"By call site", "Histogram", "Threads in syscalls"},
+			TabbedState:     &sc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "By call site":
+					return sc.layoutSites(win, gtx, a)
+				case "Histogram":
+					if len(a.Durations) == 0 {
+						return theme.Label(win.Theme, "The trace contains no syscalls.").Layout(win, gtx)
+					}
+					return sc.hist.Layout(win, gtx)
+				case "Threads in syscalls":
+					return sc.chart.Layout(win, gtx)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// syscallSpans returns the spans of a list of syscalls.
+func syscallSpans(calls []ptrace.Syscall, timelines map[any]*Timeline) Items[ptrace.Span] {
+	return goroutineSpans(func(yield func(*ptrace.Goroutine, int) bool) {
+		for _, c := range calls {
+			if !yield(c.Goroutine, c.Span) {
+				return
+			}
+		}
+	}, timelines)
+}
@@ -53,7 +53,7 @@ func NewTaskTimeline(tr *Trace, cv *Canvas, t *ptrace.Task) *Timeline {
 
 	track.spanLabel = taskSpanLabel
 	track.spanTooltip = taskSpanTooltip
-	track.spanContextMenu = nil
+	track.spanContextMenu = taskSpanContextMenu
 	track.events = t.Events
 	tl.tracks = []*Track{track}
 
@@ -79,6 +79,26 @@ func taskForSpan(span *ptrace.Span, tr *Trace) *ptrace.Task {
 	return tr.Task(ev.Task().ID)
 }
 
+func taskSpanContextMenu(spans Items[ptrace.Span], cv *Canvas) []*theme.MenuItem {
+	items := []*theme.MenuItem{
+		newZoomMenuItem(cv, spans),
+		newOpenSpansMenuItem(spans),
+	}
+
+	if spans.Len() == 1 {
+		if task := taskForSpan(spans.AtPtr(0), cv.trace); task != nil {
+			items = append(items, &theme.MenuItem{
+				Label: PlainLabel("Show critical path"),
+				Action: func() theme.Action {
+					return &OpenTaskCriticalPathAction{Task: task}
+				},
+			})
+		}
+	}
+
+	return items
+}
+
 func taskSpanLabel(spans Items[ptrace.Span], tr *Trace, out []string) []string {
 	if spans.Len() != 1 {
 		return out
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"image"
 	stdcolor "image/color"
+	"maps"
 	"math"
 	rtrace "runtime/trace"
+	"slices"
 	"sort"
 	"time"
 
@@ -52,6 +54,7 @@ const (
 	TrackKindStack
 	TrackKindUserRegions
 	TrackKindTask
+	TrackKindRange
 )
 
 type Timeline struct {
@@ -739,7 +742,7 @@ func (track *Track) layoutMain(
 		minP = f32.Pt(max(startPx, 0), 0)
 		maxP = f32.Pt(min(endPx, float32(gtx.Constraints.Max.X)), float32(mainTrackHeight))
 
-		if filter.Match(dspSpans, ItemContainer{Timeline: tl, Track: track}) {
+		if filter.Match(dspSpans, ItemContainer{Timeline: tl, Track: track}) || track.onCriticalPath(dspSpans) || track.matchesSearch(dspSpans) {
 			highlightedSpans = append(highlightedSpans, clip.FRect{Min: minP, Max: maxP})
 		}
 
@@ -1539,3 +1542,103 @@ type GC struct {
 type STW struct {
 	Spans Items[ptrace.Span]
 }
+
+// Range is the item of timelines that display ranges that aren't scoped to a goroutine, processor, or thread.
+type Range struct {
+	Name  string
+	Spans Items[ptrace.Span]
+}
+
+func rangeTrackSpanTooltip(win *theme.Window, gtx layout.Context, tr *Trace, spans Items[ptrace.Span]) layout.Dimensions {
+	if spans.Len() > 1 {
+		return defaultSpanTooltip(win, gtx, tr, spans)
+	}
+	s := spans.AtPtr(0)
+	label := tr.Event(s.StartEvent).Range().Name + "\n"
+	if s.EndEvent != -1 {
+		if endEv := tr.Event(s.EndEvent); endEv.Kind() == exptrace.EventRangeEnd {
+			for _, attr := range endEv.RangeAttributes() {
+				if attr.Value.Kind() == exptrace.ValueUint64 {
+					label += local.Sprintf("%s: %d\n", attr.Name, attr.Value.Uint64())
+				} else {
+					label += fmt.Sprintf("%s: %s\n", attr.Name, attr.Value.String())
+				}
+			}
+		}
+	}
+	label += spansDurationForTooltip(spans)
+	return theme.Tooltip(win.Theme, label).Layout(win, gtx)
+}
+
+// newRangeTrack returns a track displaying the spans of a range.
+func newRangeTrack(tl *Timeline, name string, spans []ptrace.Span) *Track {
+	track := NewTrack(tl, TrackKindRange)
+	ss := SimpleItems[ptrace.Span, any]{
+		items: spans,
+		container: ItemContainer{
+			Timeline: tl,
+			Track:    track,
+		},
+		subslice: true,
+	}
+	if len(spans) > 0 {
+		track.Start = spans[0].Start
+		track.End = spans[len(spans)-1].End
+	}
+	track.spans = theme.Immediate[Items[ptrace.Span]](ss)
+	track.spanLabel = singleSpanLabel(name)
+	track.spanColor = singleSpanColor(colorStateUserRegion)
+	track.spanTooltip = rangeTrackSpanTooltip
+	return track
+}
+
+// addRangeTracks adds a track for each range, sorted by the ranges' names.
+func addRangeTracks(tl *Timeline, ranges map[string][]ptrace.Span) {
+	for _, name := range slices.Sorted(maps.Keys(ranges)) {
+		tl.tracks = append(tl.tracks, newRangeTrack(tl, name, ranges[name]))
+	}
+}
+
+func NewRangeTimeline(cv *Canvas, tr *Trace, name string, spans []ptrace.Span) *Timeline {
+	tl := &Timeline{
+		label:     name,
+		shortName: name,
+		cv:        cv,
+	}
+	track := newRangeTrack(tl, name, spans)
+	tl.tracks = []*Track{track}
+	tl.item = &Range{
+		Name: name,
+		Spans: SimpleItems[ptrace.Span, any]{
+			items: spans,
+			container: ItemContainer{
+				Timeline: tl,
+				Track:    track,
+			},
+			subslice: true,
+		},
+	}
+	return tl
+}
+
+// onCriticalPath reports whether the canvas's critical path passes through the spans of a goroutine's main track.
+func (track *Track) onCriticalPath(spans Items[ptrace.Span]) bool {
+	cp := track.parent.cv.timeline.criticalPath
+	if cp == nil || track.kind != TrackKindUnspecified || spans.Len() == 0 {
+		return false
+	}
+	g, ok := track.parent.item.(*ptrace.Goroutine)
+	if !ok {
+		return false
+	}
+	return cp.Overlaps(g, spans.AtPtr(0).Start, LastItemPtr(spans).End)
+}
+
+// matchesSearch reports whether any of the spans are among the canvas's highlighted search matches.
+func (track *Track) matchesSearch(spans Items[ptrace.Span]) bool {
+	sm := track.parent.cv.timeline.searchMatches
+	if sm == nil {
+		return false
+	}
+	return sm.Match(spans, ItemContainer{Timeline: track.parent, Track: track})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+)
+
+// maxCachedTraces is the number of processed traces we keep in the on-disk cache. Caches can be several hundred
+// megabytes large for large traces, so we don't want to keep them around indefinitely.
+const maxCachedTraces = 10
+
+func traceCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gotraceui", "traces"), nil
+}
+
+func traceCachePath(key []byte) (string, error) {
+	dir, err := traceCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hex.EncodeToString(key)+".cache"), nil
+}
+
+// hashTrace computes the key identifying a trace in the cache. The key also covers the user's patterns, as they affect
+// the processed trace. hashTrace rewinds r to the beginning when it's done.
+func hashTrace(r io.ReadSeeker, progress func(float64)) ([]byte, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	// Prefix the patterns with their length so that they can't run into the trace.
+	binary.Write(h, binary.LittleEndian, uint64(len(userPatternsSource)))
+	h.Write(userPatternsSource)
+	buf := make([]byte, 1<<20)
+	var n int64
+	for {
+		m, err := r.Read(buf)
+		h.Write(buf[:m])
+		n += int64(m)
+		if size > 0 {
+			progress(min(float64(n)/float64(size), 1))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// readTraceCache loads the cache for the trace identified by key. It returns ptrace.ErrStaleCache or an error
+// satisfying os.IsNotExist if there is no usable cache.
+func readTraceCache(key []byte) (*ptrace.Cache, error) {
+	path, err := traceCachePath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := ptrace.ReadCache(f, key)
+	if err != nil {
+		// The cache is unusable, don't try it again.
+		os.Remove(path)
+		return nil, err
+	}
+	// Mark the cache as recently used so it doesn't get pruned.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return c, nil
+}
+
+// writeTraceCache stores the processed trace in the cache, evicting the least recently used caches if there are too
+// many.
+func writeTraceCache(key []byte, tr *ptrace.Trace) error {
+	path, err := traceCachePath(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that we never leave truncated caches behind.
+	f, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := ptrace.WriteCache(f, tr, key); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	pruneTraceCache(dir)
+	return nil
+}
+
+func pruneTraceCache(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []cacheFile
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".cache") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{filepath.Join(dir, e.Name()), info.ModTime()})
+	}
+	if len(files) <= maxCachedTraces {
+		return
+	}
+	slices.SortFunc(files, func(a, b cacheFile) int {
+		return b.modTime.Compare(a.modTime)
+	})
+	for _, f := range files[maxCachedTraces:] {
+		os.Remove(f.path)
+	}
+}
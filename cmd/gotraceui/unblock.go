@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	rtrace "runtime/trace"
+	"slices"
+	"time"
+
+	"honnef.co/go/gotraceui/clip"
+	"honnef.co/go/gotraceui/layout"
+	"honnef.co/go/gotraceui/theme"
+	"honnef.co/go/gotraceui/trace/ptrace"
+	"honnef.co/go/gotraceui/widget"
+
+	"gioui.org/font"
+	"gioui.org/text"
+)
+
+// The maximum number of functions shown in the unblock matrix.
+const unblockMatrixSize = 16
+
+// UnblockGraph describes which goroutines unblocked which other goroutines, aggregated by the goroutines' functions.
+type UnblockGraph struct {
+	Edges   []*ptrace.UnblockEdge
+	Count   int
+	Blocked time.Duration
+
+	// The functions shown in the matrix, which are the ones involved in the most blocked time.
+	Functions []*ptrace.Function
+	// Matrix[i][j] is the edge from Functions[i] to Functions[j], or nil.
+	Matrix [][]*ptrace.UnblockEdge
+}
+
+func NewUnblockGraph(tr *ptrace.Trace) *UnblockGraph {
+	ug := &UnblockGraph{
+		Edges: ptrace.ComputeUnblockGraph(tr),
+	}
+
+	involvement := map[*ptrace.Function]time.Duration{}
+	for _, e := range ug.Edges {
+		ug.Count += e.Count()
+		ug.Blocked += e.Blocked
+		involvement[e.From] += e.Blocked
+		if e.To != e.From {
+			involvement[e.To] += e.Blocked
+		}
+	}
+	for fn := range involvement {
+		ug.Functions = append(ug.Functions, fn)
+	}
+	slices.SortFunc(ug.Functions, func(a, b *ptrace.Function) int {
+		if c := cmp(involvement[a], involvement[b], true); c != 0 {
+			return c
+		}
+		return cmp(unblockFunctionName(a), unblockFunctionName(b), false)
+	})
+	if len(ug.Functions) > unblockMatrixSize {
+		ug.Functions = ug.Functions[:unblockMatrixSize]
+	}
+
+	idx := make(map[*ptrace.Function]int, len(ug.Functions))
+	for i, fn := range ug.Functions {
+		idx[fn] = i
+	}
+	ug.Matrix = make([][]*ptrace.UnblockEdge, len(ug.Functions))
+	for i := range ug.Matrix {
+		ug.Matrix[i] = make([]*ptrace.UnblockEdge, len(ug.Functions))
+	}
+	for _, e := range ug.Edges {
+		from, ok1 := idx[e.From]
+		to, ok2 := idx[e.To]
+		if ok1 && ok2 {
+			ug.Matrix[from][to] = e
+		}
+	}
+	return ug
+}
+
+func unblockFunctionName(fn *ptrace.Function) string {
+	if fn == nil {
+		return "<unknown>"
+	}
+	return fn.Func
+}
+
+// UnblockGraphComponent displays which goroutines unblock which other goroutines, as a list of pairs of functions and
+// as a matrix.
+type UnblockGraphComponent struct {
+	trace *Trace
+	graph *theme.Future[*UnblockGraph]
+
+	tabbedState     theme.TabbedState
+	descriptionText Text
+	prevSpans       []TextSpan
+
+	pairs         []*ptrace.UnblockEdge
+	pairsTable    *theme.Table
+	pairsScroll   theme.YScrollableListState
+	matrixTable   *theme.Table
+	matrixScroll  theme.YScrollableListState
+	cellFormatter CellFormatter
+}
+
+func NewUnblockGraphComponent(win *theme.Window, tr *Trace) *UnblockGraphComponent {
+	return &UnblockGraphComponent{
+		trace: tr,
+		graph: theme.NewFuture(win, func(cancelled <-chan struct{}) *UnblockGraph {
+			return NewUnblockGraph(tr.Trace)
+		}),
+	}
+}
+
+func (uc *UnblockGraphComponent) Title() string {
+	return "Wake-up graph"
+}
+
+func (uc *UnblockGraphComponent) Transition(theme.ComponentState) {
+}
+
+func (uc *UnblockGraphComponent) WantsTransition(gtx layout.Context) theme.ComponentState {
+	return theme.ComponentStateNone
+}
+
+func (uc *UnblockGraphComponent) buildDescription(win *theme.Window, ug *UnblockGraph) Description {
+	tb := TextBuilder{Window: win}
+	attrs := []DescriptionAttribute{
+		{Key: "Wake-ups", Value: *tb.Span(local.Sprintf("%d", ug.Count))},
+		{Key: "Blocked time", Value: *tb.Span(roundDuration(ug.Blocked).String())},
+		{Key: "Pairs of functions", Value: *tb.Span(local.Sprintf("%d", len(ug.Edges)))},
+	}
+	return Description{Attributes: attrs}
+}
+
+// edgeLink lays out a link that opens the individual wake-ups of an edge.
+func (uc *UnblockGraphComponent) edgeLink(win *theme.Window, gtx layout.Context, e *ptrace.UnblockEdge, label string) layout.Dimensions {
+	return layout.RightAligned(gtx, func(gtx layout.Context) layout.Dimensions {
+		link := uc.cellFormatter.Clicks.Grow()
+		link.Link = &UnblockEdgeObjectLink{Edge: e}
+		return link.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return widget.Label{
+				MaxLines:  1,
+				Alignment: text.Start,
+			}.Layout(gtx, win.Theme.Shaper, font.Font{}, 12, label, win.ColorMaterial(gtx, win.Theme.Palette.OpenLink))
+		})
+	})
+}
+
+func (uc *UnblockGraphComponent) sortPairs() {
+	desc := uc.pairsTable.SortOrder == theme.SortDescending
+	switch uc.pairsTable.Columns[uc.pairsTable.SortedBy].Name {
+	case "Unblocking function":
+		slices.SortStableFunc(uc.pairs, func(a, b *ptrace.UnblockEdge) int {
+			return cmp(unblockFunctionName(a.From), unblockFunctionName(b.From), desc)
+		})
+	case "Unblocked function":
+		slices.SortStableFunc(uc.pairs, func(a, b *ptrace.UnblockEdge) int {
+			return cmp(unblockFunctionName(a.To), unblockFunctionName(b.To), desc)
+		})
+	case "Wake-ups":
+		slices.SortStableFunc(uc.pairs, func(a, b *ptrace.UnblockEdge) int {
+			return cmp(a.Count(), b.Count(), desc)
+		})
+	case "Blocked time":
+		slices.SortStableFunc(uc.pairs, func(a, b *ptrace.UnblockEdge) int {
+			return cmp(a.Blocked, b.Blocked, desc)
+		})
+	case "Mean blocked time":
+		slices.SortStableFunc(uc.pairs, func(a, b *ptrace.UnblockEdge) int {
+			return cmp(a.Blocked/time.Duration(a.Count()), b.Blocked/time.Duration(b.Count()), desc)
+		})
+	}
+}
+
+func (uc *UnblockGraphComponent) layoutPairs(win *theme.Window, gtx layout.Context, ug *UnblockGraph) layout.Dimensions {
+	if uc.pairsTable == nil {
+		uc.pairsTable = &theme.Table{}
+		uc.pairsTable.SetColumns(win, gtx, []theme.Column{
+			{Name: "Unblocking function", Alignment: text.Start, Clickable: true},
+			{Name: "Unblocked function", Alignment: text.Start, Clickable: true},
+			{Name: "Wake-ups", Alignment: text.End, Clickable: true},
+			{Name: "Blocked time", Alignment: text.End, Clickable: true},
+			{Name: "Mean blocked time", Alignment: text.End, Clickable: true},
+		})
+		uc.pairsTable.SortedBy = 3
+		uc.pairsTable.SortOrder = theme.SortDescending
+		uc.pairs = slices.Clone(ug.Edges)
+	}
+	uc.pairsTable.Update(gtx)
+	if _, ok := uc.pairsTable.SortByClickedColumn(); ok {
+		uc.sortPairs()
+	}
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		e := uc.pairs[row]
+		switch colName := uc.pairsTable.Columns[col].Name; colName {
+		case "Unblocking function":
+			return uc.cellFormatter.Function(win, gtx, e.From)
+		case "Unblocked function":
+			return uc.cellFormatter.Function(win, gtx, e.To)
+		case "Wake-ups":
+			return uc.edgeLink(win, gtx, e, local.Sprintf("%d", e.Count()))
+		case "Blocked time":
+			return uc.cellFormatter.Duration(win, gtx, e.Blocked, false)
+		case "Mean blocked time":
+			return uc.cellFormatter.Duration(win, gtx, e.Blocked/time.Duration(e.Count()), false)
+		default:
+			panic(colName)
+		}
+	}
+	return theme.SimpleTable(win, gtx, uc.pairsTable, &uc.pairsScroll, len(uc.pairs), cellFn)
+}
+
+func (uc *UnblockGraphComponent) layoutMatrix(win *theme.Window, gtx layout.Context, ug *UnblockGraph) layout.Dimensions {
+	if uc.matrixTable == nil {
+		cols := make([]theme.Column, 0, len(ug.Functions)+1)
+		cols = append(cols, theme.Column{Name: "Unblocking \\ unblocked", Alignment: text.Start})
+		for _, fn := range ug.Functions {
+			cols = append(cols, theme.Column{Name: shortenFunctionName(unblockFunctionName(fn)), Alignment: text.End})
+		}
+		uc.matrixTable = &theme.Table{}
+		uc.matrixTable.SetColumns(win, gtx, cols)
+	}
+	uc.matrixTable.Update(gtx)
+
+	cellFn := func(win *theme.Window, gtx layout.Context, row, col int) layout.Dimensions {
+		defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+		if col == 0 {
+			return uc.cellFormatter.Function(win, gtx, ug.Functions[row])
+		}
+		e := ug.Matrix[row][col-1]
+		if e == nil {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+		return uc.edgeLink(win, gtx, e, local.Sprintf("%d × %s", e.Count(), roundDuration(e.Blocked)))
+	}
+	return theme.SimpleTable(win, gtx, uc.matrixTable, &uc.matrixScroll, len(ug.Functions), cellFn)
+}
+
+func (uc *UnblockGraphComponent) Layout(win *theme.Window, gtx layout.Context) layout.Dimensions {
+	defer rtrace.StartRegion(context.Background(), "main.UnblockGraphComponent.Layout").End()
+
+	return layoutAnalysis(win, gtx, func(win *theme.Window, gtx layout.Context) layout.Dimensions {
+		ug, ok := uc.graph.Result()
+		if !ok {
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}
+
+		uc.cellFormatter.Update(win, gtx)
+		for _, ev := range uc.descriptionText.Update(gtx, uc.prevSpans) {
+			handleLinkClick(win, ev.Event, ev.Span.ObjectLink)
+		}
+
+		return AnalysisView{
+			Description:     uc.buildDescription(win, ug),
+			DescriptionText: &uc.descriptionText,
+			PrevSpans:       &uc.prevSpans,
+			Tabs:            []string{"Pairs", "Matrix"},
+			TabbedState:     &uc.tabbedState,
+			Content: func(win *theme.Window, gtx layout.Context, tab string) layout.Dimensions {
+				switch tab {
+				case "Pairs":
+					return uc.layoutPairs(win, gtx, ug)
+				case "Matrix":
+					return uc.layoutMatrix(win, gtx, ug)
+				default:
+					panic("unreachable")
+				}
+			},
+		}.Layout(win, gtx)
+	})
+}
+
+// unblockingSpans returns the spans during which the goroutines of an edge were blocked.
+func unblockingSpans(e *ptrace.UnblockEdge, timelines map[any]*Timeline) Items[ptrace.Span] {
+	var bases []Items[ptrace.Span]
+	byGoroutine := map[*ptrace.Goroutine][]int{}
+	var gs []*ptrace.Goroutine
+	for _, u := range e.Unblockings {
+		if _, ok := byGoroutine[u.Goroutine]; !ok {
+			gs = append(gs, u.Goroutine)
+		}
+		byGoroutine[u.Goroutine] = append(byGoroutine[u.Goroutine], u.Span)
+	}
+	for _, g := range gs {
+		tl := timelines[g]
+		if tl == nil {
+			continue
+		}
+		bases = append(bases, ItemsSubset[ptrace.Span]{
+			Base: SimpleItems[ptrace.Span, any]{
+				items: g.Spans,
+				container: ItemContainer{
+					Timeline: tl,
+					Track:    tl.tracks[0],
+				},
+				contiguous: true,
+				subslice:   true,
+			},
+			Subset: byGoroutine[g],
+		})
+	}
+	return MergeItems(bases, func(a, b *ptrace.Span) bool {
+		return a.Start < b.Start
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"honnef.co/go/gotraceui/trace/ptrace"
+)
+
+// ViolationReport is the machine-readable form of a ptrace.Violation, as emitted by the validate subcommand. Time is
+// relative to the start of the trace, in nanoseconds.
+type ViolationReport struct {
+	Kind    string         `json:"kind"`
+	Object  string         `json:"object"`
+	Event   ptrace.EventID `json:"event"`
+	Time    time.Duration  `json:"time"`
+	Message string         `json:"message"`
+}
+
+func validateUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "Usage: gotraceui validate [flags] <trace file>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Validate parses a trace and checks the result for inconsistencies, such as illegal state transitions,")
+		fmt.Fprintln(os.Stderr, "overlapping spans, and mismatched tasks. It exits with status 1 if it finds any.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		printDefaults(fs)
+	}
+}
+
+// runValidate implements the validate subcommand.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = validateUsage(fs)
+	asJSON := fs.Bool("json", false, "Print violations as JSON")
+	patterns := fs.String("patterns", "", "Load span patterns from this file instead of the user's configuration directory")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := loadUserPatterns(*patterns); err != nil {
+		return fmt.Errorf("couldn't load patterns: %w", err)
+	}
+
+	tr, err := parseTraceFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	vs := ptrace.Validate(tr)
+	reps := make([]ViolationReport, len(vs))
+	for i, v := range vs {
+		reps[i] = ViolationReport{
+			Kind:    v.Kind.String(),
+			Object:  v.Object,
+			Event:   v.Event,
+			Time:    time.Duration(v.Time - tr.Start()),
+			Message: v.Message,
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(reps); err != nil {
+			return err
+		}
+	} else {
+		for _, rep := range reps {
+			fmt.Fprintf(w, "%s\t%s: %s: %s\n", rep.Time, rep.Object, rep.Kind, rep.Message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(vs) != 0 {
+		return fmt.Errorf("found %d violations", len(vs))
+	}
+	return nil
+}
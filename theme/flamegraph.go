@@ -87,6 +87,12 @@ type FlameGraphStyle struct {
 	State      *widget.FlameGraph
 	StyleState *FlameGraphState
 	Color      func(level, idx int, f *widget.FlamegraphFrame, hovered bool) color.Oklch
+	// The name of the quantity stored in the frames' durations, for flame graphs that don't display time.
+	Quantity string
+	// FormatValue formats the quantity stored in a frame's duration.
+	FormatValue func(d time.Duration) string
+	// Annotate optionally returns additional lines for the tooltip of a frame.
+	Annotate func(f *widget.FlamegraphFrame) string
 }
 
 func FlameGraph(state *widget.FlameGraph, sstate *FlameGraphState) FlameGraphStyle {
@@ -96,6 +102,10 @@ func FlameGraph(state *widget.FlameGraph, sstate *FlameGraphState) FlameGraphSty
 		Color: func(level, idx int, f *widget.FlamegraphFrame, hovered bool) color.Oklch {
 			return color.Oklch{}
 		},
+		Quantity: "Duration",
+		FormatValue: func(d time.Duration) string {
+			return roundDuration(d).String()
+		},
 	}
 }
 
@@ -469,25 +479,29 @@ func (fg FlameGraphStyle) Layout(win *Window, gtx layout.Context) (dims layout.D
 				topRoot = topRoot.Parent
 			}
 
-			var labels [7]string
+			var labels [8]string
 			labels[0] = fmt.Sprintf("Name: %s\n", hoveredSpan.frame.Name)
 			labels[1] = fmt.Sprintf("Stack depth: %d\n", hoveredSpan.level)
-			labels[2] = fmt.Sprintf("Duration: %s (%s / %.2f%% self)\n",
-				roundDuration(hoveredSpan.frame.Duration),
-				roundDuration(self),
+			labels[2] = fmt.Sprintf("%s: %s (%s / %.2f%% self)\n",
+				fg.Quantity,
+				fg.FormatValue(hoveredSpan.frame.Duration),
+				fg.FormatValue(self),
 				(float64(self)/float64(hoveredSpan.frame.Duration))*100,
 			)
 			if p := hoveredSpan.frame.Parent; p != nil {
-				labels[3] = fmt.Sprintf("Duration: %.2f%% of parent\n",
-					(float64(hoveredSpan.frame.Duration)/float64(p.Duration))*100)
+				labels[3] = fmt.Sprintf("%s: %.2f%% of parent\n",
+					fg.Quantity, (float64(hoveredSpan.frame.Duration)/float64(p.Duration))*100)
 			}
 			if r := fg.StyleState.zoom.root; r != nil {
-				labels[4] = fmt.Sprintf("Duration: %.2f%% of visible root\n",
-					(float64(hoveredSpan.frame.Duration)/float64(r.Duration))*100)
+				labels[4] = fmt.Sprintf("%s: %.2f%% of visible root\n",
+					fg.Quantity, (float64(hoveredSpan.frame.Duration)/float64(r.Duration))*100)
 			}
-			labels[5] = fmt.Sprintf("Duration: %.2f%% of top-level root\n",
-				(float64(hoveredSpan.frame.Duration)/float64(topRoot.Duration))*100)
+			labels[5] = fmt.Sprintf("%s: %.2f%% of top-level root\n",
+				fg.Quantity, (float64(hoveredSpan.frame.Duration)/float64(topRoot.Duration))*100)
 			labels[6] = fmt.Sprintf("Immediate children: %d\n", len(hoveredSpan.frame.Children))
+			if fg.Annotate != nil {
+				labels[7] = fg.Annotate(hoveredSpan.frame)
+			}
 			l := strings.Join(labels[:], "")
 
 			fg.StyleState.tooltip = l
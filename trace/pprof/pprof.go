@@ -0,0 +1,192 @@
+// Package pprof writes profiles in the profile.proto format understood by go tool pprof and used for profile-guided
+// optimization.
+package pprof
+
+import (
+	"compress/gzip"
+	"io"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// ValueType describes the type and unit of sample values, such as "cpu" and "nanoseconds".
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+// Label is a string label attached to a sample, such as the ID of the goroutine that the sample belongs to.
+type Label struct {
+	Key   string
+	Value string
+}
+
+// Profile incrementally builds a profile. Samples are encoded as they're added, to keep the memory usage of large
+// profiles low.
+type Profile struct {
+	// The types of the values of each sample.
+	SampleTypes []ValueType
+	// The kind of events between samples, and the number of events between samples, if the profile is sampled.
+	PeriodType ValueType
+	Period     int64
+	// The time at which the profile was collected, in nanoseconds since the Unix epoch, and the duration it covers.
+	TimeNanos     int64
+	DurationNanos int64
+	// StartLine, if set, returns the line on which a function starts. It is called when the profile is written.
+	// Profile-guided optimization refuses to use profiles without start lines, but traces don't record them.
+	StartLine func(function string) int64
+
+	frames    map[uint64]exptrace.StackFrame
+	strings   map[string]int64
+	locations map[uint64]uint64
+	functions map[functionKey]uint64
+	// The functions in the order of their IDs.
+	funcs   []functionKey
+	samples encoder
+	body    encoder
+	n       int
+}
+
+type functionKey struct {
+	name string
+	file string
+}
+
+// The ID of the profile's single mapping. The trace doesn't tell us about the binary's mappings, but all locations
+// are already symbolized.
+const mappingID = 1
+
+// NewProfile returns an empty profile with the given sample types. frames maps the PCs of stacks to their frames.
+func NewProfile(frames map[uint64]exptrace.StackFrame, sampleTypes ...ValueType) *Profile {
+	p := &Profile{
+		SampleTypes: sampleTypes,
+		frames:      frames,
+		strings:     map[string]int64{},
+		locations:   map[uint64]uint64{},
+		functions:   map[functionKey]uint64{},
+	}
+	// The first entry in the string table has to be the empty string.
+	p.string("")
+	return p
+}
+
+// Len returns the number of samples in the profile.
+func (p *Profile) Len() int {
+	return p.n
+}
+
+func (p *Profile) string(s string) int64 {
+	if idx, ok := p.strings[s]; ok {
+		return idx
+	}
+	idx := int64(len(p.strings))
+	p.strings[s] = idx
+	// The string table is a repeated field, so we can write it piecemeal.
+	p.body.bytes(profileStringTable, []byte(s))
+	return idx
+}
+
+func (p *Profile) function(frame exptrace.StackFrame) uint64 {
+	key := functionKey{frame.Func, frame.File}
+	if id, ok := p.functions[key]; ok {
+		return id
+	}
+	id := uint64(len(p.functions) + 1)
+	p.functions[key] = id
+	p.funcs = append(p.funcs, key)
+	return id
+}
+
+func (p *Profile) location(pc uint64) uint64 {
+	if id, ok := p.locations[pc]; ok {
+		return id
+	}
+	id := uint64(len(p.locations) + 1)
+	p.locations[pc] = id
+	frame := p.frames[pc]
+	var line encoder
+	line.uint64(lineFunctionID, p.function(frame))
+	line.int64(lineLine, int64(frame.Line))
+	var msg encoder
+	msg.uint64(locationID, id)
+	msg.uint64(locationMappingID, mappingID)
+	msg.uint64(locationAddress, pc)
+	msg.bytes(locationLine, line.buf)
+	p.body.bytes(profileLocation, msg.buf)
+	return id
+}
+
+// AddSample adds a sample with the given stack, leaf first, and values, which have to match the profile's sample
+// types.
+func (p *Profile) AddSample(pcs []uint64, values []int64, labels ...Label) {
+	locs := make([]uint64, len(pcs))
+	for i, pc := range pcs {
+		locs[i] = p.location(pc)
+	}
+	var msg encoder
+	msg.packedUint64s(sampleLocationID, locs)
+	msg.packedInt64s(sampleValue, values)
+	for _, l := range labels {
+		var label encoder
+		label.int64(labelKey, p.string(l.Key))
+		label.int64(labelStr, p.string(l.Value))
+		msg.bytes(sampleLabel, label.buf)
+	}
+	p.samples.bytes(profileSample, msg.buf)
+	p.n++
+}
+
+// Write writes the gzip-compressed profile to w.
+func (p *Profile) Write(w io.Writer) error {
+	var e encoder
+	for _, st := range p.SampleTypes {
+		e.bytes(profileSampleType, p.valueType(st))
+	}
+	e.buf = append(e.buf, p.samples.buf...)
+
+	var m encoder
+	m.uint64(mappingIDField, mappingID)
+	m.bool(mappingHasFunctions, true)
+	m.bool(mappingHasFilenames, true)
+	m.bool(mappingHasLineNumbers, true)
+	e.bytes(profileMapping, m.buf)
+
+	// Interning the strings of the remaining fields may add to the string table, so encode them before appending
+	// the body.
+	var tail encoder
+	for i, key := range p.funcs {
+		id := uint64(i + 1)
+		name := p.string(key.name)
+		var msg encoder
+		msg.uint64(functionID, id)
+		msg.int64(functionName, name)
+		msg.int64(functionSystemName, name)
+		msg.int64(functionFilename, p.string(key.file))
+		if p.StartLine != nil {
+			msg.int64(functionStartLine, p.StartLine(key.name))
+		}
+		tail.bytes(profileFunction, msg.buf)
+	}
+	tail.int64(profileTimeNanos, p.TimeNanos)
+	tail.int64(profileDurationNanos, p.DurationNanos)
+	if p.PeriodType != (ValueType{}) {
+		tail.bytes(profilePeriodType, p.valueType(p.PeriodType))
+	}
+	tail.int64(profilePeriod, p.Period)
+
+	e.buf = append(e.buf, p.body.buf...)
+	e.buf = append(e.buf, tail.buf...)
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(e.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (p *Profile) valueType(vt ValueType) []byte {
+	var e encoder
+	e.int64(valueTypeType, p.string(vt.Type))
+	e.int64(valueTypeUnit, p.string(vt.Unit))
+	return e.buf
+}
@@ -0,0 +1,491 @@
+package pprof
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// The decoder below implements just enough of protobuf and profile.proto to check what Profile writes.
+
+type protoField struct {
+	num   int
+	wire  int
+	value uint64
+	bytes []byte
+}
+
+func decodeFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad key")
+		}
+		b = b[n:]
+		f := protoField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("bad varint in field %d", f.num)
+			}
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, fmt.Errorf("bad length in field %d", f.num)
+			}
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d in field %d", f.wire, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func decodePacked(b []byte) ([]uint64, error) {
+	var vs []uint64
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad packed varint")
+		}
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs, nil
+}
+
+// decodedSample is a sample with its stack resolved to "function file:line" strings.
+type decodedSample struct {
+	Stack  []string
+	Values []int64
+	Labels []Label
+}
+
+type decodedProfile struct {
+	SampleTypes   []ValueType
+	PeriodType    ValueType
+	Period        int64
+	TimeNanos     int64
+	DurationNanos int64
+	Samples       []decodedSample
+	StartLines    map[string]int64
+}
+
+func decodeProfile(r io.Reader) (*decodedProfile, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	type rawSample struct {
+		locs   []uint64
+		values []uint64
+		labels [][2]uint64
+	}
+	type rawLocation struct {
+		mapping uint64
+		fn      uint64
+		line    int64
+	}
+	type rawFunction struct {
+		name, file uint64
+		startLine  int64
+	}
+	var (
+		strs        []string
+		sampleTypes [][2]uint64
+		periodType  [2]uint64
+		samples     []rawSample
+		mappings    = map[uint64]bool{}
+		locations   = map[uint64]rawLocation{}
+		functions   = map[uint64]rawFunction{}
+		p           = &decodedProfile{StartLines: map[string]int64{}}
+	)
+
+	valueType := func(b []byte) ([2]uint64, error) {
+		var vt [2]uint64
+		fs, err := decodeFields(b)
+		for _, f := range fs {
+			switch f.num {
+			case valueTypeType:
+				vt[0] = f.value
+			case valueTypeUnit:
+				vt[1] = f.value
+			}
+		}
+		return vt, err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case profileStringTable:
+			strs = append(strs, string(f.bytes))
+		case profileSampleType:
+			vt, err := valueType(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			sampleTypes = append(sampleTypes, vt)
+		case profilePeriodType:
+			if periodType, err = valueType(f.bytes); err != nil {
+				return nil, err
+			}
+		case profilePeriod:
+			p.Period = int64(f.value)
+		case profileTimeNanos:
+			p.TimeNanos = int64(f.value)
+		case profileDurationNanos:
+			p.DurationNanos = int64(f.value)
+		case profileMapping:
+			fs, err := decodeFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range fs {
+				if f.num == mappingIDField {
+					mappings[f.value] = true
+				}
+			}
+		case profileSample:
+			fs, err := decodeFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			var s rawSample
+			for _, f := range fs {
+				switch f.num {
+				case sampleLocationID:
+					if s.locs, err = decodePacked(f.bytes); err != nil {
+						return nil, err
+					}
+				case sampleValue:
+					if s.values, err = decodePacked(f.bytes); err != nil {
+						return nil, err
+					}
+				case sampleLabel:
+					lfs, err := decodeFields(f.bytes)
+					if err != nil {
+						return nil, err
+					}
+					var l [2]uint64
+					for _, lf := range lfs {
+						switch lf.num {
+						case labelKey:
+							l[0] = lf.value
+						case labelStr:
+							l[1] = lf.value
+						}
+					}
+					s.labels = append(s.labels, l)
+				}
+			}
+			samples = append(samples, s)
+		case profileLocation:
+			fs, err := decodeFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			var id uint64
+			var loc rawLocation
+			for _, f := range fs {
+				switch f.num {
+				case locationID:
+					id = f.value
+				case locationMappingID:
+					loc.mapping = f.value
+				case locationLine:
+					lfs, err := decodeFields(f.bytes)
+					if err != nil {
+						return nil, err
+					}
+					for _, lf := range lfs {
+						switch lf.num {
+						case lineFunctionID:
+							loc.fn = lf.value
+						case lineLine:
+							loc.line = int64(lf.value)
+						}
+					}
+				}
+			}
+			if _, ok := locations[id]; ok || id == 0 {
+				return nil, fmt.Errorf("invalid or duplicate location ID %d", id)
+			}
+			locations[id] = loc
+		case profileFunction:
+			fs, err := decodeFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			var id uint64
+			var fn rawFunction
+			for _, f := range fs {
+				switch f.num {
+				case functionID:
+					id = f.value
+				case functionName:
+					fn.name = f.value
+				case functionFilename:
+					fn.file = f.value
+				case functionStartLine:
+					fn.startLine = int64(f.value)
+				}
+			}
+			if _, ok := functions[id]; ok || id == 0 {
+				return nil, fmt.Errorf("invalid or duplicate function ID %d", id)
+			}
+			functions[id] = fn
+		}
+	}
+
+	if len(strs) == 0 || strs[0] != "" {
+		return nil, fmt.Errorf("string table doesn't start with the empty string")
+	}
+	str := func(idx uint64) (string, error) {
+		if idx >= uint64(len(strs)) {
+			return "", fmt.Errorf("string index %d out of range", idx)
+		}
+		return strs[idx], nil
+	}
+	toValueType := func(vt [2]uint64) (ValueType, error) {
+		typ, err := str(vt[0])
+		if err != nil {
+			return ValueType{}, err
+		}
+		unit, err := str(vt[1])
+		return ValueType{typ, unit}, err
+	}
+
+	for _, vt := range sampleTypes {
+		st, err := toValueType(vt)
+		if err != nil {
+			return nil, err
+		}
+		p.SampleTypes = append(p.SampleTypes, st)
+	}
+	if p.PeriodType, err = toValueType(periodType); err != nil {
+		return nil, err
+	}
+	for _, fn := range functions {
+		name, err := str(fn.name)
+		if err != nil {
+			return nil, err
+		}
+		if fn.startLine != 0 {
+			p.StartLines[name] = fn.startLine
+		}
+	}
+	for _, s := range samples {
+		if len(s.values) != len(p.SampleTypes) {
+			return nil, fmt.Errorf("sample has %d values for %d sample types", len(s.values), len(p.SampleTypes))
+		}
+		var ds decodedSample
+		for _, id := range s.locs {
+			loc, ok := locations[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown location %d", id)
+			}
+			if !mappings[loc.mapping] {
+				return nil, fmt.Errorf("unknown mapping %d", loc.mapping)
+			}
+			fn, ok := functions[loc.fn]
+			if !ok {
+				return nil, fmt.Errorf("unknown function %d", loc.fn)
+			}
+			name, err := str(fn.name)
+			if err != nil {
+				return nil, err
+			}
+			file, err := str(fn.file)
+			if err != nil {
+				return nil, err
+			}
+			ds.Stack = append(ds.Stack, fmt.Sprintf("%s %s:%d", name, file, loc.line))
+		}
+		for _, v := range s.values {
+			ds.Values = append(ds.Values, int64(v))
+		}
+		for _, l := range s.labels {
+			key, err := str(l[0])
+			if err != nil {
+				return nil, err
+			}
+			value, err := str(l[1])
+			if err != nil {
+				return nil, err
+			}
+			ds.Labels = append(ds.Labels, Label{key, value})
+		}
+		p.Samples = append(p.Samples, ds)
+	}
+	return p, nil
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	frames := map[uint64]exptrace.StackFrame{
+		0x10: {PC: 0x10, Func: "main.leaf", File: "/src/main.go", Line: 10},
+		0x11: {PC: 0x11, Func: "main.leaf", File: "/src/main.go", Line: 12},
+		0x20: {PC: 0x20, Func: "main.caller", File: "/src/main.go", Line: 20},
+		0x30: {PC: 0x30, Func: "main.main", File: "/src/main.go", Line: 30},
+	}
+	cpu := []ValueType{{"samples", "count"}, {"cpu", "nanoseconds"}}
+
+	type sample struct {
+		pcs    []uint64
+		values []int64
+		labels []Label
+	}
+	tests := []struct {
+		name        string
+		sampleTypes []ValueType
+		setup       func(p *Profile)
+		samples     []sample
+		want        decodedProfile
+	}{
+		{
+			name:        "empty",
+			sampleTypes: cpu,
+			want: decodedProfile{
+				SampleTypes: cpu,
+				StartLines:  map[string]int64{},
+			},
+		},
+		{
+			name:        "metadata",
+			sampleTypes: cpu,
+			setup: func(p *Profile) {
+				p.PeriodType = ValueType{"cpu", "nanoseconds"}
+				p.Period = 10_000_000
+				p.TimeNanos = 1_700_000_000_000_000_000
+				p.DurationNanos = 5_000_000_000
+			},
+			want: decodedProfile{
+				SampleTypes:   cpu,
+				PeriodType:    ValueType{"cpu", "nanoseconds"},
+				Period:        10_000_000,
+				TimeNanos:     1_700_000_000_000_000_000,
+				DurationNanos: 5_000_000_000,
+				StartLines:    map[string]int64{},
+			},
+		},
+		{
+			name:        "shared frames",
+			sampleTypes: cpu,
+			samples: []sample{
+				{[]uint64{0x10, 0x20, 0x30}, []int64{1, 10_000_000}, nil},
+				{[]uint64{0x11, 0x20, 0x30}, []int64{2, 20_000_000}, nil},
+				{[]uint64{0x20, 0x30}, []int64{1, 0}, nil},
+			},
+			want: decodedProfile{
+				SampleTypes: cpu,
+				Samples: []decodedSample{
+					{
+						Stack:  []string{"main.leaf /src/main.go:10", "main.caller /src/main.go:20", "main.main /src/main.go:30"},
+						Values: []int64{1, 10_000_000},
+					},
+					{
+						Stack:  []string{"main.leaf /src/main.go:12", "main.caller /src/main.go:20", "main.main /src/main.go:30"},
+						Values: []int64{2, 20_000_000},
+					},
+					{
+						Stack:  []string{"main.caller /src/main.go:20", "main.main /src/main.go:30"},
+						Values: []int64{1, 0},
+					},
+				},
+				StartLines: map[string]int64{},
+			},
+		},
+		{
+			name:        "labels",
+			sampleTypes: []ValueType{{"off_cpu", "nanoseconds"}},
+			samples: []sample{
+				{[]uint64{0x30}, []int64{5}, []Label{{"goroutine", "1"}, {"state", "blocked"}}},
+				{[]uint64{0x30}, []int64{7}, []Label{{"goroutine", "2"}}},
+			},
+			want: decodedProfile{
+				SampleTypes: []ValueType{{"off_cpu", "nanoseconds"}},
+				Samples: []decodedSample{
+					{
+						Stack:  []string{"main.main /src/main.go:30"},
+						Values: []int64{5},
+						Labels: []Label{{"goroutine", "1"}, {"state", "blocked"}},
+					},
+					{
+						Stack:  []string{"main.main /src/main.go:30"},
+						Values: []int64{7},
+						Labels: []Label{{"goroutine", "2"}},
+					},
+				},
+				StartLines: map[string]int64{},
+			},
+		},
+		{
+			name:        "start lines",
+			sampleTypes: cpu,
+			setup: func(p *Profile) {
+				p.StartLine = func(fn string) int64 {
+					return map[string]int64{"main.leaf": 9, "main.main": 29}[fn]
+				}
+			},
+			samples: []sample{
+				{[]uint64{0x10, 0x20, 0x30}, []int64{1, 1}, nil},
+			},
+			want: decodedProfile{
+				SampleTypes: cpu,
+				Samples: []decodedSample{
+					{
+						Stack:  []string{"main.leaf /src/main.go:10", "main.caller /src/main.go:20", "main.main /src/main.go:30"},
+						Values: []int64{1, 1},
+					},
+				},
+				StartLines: map[string]int64{"main.leaf": 9, "main.main": 29},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile(frames, tt.sampleTypes...)
+			if tt.setup != nil {
+				tt.setup(p)
+			}
+			for _, s := range tt.samples {
+				p.AddSample(s.pcs, s.values, s.labels...)
+			}
+			if p.Len() != len(tt.samples) {
+				t.Errorf("got Len() == %d, want %d", p.Len(), len(tt.samples))
+			}
+
+			var buf bytes.Buffer
+			if err := p.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeProfile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
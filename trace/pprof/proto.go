@@ -0,0 +1,130 @@
+package pprof
+
+import "encoding/binary"
+
+// Field numbers of the messages in profile.proto.
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileMapping       = 3
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+	sampleLabel      = 3
+
+	labelKey = 1
+	labelStr = 2
+
+	mappingIDField        = 1
+	mappingHasFunctions   = 7
+	mappingHasFilenames   = 8
+	mappingHasLineNumbers = 9
+
+	locationID        = 1
+	locationMappingID = 2
+	locationAddress   = 3
+	locationLine      = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// encoder encodes protobuf messages. Fields with zero values are omitted, as they would be by the proto3 encoding.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) varint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) key(field int, wireType int) {
+	e.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (e *encoder) uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.varint(v)
+}
+
+func (e *encoder) int64(field int, v int64) {
+	e.uint64(field, uint64(v))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint64(field, 1)
+	}
+}
+
+// bytes encodes a length-delimited field, which is used for strings and embedded messages. Unlike other fields,
+// it is encoded even if it is empty, as it may be an element of a repeated field.
+func (e *encoder) bytes(field int, b []byte) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) packedUint64s(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	n := 0
+	for _, v := range vs {
+		n += varintLen(v)
+	}
+	e.key(field, wireBytes)
+	e.varint(uint64(n))
+	for _, v := range vs {
+		e.varint(v)
+	}
+}
+
+func (e *encoder) packedInt64s(field int, vs []int64) {
+	if len(vs) == 0 {
+		return
+	}
+	n := 0
+	for _, v := range vs {
+		n += varintLen(uint64(v))
+	}
+	e.key(field, wireBytes)
+	e.varint(uint64(n))
+	for _, v := range vs {
+		e.varint(uint64(v))
+	}
+}
+
+func varintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
@@ -0,0 +1,358 @@
+package ptrace
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// The name of the tracing experiment that records heap and stack allocations. It is enabled with
+// GODEBUG=traceallocfree=1.
+const allocFreeExperiment = "AllocFree"
+
+// The interval over which allocation rates are computed.
+const allocRateInterval = 10 * time.Millisecond
+
+type AllocKind uint8
+
+const (
+	AllocHeapObject AllocKind = iota
+	AllocHeapSpan
+	AllocGoroutineStack
+)
+
+// An Allocation describes a heap object, heap span, or goroutine stack being allocated or freed.
+type Allocation struct {
+	Event     EventID
+	Goroutine exptrace.GoID
+	// The size in bytes. It is zero if the size couldn't be determined, for example when freeing memory whose
+	// allocation we haven't seen.
+	Size uint64
+	// Index into Allocations.Types, or -1 if the type isn't known. Only heap objects have types.
+	Type int32
+	Kind AllocKind
+	Free bool
+}
+
+type AllocType struct {
+	Name string
+	Size uint64
+}
+
+// Allocations contains the allocations and frees recorded by the AllocFree tracing experiment. The runtime doesn't
+// record stack traces for these events; allocations are attributed to the goroutines making them.
+type Allocations struct {
+	// All allocations and frees, in the order in which they happened.
+	Events []Allocation
+	Types  []AllocType
+	// Indices into Events, grouped by the goroutine that allocated or freed memory.
+	ByG map[exptrace.GoID][]int
+}
+
+// Batch kinds of the AllocFree experiment.
+const (
+	allocFreeTypesBatch = iota
+	allocFreeInfoBatch
+)
+
+// sizeClassToSize maps the runtime's size classes to the sizes of the objects they contain. This table has to match
+// the runtime's. It hasn't changed since Go 1.21.
+var sizeClassToSize = [...]uint16{0, 8, 16, 24, 32, 48, 64, 80, 96, 112, 128, 144, 160, 176, 192, 208, 224, 240, 256, 288, 320, 352, 384, 416, 448, 480, 512, 576, 640, 704, 768, 896, 1024, 1152, 1280, 1408, 1536, 1792, 2048, 2304, 2688, 3072, 3200, 3456, 4096, 4864, 5376, 6144, 6528, 6784, 6912, 8192, 9472, 9728, 10240, 10880, 12288, 13568, 14336, 16384, 18432, 19072, 20480, 21760, 24576, 27264, 28672, 32768}
+
+// The maximum number of pages occupied by spans for small objects.
+const maxSizeClassNPages = 10
+
+type allocSpan struct {
+	npages uint64
+	// The size class of the span's objects. Zero for spans containing a single large object and for spans not used
+	// by the heap.
+	sizeClass uint8
+	heap      bool
+}
+
+type allocObject struct {
+	size uint64
+	typ  int32
+}
+
+// allocState is the state needed to decode the events of the AllocFree experiment.
+type allocState struct {
+	pageSize  uint64
+	heapAlign uint64
+
+	// Maps the current generation's type IDs to indices into Allocations.Types.
+	types map[uint64]int32
+	// Maps type names to indices into Allocations.Types.
+	typesByName map[string]int32
+
+	spans   map[uint64]allocSpan
+	objects map[uint64]allocObject
+	stacks  map[uint64]uint64
+
+	allocated, freed rateBuilder
+}
+
+func newAllocState() allocState {
+	// These defaults match all current architectures and are overridden by the trace.
+	return allocState{
+		pageSize:    8192,
+		heapAlign:   8,
+		types:       map[uint64]int32{},
+		typesByName: map[string]int32{},
+		spans:       map[uint64]allocSpan{},
+		objects:     map[uint64]allocObject{},
+		stacks:      map[uint64]uint64{},
+		allocated:   rateBuilder{interval: allocRateInterval},
+		freed:       rateBuilder{interval: allocRateInterval},
+	}
+}
+
+// rateBuilder builds a metric of the rate at which a value grows, in units per second.
+type rateBuilder struct {
+	interval time.Duration
+	// The start of the current interval and the sum of values in it.
+	start      exptrace.Time
+	current    uint64
+	timestamps []exptrace.Time
+	values     []uint64
+}
+
+// add adds v at time t. It assumes that t will not decrease with subsequent calls.
+func (rb *rateBuilder) add(t exptrace.Time, v uint64) {
+	// Intervals are aligned to multiples of the interval, so that incremental processing produces the same results as
+	// processing the trace in one go.
+	start := t - t%exptrace.Time(rb.interval)
+	if len(rb.timestamps) == 0 || start != rb.start {
+		// Drop back to zero at the end of the previous interval, unless the new interval immediately follows it.
+		if end := rb.start + exptrace.Time(rb.interval); len(rb.timestamps) != 0 && end != start {
+			rb.timestamps = append(rb.timestamps, end)
+			rb.values = append(rb.values, 0)
+		}
+		rb.start = start
+		rb.current = 0
+		rb.timestamps = append(rb.timestamps, start)
+		rb.values = append(rb.values, 0)
+	}
+	rb.current += v
+	rb.values[len(rb.values)-1] = rb.current * uint64(time.Second/rb.interval)
+}
+
+func (rb *rateBuilder) metric() Metric {
+	if len(rb.timestamps) == 0 {
+		return Metric{}
+	}
+	// End the last interval without modifying the builder, which may see more values for the same interval.
+	n := len(rb.timestamps)
+	return Metric{
+		Timestamps: append(rb.timestamps[:n:n], rb.start+exptrace.Time(rb.interval)),
+		Values:     append(rb.values[:n:n], 0),
+	}
+}
+
+var errMalformedAllocFreeBatch = errors.New("malformed AllocFree batch")
+
+// readAllocFreeBatches decodes the experimental batches of a generation, which contain the generation's type table and
+// the information needed to interpret allocation events.
+func (p *Parser) readAllocFreeBatches(batches []exptrace.ExperimentalBatch) error {
+	as := &p.allocs
+	// Type IDs are only valid for a single generation.
+	clear(as.types)
+
+	for _, b := range batches {
+		data := b.Data
+		if len(data) == 0 {
+			continue
+		}
+		kind := data[0]
+		data = data[1:]
+		readUint := func() (uint64, error) {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return 0, errMalformedAllocFreeBatch
+			}
+			data = data[n:]
+			return v, nil
+		}
+
+		switch kind {
+		case allocFreeInfoBatch:
+			var info [4]uint64
+			for i := range info {
+				v, err := readUint()
+				if err != nil {
+					return err
+				}
+				info[i] = v
+			}
+			// The info consists of the minimum heap address, the page size, the minimum heap alignment, and the
+			// minimum stack alignment. We only need the page size and heap alignment to map objects to spans.
+			as.pageSize, as.heapAlign = info[1], info[2]
+		case allocFreeTypesBatch:
+			for len(data) > 0 {
+				// Each type consists of its ID, address, size, the number of bytes containing pointers, and its name.
+				var fields [5]uint64
+				for i := range fields {
+					v, err := readUint()
+					if err != nil {
+						return err
+					}
+					fields[i] = v
+				}
+				if uint64(len(data)) < fields[4] {
+					return errMalformedAllocFreeBatch
+				}
+				name := string(data[:fields[4]])
+				data = data[fields[4]:]
+
+				idx, ok := as.typesByName[name]
+				if !ok {
+					idx = int32(len(p.tr.Allocations.Types))
+					p.tr.Allocations.Types = append(p.tr.Allocations.Types, AllocType{Name: name, Size: fields[2]})
+					as.typesByName[name] = idx
+				}
+				as.types[fields[0]] = idx
+			}
+		default:
+			// Batches we don't understand are from newer versions of Go. We can still make use of the events, we just
+			// might lack some information.
+		}
+	}
+	return nil
+}
+
+// heapObjectSize returns the size of the heap object with the given ID, based on the span containing it.
+func (as *allocState) heapObjectSize(id uint64) uint64 {
+	page := id * as.heapAlign / as.pageSize
+	for i := uint64(0); i < maxSizeClassNPages && i <= page; i++ {
+		s, ok := as.spans[page-i]
+		if !ok || !s.heap {
+			continue
+		}
+		if page-i+s.npages <= page {
+			// The closest span doesn't contain the object.
+			return 0
+		}
+		if s.sizeClass == 0 {
+			return s.npages * as.pageSize
+		}
+		return uint64(sizeClassToSize[s.sizeClass])
+	}
+	return 0
+}
+
+// processAllocEvent processes an event of the AllocFree experiment.
+func (p *Parser) processAllocEvent(ev exptrace.Event, evID EventID) error {
+	exp := ev.Experimental()
+	if exp.Experiment != allocFreeExperiment {
+		return nil
+	}
+	tr := p.tr
+	as := &p.allocs
+
+	arg := func(name string) uint64 {
+		for i, argName := range exp.Args {
+			if argName == name {
+				return exp.ArgValue(i).Uint64()
+			}
+		}
+		return 0
+	}
+	id := arg("id")
+
+	a := Allocation{
+		Event:     evID,
+		Goroutine: ev.Goroutine(),
+		Type:      -1,
+	}
+	// "Exists" events describe memory that was allocated before the trace started. We need them to interpret later
+	// events, but they aren't allocations.
+	var exists bool
+
+	switch exp.Name {
+	case "Span", "SpanAlloc":
+		// The span's kind and size class are encoded as the span class shifted left by one, with the lowest bit
+		// set if the span isn't used for heap objects.
+		kindClass := arg("kindclass")
+		s := allocSpan{
+			npages:    arg("npages_value"),
+			sizeClass: uint8(kindClass >> 2),
+			heap:      kindClass&1 == 0,
+		}
+		if int(s.sizeClass) >= len(sizeClassToSize) {
+			return fmt.Errorf("unknown size class %d", s.sizeClass)
+		}
+		as.spans[id] = s
+		a.Kind = AllocHeapSpan
+		a.Size = s.npages * as.pageSize
+		exists = exp.Name == "Span"
+	case "SpanFree":
+		if s, ok := as.spans[id]; ok {
+			a.Size = s.npages * as.pageSize
+			delete(as.spans, id)
+		}
+		a.Kind = AllocHeapSpan
+		a.Free = true
+	case "HeapObject", "HeapObjectAlloc":
+		obj := allocObject{
+			size: as.heapObjectSize(id),
+			typ:  -1,
+		}
+		if typ := arg("type"); typ != 0 {
+			if idx, ok := as.types[typ]; ok {
+				obj.typ = idx
+			}
+		}
+		if obj.size == 0 && obj.typ != -1 {
+			obj.size = tr.Allocations.Types[obj.typ].Size
+		}
+		as.objects[id] = obj
+		a.Kind = AllocHeapObject
+		a.Size = obj.size
+		a.Type = obj.typ
+		exists = exp.Name == "HeapObject"
+	case "HeapObjectFree":
+		if obj, ok := as.objects[id]; ok {
+			a.Size = obj.size
+			a.Type = obj.typ
+			delete(as.objects, id)
+		}
+		a.Kind = AllocHeapObject
+		a.Free = true
+	case "GoroutineStack", "GoroutineStackAlloc":
+		// The order is the base 2 logarithm of the stack size, plus one.
+		size := uint64(1) << arg("order") >> 1
+		as.stacks[id] = size
+		a.Kind = AllocGoroutineStack
+		a.Size = size
+		exists = exp.Name == "GoroutineStack"
+	case "GoroutineStackFree":
+		a.Size = as.stacks[id]
+		delete(as.stacks, id)
+		a.Kind = AllocGoroutineStack
+		a.Free = true
+	default:
+		// An event added by a newer version of Go.
+		return nil
+	}
+
+	if exists {
+		return nil
+	}
+
+	allocs := &tr.Allocations
+	if a.Goroutine != exptrace.NoGoroutine {
+		allocs.ByG[a.Goroutine] = append(allocs.ByG[a.Goroutine], len(allocs.Events))
+	}
+	allocs.Events = append(allocs.Events, a)
+	if a.Kind == AllocHeapObject {
+		if a.Free {
+			as.freed.add(ev.Time(), a.Size)
+		} else {
+			as.allocated.add(ev.Time(), a.Size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package ptrace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"honnef.co/go/stuff/container/maybe"
+
+	"github.com/golang/snappy"
+	exptrace "golang.org/x/exp/trace"
+)
+
+// CacheVersion is the version of the cache format written by WriteCache. It has to be incremented whenever the
+// format changes, or whenever Parse starts producing different results for the same input, as that makes existing
+// caches stale.
+const CacheVersion = 6
+
+const cacheMagic = "gotraceui cache\x00"
+
+// ErrStaleCache is returned by ReadCache when the cache was written for a different trace or by a different version
+// of ptrace.
+var ErrStaleCache = errors.New("stale cache")
+
+// Cache is the decoded contents of a cache file, ready to be combined with the trace's events by ParseWithCache.
+type Cache struct {
+	data cachedTrace
+}
+
+// The cache stores the results of processing events, but not the events themselves. exptrace.Event and
+// exptrace.Stack are opaque handles into the parser's internal tables and cannot be serialized. Events still have to
+// be read from the trace, but that is much cheaper than processing them.
+type cachedTrace struct {
+	NumEvents int
+	// Goroutines contains all goroutines, including those without spans. The first NumGoroutines entries are the
+	// goroutines that make up Trace.Goroutines.
+	Goroutines    []cachedGoroutine
+	NumGoroutines int
+	Processors    []*Processor
+	Machines      []*Machine
+	Functions     []cachedFunction
+	GC            []Span
+	STW           []Span
+	Ranges        map[string][]Span
+	Allocations   Allocations
+	Tasks         []cachedTask
+	Metrics       map[string]Metric
+	CPUSamples    []EventID
+	CPUSamplesByG map[exptrace.GoID][]EventID
+	CPUSamplesByP map[exptrace.ProcID][]EventID
+}
+
+type cachedTime struct {
+	Set   bool
+	Value exptrace.Time
+}
+
+func toCachedTime(opt maybe.Option[exptrace.Time]) cachedTime {
+	v, ok := opt.Get()
+	return cachedTime{ok, v}
+}
+
+func (t cachedTime) option() maybe.Option[exptrace.Time] {
+	if t.Set {
+		return maybe.Some(t.Value)
+	}
+	return maybe.Option[exptrace.Time]{}
+}
+
+type cachedGoroutine struct {
+	ID     exptrace.GoID
+	Parent exptrace.GoID
+	SeqID  int
+	// Index into cachedTrace.Functions, or -1.
+	Function    int
+	Spans       []Span
+	Ranges      map[string][]Span
+	Start       cachedTime
+	End         cachedTime
+	UserRegions [][]Span
+	Events      []EventID
+}
+
+type cachedFunction struct {
+	Frame exptrace.StackFrame
+	SeqID int
+	// Indices into cachedTrace.Goroutines
+	Goroutines []int
+}
+
+type cachedTask struct {
+	ID         exptrace.TaskID
+	Parent     exptrace.TaskID
+	SeqID      int
+	Name       string
+	Start      cachedTime
+	End        cachedTime
+	StartEvent EventID
+	EndEvent   EventID
+	Spans      []Span
+	Events     []EventID
+}
+
+// WriteCache writes the processed trace to w, in a form that can later be loaded by ReadCache and ParseWithCache. key
+// identifies the trace, typically by a hash of its contents, and has to be passed to ReadCache. WriteCache must be
+// called before the trace has been modified by the caller.
+func WriteCache(w io.Writer, tr *Trace, key []byte) error {
+	var data cachedTrace
+	data.NumEvents = tr.Events.Len()
+
+	gIndices := make(map[*Goroutine]int, len(tr.gsByID))
+	fnIndices := make(map[*Function]int, len(tr.Functions))
+
+	fns := make([]*Function, len(tr.Functions))
+	for _, fn := range tr.Functions {
+		fns[fn.SeqID] = fn
+	}
+	for i, fn := range fns {
+		fnIndices[fn] = i
+	}
+
+	addG := func(g *Goroutine) {
+		fn := -1
+		if g.Function != nil {
+			fn = fnIndices[g.Function]
+		}
+		gIndices[g] = len(data.Goroutines)
+		data.Goroutines = append(data.Goroutines, cachedGoroutine{
+			ID:          g.ID,
+			Parent:      g.Parent,
+			SeqID:       g.SeqID,
+			Function:    fn,
+			Spans:       g.Spans,
+			Ranges:      g.Ranges,
+			Start:       toCachedTime(g.Start),
+			End:         toCachedTime(g.End),
+			UserRegions: g.UserRegions,
+			Events:      g.Events,
+		})
+	}
+	for _, g := range tr.Goroutines {
+		addG(g)
+	}
+	data.NumGoroutines = len(data.Goroutines)
+	for _, g := range tr.gsByID {
+		if _, ok := gIndices[g]; !ok {
+			addG(g)
+		}
+	}
+
+	data.Functions = make([]cachedFunction, len(fns))
+	for i, fn := range fns {
+		gs := make([]int, len(fn.Goroutines))
+		for j, g := range fn.Goroutines {
+			gs[j] = gIndices[g]
+		}
+		data.Functions[i] = cachedFunction{
+			Frame:      fn.StackFrame,
+			SeqID:      fn.SeqID,
+			Goroutines: gs,
+		}
+	}
+
+	data.Tasks = make([]cachedTask, len(tr.Tasks))
+	for i, t := range tr.Tasks {
+		data.Tasks[i] = cachedTask{
+			ID:         t.ID,
+			Parent:     t.Parent,
+			SeqID:      t.SeqID,
+			Name:       t.Name,
+			Start:      toCachedTime(t.Start),
+			End:        toCachedTime(t.End),
+			StartEvent: t.StartEvent,
+			EndEvent:   t.EndEvent,
+			Spans:      t.Spans,
+			Events:     t.Events,
+		}
+	}
+
+	data.Processors = tr.Processors
+	data.Machines = tr.Machines
+	data.GC = tr.GC
+	data.STW = tr.STW
+	data.Ranges = tr.Ranges
+	data.Allocations = tr.Allocations
+	data.Metrics = tr.Metrics
+	data.CPUSamples = tr.CPUSamples
+	data.CPUSamplesByG = tr.CPUSamplesByG
+	data.CPUSamplesByP = tr.CPUSamplesByP
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(cacheMagic)
+	binary.Write(bw, binary.LittleEndian, uint32(CacheVersion))
+	binary.Write(bw, binary.LittleEndian, uint32(len(key)))
+	bw.Write(key)
+
+	zw := snappy.NewBufferedWriter(bw)
+	if err := gob.NewEncoder(zw).Encode(&data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadCache reads a cache written by WriteCache. It returns ErrStaleCache if the cache was written for a different
+// key or with a different version of the cache format.
+func ReadCache(r io.Reader, key []byte) (*Cache, error) {
+	br := bufio.NewReader(r)
+
+	var hdr struct {
+		Magic   [len(cacheMagic)]byte
+		Version uint32
+		KeyLen  uint32
+	}
+	if err := binary.Read(br, binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("couldn't read cache header: %w", err)
+	}
+	if string(hdr.Magic[:]) != cacheMagic {
+		return nil, errors.New("not a cache file")
+	}
+	if hdr.Version != CacheVersion || int(hdr.KeyLen) != len(key) {
+		return nil, ErrStaleCache
+	}
+	cacheKey := make([]byte, hdr.KeyLen)
+	if _, err := io.ReadFull(br, cacheKey); err != nil {
+		return nil, fmt.Errorf("couldn't read cache header: %w", err)
+	}
+	if !bytes.Equal(cacheKey, key) {
+		return nil, ErrStaleCache
+	}
+
+	var c Cache
+	if err := gob.NewDecoder(snappy.NewReader(br)).Decode(&c.data); err != nil {
+		return nil, fmt.Errorf("couldn't decode cache: %w", err)
+	}
+	return &c, nil
+}
+
+// ParseWithCache is like Parse, but instead of processing the trace's events it uses the results stored in c. r must
+// read the same trace that the cache was written for.
+func ParseWithCache(r *exptrace.Reader, c *Cache, progress func(float64)) (*Trace, error) {
+	data := &c.data
+	tr := &Trace{
+		gsByID:        make(map[exptrace.GoID]*Goroutine, len(data.Goroutines)),
+		Functions:     make(map[string]*Function, len(data.Functions)),
+		Processors:    data.Processors,
+		Machines:      data.Machines,
+		GC:            data.GC,
+		STW:           data.STW,
+		Ranges:        data.Ranges,
+		Allocations:   data.Allocations,
+		Tasks:         make([]*Task, len(data.Tasks)),
+		Metrics:       data.Metrics,
+		CPUSamples:    data.CPUSamples,
+		CPUSamplesByG: data.CPUSamplesByG,
+		CPUSamplesByP: data.CPUSamplesByP,
+		PCs:           make(map[uint64]exptrace.StackFrame),
+		Stacks:        make(map[exptrace.Stack][]uint64),
+	}
+
+	for {
+		ev, err := r.ReadEvent()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		tr.Events.Append(ev)
+		tr.addStack(ev.Stack())
+		if ev.Kind() == exptrace.EventStateTransition {
+			tr.addStack(ev.StateTransition().Stack)
+		}
+
+		if n := tr.Events.Len(); n%(1<<16) == 0 && n <= data.NumEvents {
+			progress(float64(n) / float64(data.NumEvents))
+		}
+	}
+	if tr.Events.Len() != data.NumEvents {
+		return nil, fmt.Errorf("cache expected %d events, trace has %d: %w", data.NumEvents, tr.Events.Len(), ErrStaleCache)
+	}
+
+	fns := make([]*Function, len(data.Functions))
+	for i, cfn := range data.Functions {
+		fn := &Function{
+			StackFrame: cfn.Frame,
+			SeqID:      cfn.SeqID,
+			Goroutines: make([]*Goroutine, len(cfn.Goroutines)),
+		}
+		fns[i] = fn
+		tr.Functions[fn.Func] = fn
+	}
+
+	gs := make([]*Goroutine, len(data.Goroutines))
+	for i, cg := range data.Goroutines {
+		g := &Goroutine{
+			ID:          cg.ID,
+			Parent:      cg.Parent,
+			SeqID:       cg.SeqID,
+			Spans:       cg.Spans,
+			Ranges:      cg.Ranges,
+			Start:       cg.Start.option(),
+			End:         cg.End.option(),
+			UserRegions: cg.UserRegions,
+			Events:      cg.Events,
+		}
+		if g.Ranges == nil {
+			g.Ranges = map[string][]Span{}
+		}
+		if cg.Function != -1 {
+			g.Function = fns[cg.Function]
+		}
+		gs[i] = g
+		tr.gsByID[g.ID] = g
+	}
+	tr.Goroutines = gs[:data.NumGoroutines:data.NumGoroutines]
+
+	for i, cfn := range data.Functions {
+		for j, idx := range cfn.Goroutines {
+			fns[i].Goroutines[j] = gs[idx]
+		}
+	}
+
+	for i, ct := range data.Tasks {
+		tr.Tasks[i] = &Task{
+			ID:         ct.ID,
+			Parent:     ct.Parent,
+			SeqID:      ct.SeqID,
+			Name:       ct.Name,
+			Start:      ct.Start.option(),
+			End:        ct.End.option(),
+			StartEvent: ct.StartEvent,
+			EndEvent:   ct.EndEvent,
+			Spans:      ct.Spans,
+			Events:     ct.Events,
+		}
+	}
+
+	for _, p := range tr.Processors {
+		if p.Ranges == nil {
+			p.Ranges = map[string][]Span{}
+		}
+	}
+	for _, m := range tr.Machines {
+		if m.Ranges == nil {
+			m.Ranges = map[string][]Span{}
+		}
+	}
+	if tr.Ranges == nil {
+		tr.Ranges = map[string][]Span{}
+	}
+	if tr.Allocations.ByG == nil {
+		tr.Allocations.ByG = map[exptrace.GoID][]int{}
+	}
+	if tr.Metrics == nil {
+		tr.Metrics = map[string]Metric{}
+	}
+	if tr.CPUSamplesByG == nil {
+		tr.CPUSamplesByG = map[exptrace.GoID][]EventID{}
+	}
+	if tr.CPUSamplesByP == nil {
+		tr.CPUSamplesByP = map[exptrace.ProcID][]EventID{}
+	}
+	if tr.GC == nil {
+		tr.GC = make(spansSlice, 0)
+	}
+	if tr.STW == nil {
+		tr.STW = make(spansSlice, 0)
+	}
+
+	progress(1)
+	return tr, nil
+}
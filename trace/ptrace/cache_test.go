@@ -0,0 +1,218 @@
+package ptrace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"runtime"
+	rtrace "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+var (
+	testTraceOnce sync.Once
+	testTraceData []byte
+	testTraceErr  error
+)
+
+// testTrace returns a small execution trace of the test binary, covering blocking, syscalls and goroutine creation.
+func testTrace(t *testing.T) []byte {
+	t.Helper()
+	testTraceOnce.Do(func() {
+		var buf bytes.Buffer
+		if err := rtrace.Start(&buf); err != nil {
+			testTraceErr = err
+			return
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		ch := make(chan int)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mu.Lock()
+				time.Sleep(time.Millisecond)
+				mu.Unlock()
+				ch <- i
+			}()
+		}
+		for i := 0; i < 8; i++ {
+			<-ch
+		}
+		wg.Wait()
+		runtime.GC()
+
+		rtrace.Stop()
+		testTraceData = buf.Bytes()
+	})
+	if testTraceErr != nil {
+		t.Fatal(testTraceErr)
+	}
+	return testTraceData
+}
+
+func parseTestTrace(t *testing.T, data []byte, c *Cache) *Trace {
+	t.Helper()
+	r, err := exptrace.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tr *Trace
+	if c == nil {
+		tr, err = Parse(r, func(float64) {})
+	} else {
+		tr, err = ParseWithCache(r, c, func(float64) {})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	data := testTrace(t)
+	want := parseTestTrace(t, data, nil)
+
+	var buf bytes.Buffer
+	if err := WriteCache(&buf, want, []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCache(bytes.NewReader(buf.Bytes()), []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parseTestTrace(t, data, c)
+
+	if len(got.Goroutines) != len(want.Goroutines) {
+		t.Fatalf("got %d goroutines, want %d", len(got.Goroutines), len(want.Goroutines))
+	}
+	for i, wg := range want.Goroutines {
+		gg := got.Goroutines[i]
+		if gg.ID != wg.ID || gg.Parent != wg.Parent || gg.SeqID != wg.SeqID {
+			t.Errorf("goroutine %d: got ID %d, parent %d, seq %d, want %d, %d, %d",
+				i, gg.ID, gg.Parent, gg.SeqID, wg.ID, wg.Parent, wg.SeqID)
+		}
+		if (gg.Function == nil) != (wg.Function == nil) || (wg.Function != nil && gg.Function.Func != wg.Function.Func) {
+			t.Errorf("goroutine %d: functions differ", wg.ID)
+		}
+		if !reflect.DeepEqual(gg.Spans, wg.Spans) {
+			t.Errorf("goroutine %d: spans differ", wg.ID)
+		}
+		if !reflect.DeepEqual(gg.Events, wg.Events) {
+			t.Errorf("goroutine %d: events differ", wg.ID)
+		}
+		if gg.Start != wg.Start || gg.End != wg.End {
+			t.Errorf("goroutine %d: got bounds %v–%v, want %v–%v", wg.ID, gg.Start, gg.End, wg.Start, wg.End)
+		}
+	}
+
+	fields := []struct {
+		name      string
+		got, want any
+	}{
+		{"Processors", got.Processors, want.Processors},
+		{"GC", got.GC, want.GC},
+		{"STW", got.STW, want.STW},
+		{"Ranges", got.Ranges, want.Ranges},
+		{"Metrics", got.Metrics, want.Metrics},
+		{"CPUSamples", got.CPUSamples, want.CPUSamples},
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.got, f.want) {
+			t.Errorf("%s differ", f.name)
+		}
+	}
+	if len(got.Functions) != len(want.Functions) {
+		t.Errorf("got %d functions, want %d", len(got.Functions), len(want.Functions))
+	}
+	if got.Events.Len() != want.Events.Len() {
+		t.Errorf("got %d events, want %d", got.Events.Len(), want.Events.Len())
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	tr := parseTestTrace(t, testTrace(t), nil)
+	var buf bytes.Buffer
+	if err := WriteCache(&buf, tr, []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	cache := buf.Bytes()
+
+	withVersion := func(v uint32) []byte {
+		b := bytes.Clone(cache)
+		binary.LittleEndian.PutUint32(b[len(cacheMagic):], v)
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		key   string
+		err   error
+		fails bool
+	}{
+		{"valid", cache, "key", nil, false},
+		{"different key", cache, "kez", ErrStaleCache, true},
+		{"different key length", cache, "longer key", ErrStaleCache, true},
+		{"older version", withVersion(CacheVersion - 1), "key", ErrStaleCache, true},
+		{"newer version", withVersion(CacheVersion + 1), "key", ErrStaleCache, true},
+		{"not a cache", []byte("gotraceui kache\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "key", nil, true},
+		{"truncated header", cache[:len(cacheMagic)+2], "key", nil, true},
+		{"truncated body", cache[:len(cache)/2], "key", nil, true},
+		{"empty", nil, "key", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ReadCache(bytes.NewReader(tt.data), []byte(tt.key))
+			if !tt.fails {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if c == nil {
+					t.Fatal("got nil cache")
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("got error %q, want %q", err, tt.err)
+			}
+			if tt.err == nil && errors.Is(err, ErrStaleCache) {
+				t.Fatalf("got %q for a broken cache", err)
+			}
+		})
+	}
+}
+
+func TestParseWithCacheDifferentTrace(t *testing.T) {
+	data := testTrace(t)
+	tr := parseTestTrace(t, data, nil)
+
+	// Pretend the cache was for a trace with a different number of events.
+	var buf bytes.Buffer
+	if err := WriteCache(&buf, tr, []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCache(&buf, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.data.NumEvents++
+
+	r, err := exptrace.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseWithCache(r, c, func(float64) {}); !errors.Is(err, ErrStaleCache) {
+		t.Fatalf("got error %v, want %q", err, ErrStaleCache)
+	}
+}
@@ -0,0 +1,100 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// IsSyncBlocked reports whether a goroutine in the given state is blocked on a sync primitive, such as a mutex, a
+// sync.Once, or a condition variable. Goroutines in StateBlockedSyncTriggeringGC are waiting for a GC cycle to start,
+// not for another goroutine to release a lock, and don't count.
+func IsSyncBlocked(state SchedulingState) bool {
+	switch state {
+	case StateBlockedSync, StateBlockedSyncOnce, StateBlockedCond:
+		return true
+	default:
+		return false
+	}
+}
+
+// ContentionWait is a single time a goroutine blocked on a sync primitive.
+type ContentionWait struct {
+	Goroutine *Goroutine
+	// The index of the span in Goroutine.Spans.
+	Span int
+	// The goroutine that released the goroutine, or nil if the trace doesn't say.
+	ReleasedBy *Goroutine
+}
+
+// ContentionSite aggregates the times goroutines blocked on sync primitives in the same place.
+type ContentionSite struct {
+	// The PC of the frame that called the sync primitive, as determined by the spans' At field, or 0 if the trace
+	// didn't record stacks.
+	PC uint64
+	// The waits, in the order of the goroutines and their spans.
+	Waits []ContentionWait
+	// The durations of the waits, in ascending order.
+	Durations []time.Duration
+	Total     time.Duration
+	// The number of distinct goroutines that waited.
+	Goroutines int
+}
+
+// Percentile returns the p-th percentile of the site's wait durations, with p in [0, 1].
+func (cs *ContentionSite) Percentile(p float64) time.Duration {
+	if len(cs.Durations) == 0 {
+		return 0
+	}
+	return cs.Durations[int(float64(len(cs.Durations)-1)*p)]
+}
+
+// ComputeContention groups all the times goroutines blocked on sync primitives by the user code that called the sync
+// primitive. The sites are sorted by their total wait time, in descending order.
+func ComputeContention(tr *Trace) []*ContentionSite {
+	sites := map[uint64]*ContentionSite{}
+	for _, g := range tr.Goroutines {
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			if !IsSyncBlocked(s.State) {
+				continue
+			}
+
+			var pc uint64
+			if s.StartEvent != NoEvent {
+				if pcs := tr.Stacks[tr.Event(s.StartEvent).Stack()]; int(s.At) < len(pcs) {
+					pc = pcs[s.At]
+				}
+			}
+			site, ok := sites[pc]
+			if !ok {
+				site = &ContentionSite{PC: pc}
+				sites[pc] = site
+			}
+
+			w := ContentionWait{Goroutine: g, Span: i}
+			if gid, ok := UnblockedBy(tr, s); ok {
+				w.ReleasedBy = tr.gsByID[gid]
+			}
+			if n := len(site.Waits); n == 0 || site.Waits[n-1].Goroutine != g {
+				site.Goroutines++
+			}
+			site.Waits = append(site.Waits, w)
+			site.Durations = append(site.Durations, s.Duration())
+			site.Total += s.Duration()
+		}
+	}
+
+	out := make([]*ContentionSite, 0, len(sites))
+	for _, site := range sites {
+		slices.Sort(site.Durations)
+		out = append(out, site)
+	}
+	slices.SortFunc(out, func(a, b *ContentionSite) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PC, b.PC)
+	})
+	return out
+}
@@ -0,0 +1,129 @@
+package ptrace
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// UnblockedBy returns the goroutine that unblocked the goroutine at the end of the blocked span s, if the trace records
+// one.
+func UnblockedBy(tr *Trace, s *Span) (exptrace.GoID, bool) {
+	switch s.State {
+	case StateBlocked, StateBlockedSend, StateBlockedRecv, StateBlockedSelect, StateBlockedSync,
+		StateBlockedSyncOnce, StateBlockedSyncTriggeringGC, StateBlockedCond, StateBlockedNet, StateBlockedGC:
+		if s.EndEvent == NoEvent {
+			return 0, false
+		}
+		endEv := tr.Event(s.EndEvent)
+		if endEv.Kind() == exptrace.EventStateTransition {
+			if gid := endEv.Goroutine(); gid != exptrace.NoGoroutine {
+				return gid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// CriticalPathSegment is the part of a critical path during which it followed a single span of a single goroutine.
+type CriticalPathSegment struct {
+	Goroutine *Goroutine
+	// The index of the span in Goroutine.Spans.
+	Span       int
+	Start, End exptrace.Time
+}
+
+func (seg *CriticalPathSegment) Duration() time.Duration {
+	return time.Duration(seg.End - seg.Start)
+}
+
+// CriticalPath is the chain of goroutine spans that determined when a goroutine reached a point in time.
+type CriticalPath struct {
+	Start, End exptrace.Time
+	// The segments in chronological order. They cover the path without overlap, but the path may begin after Start if
+	// the trace doesn't tell us what happened before.
+	Segments []CriticalPathSegment
+
+	byGoroutine map[*Goroutine][]int
+}
+
+// ComputeCriticalPath computes the critical path that led goroutine g to reach end, starting at start. Beginning at
+// end, it walks backwards through the spans of g. When it reaches a blocked span that another goroutine ended by
+// unblocking g, the path continues in that goroutine, at the time it unblocked g. Similarly, when it reaches the
+// creation of a goroutine, it continues in the creating goroutine. Time during which goroutines were blocked on
+// something the trace can't attribute to a goroutine, such as timers or syscalls, stays on the blocked goroutine.
+func ComputeCriticalPath(tr *Trace, g *Goroutine, start, end exptrace.Time) *CriticalPath {
+	cp := &CriticalPath{
+		Start:       start,
+		End:         end,
+		byGoroutine: map[*Goroutine][]int{},
+	}
+
+	t := end
+	// Whether we switched goroutines without making progress, to guard against cycles of goroutines unblocking each
+	// other at the same time.
+	jumped := false
+	for t > start {
+		// Find the span that covers the time right before t.
+		i := sort.Search(len(g.Spans), func(i int) bool {
+			return g.Spans[i].End >= t
+		})
+		if i == len(g.Spans) || g.Spans[i].Start >= t {
+			// The goroutine didn't exist or the trace didn't observe it.
+			break
+		}
+		s := &g.Spans[i]
+
+		if !jumped && s.End == t {
+			if by, ok := UnblockedBy(tr, s); ok && by != g.ID {
+				if next, ok := tr.gsByID[by]; ok {
+					g = next
+					jumped = true
+					continue
+				}
+			}
+		}
+
+		segStart := max(s.Start, start)
+		cp.Segments = append(cp.Segments, CriticalPathSegment{
+			Goroutine: g,
+			Span:      i,
+			Start:     segStart,
+			End:       t,
+		})
+		t = segStart
+		jumped = false
+
+		if s.State == StateCreated {
+			parent, ok := tr.gsByID[g.Parent]
+			if !ok {
+				break
+			}
+			g = parent
+			jumped = true
+		}
+	}
+
+	slices.Reverse(cp.Segments)
+	for i, seg := range cp.Segments {
+		cp.byGoroutine[seg.Goroutine] = append(cp.byGoroutine[seg.Goroutine], i)
+	}
+	return cp
+}
+
+// Overlaps reports whether the critical path passes through goroutine g between start and end.
+func (cp *CriticalPath) Overlaps(g *Goroutine, start, end exptrace.Time) bool {
+	idxs := cp.byGoroutine[g]
+	// The segments of a goroutine are sorted and don't overlap, so we can find the first one that ends after start.
+	n := sort.Search(len(idxs), func(n int) bool {
+		return cp.Segments[idxs[n]].End > start
+	})
+	return n < len(idxs) && cp.Segments[idxs[n]].Start < end
+}
+
+// Goroutines returns the number of distinct goroutines that the critical path passes through.
+func (cp *CriticalPath) Goroutines() int {
+	return len(cp.byGoroutine)
+}
@@ -0,0 +1,151 @@
+package ptrace
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"honnef.co/go/stuff/container/maybe"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// GCPause is a stop-the-world pause that happened during a GC cycle.
+type GCPause struct {
+	Span Span
+	// Why the world was stopped, such as "GC mark termination".
+	Reason string
+}
+
+// GCCycle describes a single garbage collection cycle.
+type GCCycle struct {
+	// The span of the concurrent mark phase, as found in Trace.GC.
+	Mark Span
+	// The bounds of the cycle. Unlike Mark, these include the stop-the-world pauses for sweep termination and mark
+	// termination, which overlap the start and end of the mark phase.
+	Start, End exptrace.Time
+	Pauses     []GCPause
+	// The time goroutines spent assisting the mark phase.
+	MarkAssist time.Duration
+	// The time spent running the GC's dedicated, fractional, and idle mark workers.
+	Dedicated, Fractional, Idle time.Duration
+	// The size of the heap at the start and end of the cycle, and the heap goal at the start of the cycle, if the
+	// trace recorded them.
+	HeapStart, HeapEnd, HeapGoal maybe.Option[uint64]
+}
+
+// STW returns the total duration of the cycle's stop-the-world pauses.
+func (c *GCCycle) STW() time.Duration {
+	var d time.Duration
+	for _, p := range c.Pauses {
+		d += p.Span.Duration()
+	}
+	return d
+}
+
+// STWReason returns the reason for a stop-the-world pause, such as "GC sweep termination", given one of the trace's
+// STW spans.
+func STWReason(tr *Trace, s *Span) string {
+	reason := tr.Event(s.StartEvent).Range().Name
+	return strings.TrimSuffix(strings.TrimPrefix(reason, "stop-the-world ("), ")")
+}
+
+// overlap returns how much of the span falls in the interval [start, end).
+func overlap(s *Span, start, end exptrace.Time) time.Duration {
+	return time.Duration(max(0, min(s.End, end)-max(s.Start, start)))
+}
+
+// ComputeGCCycles returns one GCCycle per GC cycle in the trace, in chronological order. Mark worker time is taken
+// from processor spans and attributed to the cycles they overlap.
+func ComputeGCCycles(tr *Trace) []*GCCycle {
+	cycles := make([]*GCCycle, len(tr.GC))
+	for i, s := range tr.GC {
+		cycles[i] = &GCCycle{Mark: s, Start: s.Start, End: s.End}
+	}
+
+	// overlapping calls fn for each cycle that overlaps the span.
+	overlapping := func(s *Span, fn func(c *GCCycle)) {
+		i := sort.Search(len(cycles), func(i int) bool {
+			return cycles[i].End > s.Start
+		})
+		for ; i < len(cycles) && cycles[i].Start < s.End; i++ {
+			fn(cycles[i])
+		}
+	}
+
+	for i := range tr.STW {
+		s := &tr.STW[i]
+		overlapping(s, func(c *GCCycle) {
+			c.Pauses = append(c.Pauses, GCPause{Span: *s, Reason: STWReason(tr, s)})
+		})
+	}
+	// Extend the cycles to cover their pauses only after we've assigned all pauses, so that a cycle never claims
+	// the pauses of its neighbours.
+	for _, c := range cycles {
+		for _, p := range c.Pauses {
+			c.Start = min(c.Start, p.Span.Start)
+			c.End = max(c.End, p.Span.End)
+		}
+	}
+
+	for _, g := range tr.Goroutines {
+		for i := range g.Ranges["GC mark assist"] {
+			s := &g.Ranges["GC mark assist"][i]
+			overlapping(s, func(c *GCCycle) {
+				c.MarkAssist += overlap(s, c.Start, c.End)
+			})
+		}
+	}
+
+	for _, p := range tr.Processors {
+		for i := range p.Spans {
+			ps := &p.Spans[i]
+			if ps.State != StateProcRunningG {
+				continue
+			}
+			// The goroutine's span that started with the same event has the state of the mark worker, if the
+			// goroutine is one.
+			g := tr.G(tr.Event(ps.StartEvent).StateTransition().Resource.Goroutine())
+			j := sort.Search(len(g.Spans), func(j int) bool {
+				return g.Spans[j].Start >= ps.Start
+			})
+			var state SchedulingState
+			for ; j < len(g.Spans) && g.Spans[j].Start == ps.Start; j++ {
+				if g.Spans[j].StartEvent == ps.StartEvent {
+					state = g.Spans[j].State
+					break
+				}
+			}
+			if state != StateGCDedicated && state != StateGCFractional && state != StateGCIdle {
+				continue
+			}
+			overlapping(ps, func(c *GCCycle) {
+				d := overlap(ps, c.Start, c.End)
+				switch state {
+				case StateGCDedicated:
+					c.Dedicated += d
+				case StateGCFractional:
+					c.Fractional += d
+				case StateGCIdle:
+					c.Idle += d
+				}
+			})
+		}
+	}
+
+	heap := tr.Metrics["/memory/classes/heap/objects:bytes"]
+	goal := tr.Metrics["/gc/heap/goal:bytes"]
+	at := func(m *Metric, ts exptrace.Time) maybe.Option[uint64] {
+		if v, ok := m.At(ts); ok {
+			return maybe.Some(v)
+		}
+		return maybe.None[uint64]()
+	}
+	for _, c := range cycles {
+		c.HeapStart = at(&heap, c.Start)
+		c.HeapEnd = at(&heap, c.End)
+		c.HeapGoal = at(&goal, c.Start)
+	}
+
+	return cycles
+}
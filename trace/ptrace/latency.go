@@ -0,0 +1,130 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// WakeupReason describes what made a goroutine runnable.
+type WakeupReason uint8
+
+const (
+	// The goroutine was made runnable by the runtime, for example by a timer, without the trace telling us why.
+	WakeupOther WakeupReason = iota
+	// The goroutine was created.
+	WakeupCreated
+	// The goroutine was unblocked by another goroutine.
+	WakeupUnblocked
+	// The goroutine was preempted or yielded and became runnable again.
+	WakeupPreempted
+	// The goroutine was blocked on I/O and was woken up by the network poller.
+	WakeupNetpoll
+	// The goroutine returned from a syscall after its processor had been taken away.
+	WakeupSyscall
+
+	WakeupLast
+)
+
+func (r WakeupReason) String() string {
+	switch r {
+	case WakeupOther:
+		return "other"
+	case WakeupCreated:
+		return "creation"
+	case WakeupUnblocked:
+		return "unblocked by goroutine"
+	case WakeupPreempted:
+		return "preemption"
+	case WakeupNetpoll:
+		return "network poller"
+	case WakeupSyscall:
+		return "syscall return"
+	default:
+		return "unknown"
+	}
+}
+
+// SchedulingLatency is the time a goroutine spent waiting to be scheduled after it became runnable.
+type SchedulingLatency struct {
+	Goroutine *Goroutine
+	// The index of the span in Goroutine.Spans during which the goroutine was runnable.
+	Span   int
+	Reason WakeupReason
+	// The goroutine that created or unblocked the goroutine, or exptrace.NoGoroutine.
+	By      exptrace.GoID
+	Latency time.Duration
+}
+
+// Wakeup determines why the goroutine became runnable at the start of its span at index idx, and which goroutine
+// caused it, if any.
+func Wakeup(tr *Trace, g *Goroutine, idx int) (WakeupReason, exptrace.GoID) {
+	s := &g.Spans[idx]
+	if s.State == StateCreated {
+		return WakeupCreated, g.Parent
+	}
+
+	ev := tr.Event(s.StartEvent)
+	if ev.Kind() != exptrace.EventStateTransition {
+		return WakeupOther, exptrace.NoGoroutine
+	}
+	from, _ := ev.StateTransition().Goroutine()
+	var prev SchedulingState
+	if idx > 0 {
+		prev = g.Spans[idx-1].State
+	}
+	switch {
+	case from == exptrace.GoRunning || prev == StateWaitingPreempted:
+		return WakeupPreempted, exptrace.NoGoroutine
+	case from == exptrace.GoSyscall:
+		return WakeupSyscall, exptrace.NoGoroutine
+	case prev == StateBlockedNet:
+		return WakeupNetpoll, exptrace.NoGoroutine
+	}
+	if by := ev.Goroutine(); by != exptrace.NoGoroutine && by != g.ID {
+		return WakeupUnblocked, by
+	}
+	return WakeupOther, exptrace.NoGoroutine
+}
+
+// ComputeSchedulingLatencies computes the latencies of all the times the goroutines in gs, or all goroutines if gs is
+// nil, became runnable and were later scheduled. Goroutines that were still runnable at the end of the trace aren't
+// included. The latencies are sorted in descending order.
+func ComputeSchedulingLatencies(tr *Trace, gs []*Goroutine) []SchedulingLatency {
+	if gs == nil {
+		gs = tr.Goroutines
+	}
+
+	var out []SchedulingLatency
+	for _, g := range gs {
+		// The last span has no successor to tell us whether the goroutine got to run.
+		for i := range len(g.Spans) - 1 {
+			s := &g.Spans[i]
+			if s.State != StateReady && s.State != StateCreated {
+				continue
+			}
+			// Only count runnable spans that ended with the goroutine being scheduled.
+			next := tr.Event(g.Spans[i+1].StartEvent)
+			if next.Kind() != exptrace.EventStateTransition {
+				continue
+			}
+			if _, to := next.StateTransition().Goroutine(); to != exptrace.GoRunning {
+				continue
+			}
+			reason, by := Wakeup(tr, g, i)
+			out = append(out, SchedulingLatency{
+				Goroutine: g,
+				Span:      i,
+				Reason:    reason,
+				By:        by,
+				Latency:   s.Duration(),
+			})
+		}
+	}
+	slices.SortFunc(out, func(a, b SchedulingLatency) int {
+		return cmp.Compare(b.Latency, a.Latency)
+	})
+	return out
+}
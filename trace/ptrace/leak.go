@@ -0,0 +1,79 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// IsLeaked reports whether goroutine g blocked and never ran again until the end of the trace. This is how leaked
+// goroutines look, but goroutines that are waiting for work, or that blocked shortly before the end of the trace, look
+// the same.
+func IsLeaked(g *Goroutine) bool {
+	if g.End.Set() || len(g.Spans) == 0 {
+		return false
+	}
+	return IsBlocked(g.Spans[len(g.Spans)-1].State)
+}
+
+// LeakGroup is a group of leaked goroutines that run the same function and blocked in the same state with the same
+// stack.
+type LeakGroup struct {
+	Function *Function
+	State    SchedulingState
+	// The stack of the event that blocked one of the goroutines.
+	Stack exptrace.Stack
+	// The goroutines, sorted by the time they blocked at.
+	Goroutines []*Goroutine
+}
+
+// BlockedAt returns the time at which the i-th goroutine of the group blocked.
+func (lg *LeakGroup) BlockedAt(i int) exptrace.Time {
+	g := lg.Goroutines[i]
+	return g.Spans[len(g.Spans)-1].Start
+}
+
+// ComputeLeaks finds all goroutines that look leaked, as reported by IsLeaked, and groups them. The groups are sorted
+// by the number of goroutines, in descending order.
+func ComputeLeaks(tr *Trace) []*LeakGroup {
+	type key struct {
+		fn    *Function
+		state SchedulingState
+		stack string
+	}
+	groups := map[key]*LeakGroup{}
+	for _, g := range tr.Goroutines {
+		if !IsLeaked(g) {
+			continue
+		}
+		s := &g.Spans[len(g.Spans)-1]
+		var stk exptrace.Stack
+		if s.StartEvent != NoEvent {
+			stk = tr.Event(s.StartEvent).Stack()
+		}
+		k := key{g.Function, s.State, stackKey(tr, stk)}
+		lg, ok := groups[k]
+		if !ok {
+			lg = &LeakGroup{Function: g.Function, State: s.State, Stack: stk}
+			groups[k] = lg
+		}
+		lg.Goroutines = append(lg.Goroutines, g)
+	}
+
+	out := make([]*LeakGroup, 0, len(groups))
+	for _, lg := range groups {
+		slices.SortFunc(lg.Goroutines, func(a, b *Goroutine) int {
+			return cmp.Compare(a.Spans[len(a.Spans)-1].Start, b.Spans[len(b.Spans)-1].Start)
+		})
+		out = append(out, lg)
+	}
+	slices.SortFunc(out, func(a, b *LeakGroup) int {
+		if c := cmp.Compare(len(b.Goroutines), len(a.Goroutines)); c != 0 {
+			return c
+		}
+		// Break ties deterministically by the earliest blocking.
+		return cmp.Compare(a.BlockedAt(0), b.BlockedAt(0))
+	})
+	return out
+}
@@ -0,0 +1,19 @@
+package ptrace
+
+import "testing"
+
+func TestComputeLeaksGenerations(t *testing.T) {
+	tr := parseTestTrace(t, generationsTestTrace(t), nil)
+	var found []*LeakGroup
+	for _, lg := range ComputeLeaks(tr) {
+		if lg.Function != nil && lg.Function.Func == "honnef.co/go/gotraceui/trace/ptrace.blockForever" {
+			found = append(found, lg)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("got %d groups of goroutines leaked in blockForever, want 1", len(found))
+	}
+	if lg := found[0]; lg.State != StateBlockedRecv || len(lg.Goroutines) != 8 {
+		t.Errorf("got %d goroutines in state %d, want 8 in state %d", len(lg.Goroutines), lg.State, StateBlockedRecv)
+	}
+}
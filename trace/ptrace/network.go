@@ -0,0 +1,121 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// NetworkWait is a single time a goroutine blocked on the network.
+type NetworkWait struct {
+	Goroutine *Goroutine
+	// The index of the span in Goroutine.Spans.
+	Span int
+}
+
+// NetworkCategory aggregates the network waits whose spans have the same tags, such as all TCP reads.
+type NetworkCategory struct {
+	Tags  SpanTags
+	Waits []NetworkWait
+	// The durations of the waits, in ascending order.
+	Durations []time.Duration
+	Total     time.Duration
+}
+
+// NetworkSite aggregates the network waits of a category that blocked in the same place.
+type NetworkSite struct {
+	Category *NetworkCategory
+	// The PC of the frame the spans are attributed to, as determined by their At field, or 0 if the trace didn't
+	// record stacks.
+	PC    uint64
+	Waits []NetworkWait
+	Total time.Duration
+	Max   time.Duration
+}
+
+// NetworkWaits describes the time goroutines spent blocked on the network.
+type NetworkWaits struct {
+	// The categories and sites, sorted by their total wait time, in descending order.
+	Categories []*NetworkCategory
+	Sites      []*NetworkSite
+	Total      time.Duration
+	// The number of goroutines blocked on the network over time.
+	Concurrency Metric
+}
+
+// ComputeNetworkWaits groups the spans of goroutines blocked on the network by their tags, and by their tags and the
+// frame they're attributed to.
+func ComputeNetworkWaits(tr *Trace) *NetworkWaits {
+	type siteKey struct {
+		tags SpanTags
+		pc   uint64
+	}
+
+	var nw NetworkWaits
+	categories := map[SpanTags]*NetworkCategory{}
+	sites := map[siteKey]*NetworkSite{}
+	var gauge spanGauge
+	for _, g := range tr.Goroutines {
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			if s.State != StateBlockedNet {
+				continue
+			}
+			d := s.Duration()
+			w := NetworkWait{Goroutine: g, Span: i}
+
+			cat, ok := categories[s.Tags]
+			if !ok {
+				cat = &NetworkCategory{Tags: s.Tags}
+				categories[s.Tags] = cat
+			}
+			cat.Waits = append(cat.Waits, w)
+			cat.Durations = append(cat.Durations, d)
+			cat.Total += d
+
+			var pc uint64
+			if s.StartEvent != NoEvent {
+				if pcs := tr.Stacks[tr.Event(s.StartEvent).Stack()]; int(s.At) < len(pcs) {
+					pc = pcs[s.At]
+				}
+			}
+			site, ok := sites[siteKey{s.Tags, pc}]
+			if !ok {
+				site = &NetworkSite{Category: cat, PC: pc}
+				sites[siteKey{s.Tags, pc}] = site
+			}
+			site.Waits = append(site.Waits, w)
+			site.Total += d
+			site.Max = max(site.Max, d)
+
+			nw.Total += d
+			gauge.add(s)
+		}
+	}
+
+	for _, cat := range categories {
+		slices.Sort(cat.Durations)
+		nw.Categories = append(nw.Categories, cat)
+	}
+	slices.SortFunc(nw.Categories, func(a, b *NetworkCategory) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tags, b.Tags)
+	})
+	for _, site := range sites {
+		nw.Sites = append(nw.Sites, site)
+	}
+	slices.SortFunc(nw.Sites, func(a, b *NetworkSite) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Category.Tags, b.Category.Tags); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PC, b.PC)
+	})
+
+	nw.Concurrency = gauge.metric()
+	return &nw
+}
@@ -0,0 +1,92 @@
+package ptrace
+
+import (
+	"cmp"
+	"encoding/binary"
+	"slices"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// IsBlocked reports whether a goroutine in the given state is blocked, that is, waiting for something other than
+// being scheduled.
+func IsBlocked(state SchedulingState) bool {
+	switch state {
+	case StateBlocked, StateBlockedSend, StateBlockedRecv, StateBlockedSelect, StateBlockedSync, StateBlockedSyncOnce,
+		StateBlockedSyncTriggeringGC, StateBlockedCond, StateBlockedNet, StateBlockedGC, StateBlockedSyscall, StateStuck:
+		return true
+	default:
+		return false
+	}
+}
+
+// stackKey returns a key that identifies the PCs of a stack. Unlike exptrace.Stack, which is only valid within a
+// single generation, the key is the same for identical stacks in different generations.
+func stackKey(tr *Trace, stk exptrace.Stack) string {
+	pcs := tr.Stacks[stk]
+	b := make([]byte, 8*len(pcs))
+	for i, pc := range pcs {
+		binary.LittleEndian.PutUint64(b[8*i:], pc)
+	}
+	return string(b)
+}
+
+// OffCPUSample is the time goroutines spent blocked in the same state, having blocked with the same stack.
+type OffCPUSample struct {
+	// The stack of one of the blocking events.
+	Stack exptrace.Stack
+	State SchedulingState
+	// How often goroutines blocked, and how long they were blocked for in total.
+	Count    int
+	Duration time.Duration
+}
+
+// ComputeOffCPU aggregates the time that the goroutines in gs, or all goroutines if gs is nil, spent blocked between
+// start and end by the stacks at which they blocked and the states they blocked in. Blocking that extends past the
+// start or end only counts the time inside. If end is zero, it is the end of the trace. The samples are sorted by
+// duration, in descending order.
+func ComputeOffCPU(tr *Trace, gs []*Goroutine, start, end exptrace.Time) []OffCPUSample {
+	if gs == nil {
+		gs = tr.Goroutines
+	}
+	if end == 0 {
+		end = tr.End()
+	}
+
+	type key struct {
+		stack string
+		state SchedulingState
+	}
+	samples := map[key]*OffCPUSample{}
+	for _, g := range gs {
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			if !IsBlocked(s.State) || s.End <= start || s.Start >= end {
+				continue
+			}
+			d := time.Duration(min(s.End, end) - max(s.Start, start))
+			stk := tr.Event(s.StartEvent).Stack()
+			k := key{stackKey(tr, stk), s.State}
+			sample, ok := samples[k]
+			if !ok {
+				sample = &OffCPUSample{Stack: stk, State: s.State}
+				samples[k] = sample
+			}
+			sample.Count++
+			sample.Duration += d
+		}
+	}
+
+	out := make([]OffCPUSample, 0, len(samples))
+	for _, sample := range samples {
+		out = append(out, *sample)
+	}
+	slices.SortFunc(out, func(a, b OffCPUSample) int {
+		if c := cmp.Compare(b.Duration, a.Duration); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.State, b.State)
+	})
+	return out
+}
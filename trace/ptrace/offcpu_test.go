@@ -0,0 +1,66 @@
+package ptrace
+
+import (
+	"bytes"
+	rtrace "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	generationsTraceOnce sync.Once
+	generationsTraceData []byte
+	generationsTraceErr  error
+)
+
+//go:noinline
+func blockForever(ch chan struct{}) {
+	<-ch
+}
+
+// generationsTestTrace returns an execution trace of the test binary that spans two generations. In each generation,
+// four goroutines block in blockForever until the end of the trace.
+func generationsTestTrace(t *testing.T) []byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("recording a trace with several generations takes more than a second")
+	}
+	generationsTraceOnce.Do(func() {
+		var buf bytes.Buffer
+		if err := rtrace.Start(&buf); err != nil {
+			generationsTraceErr = err
+			return
+		}
+		ch := make(chan struct{})
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 4; j++ {
+				go blockForever(ch)
+			}
+			// The runtime starts a new generation roughly once per second.
+			time.Sleep(1200 * time.Millisecond)
+		}
+		rtrace.Stop()
+		close(ch)
+		generationsTraceData = buf.Bytes()
+	})
+	if generationsTraceErr != nil {
+		t.Fatal(generationsTraceErr)
+	}
+	return generationsTraceData
+}
+
+func TestComputeOffCPUGenerations(t *testing.T) {
+	tr := parseTestTrace(t, generationsTestTrace(t), nil)
+	fn, ok := tr.Functions["honnef.co/go/gotraceui/trace/ptrace.blockForever"]
+	if !ok {
+		t.Fatal("trace contains no goroutines running blockForever")
+	}
+	samples := ComputeOffCPU(tr, fn.Goroutines, 0, 0)
+	if len(samples) != 1 {
+		t.Fatalf("got %d samples, want 1: %v", len(samples), samples)
+	}
+	if s := samples[0]; s.State != StateBlockedRecv || s.Count != 8 {
+		t.Errorf("got %d blockings in state %d, want 8 in state %d", s.Count, s.State, StateBlockedRecv)
+	}
+}
@@ -1,264 +1,590 @@
 package ptrace
 
-type SpanTags uint8
-
-const (
-	SpanTagNetwork SpanTags = 1 << iota
-	SpanTagTCP
-	SpanTagTLS
-	SpanTagRead
-	SpanTagAccept
-	SpanTagDial
-	SpanTagHTTP
-
-	// Used for spans of GC goroutines, used when choosing span colors for processor timelines.
-	SpanTagGC
+import (
+	"fmt"
+	"math"
+	"strconv"
 )
 
-type pattern struct {
-	state SchedulingState
-	// fns looks for functions in the stack at absolute offsets
-	fns []string
+// Patterns refine the states of goroutine spans based on their stacks. For example, a goroutine blocked in
+// sync.(*Mutex).Lock called by sync.(*Once).doSlow is blocked on a sync.Once, not on an ordinary mutex.
+//
+// Patterns are written in a small S-expression language. A file of patterns consists of any number of rules of the
+// form
+//
+//	(Rule <state> <condition> <action>...)
+//
+// which apply to spans in the named state. Conditions are
+//
+//	(Frame <n> "<function>")   the frame at offset n, counting from the innermost frame, is in the function
+//	(Frames "<function>"...)   a run of consecutive frames, at any offset, is in the functions; _ matches any function
+//	(And <condition>...)       all conditions match
+//	(Or <condition>...)        at least one condition matches
+//	(Not <condition>)          the condition doesn't match
+//	_                          always matches
+//
+// and actions are
+//
+//	(State <state>)            changes the span's state
+//	(At <n>)                   attributes the span to the frame at offset n. The rule doesn't apply to stacks that
+//	                           have no such frame.
+//	(Tags <tag>...)            adds tags to the span
+//
+// Tags are either built in (read, accept, dial, network, tcp, tls, http, and gc) or declared with
+//
+//	(Tag <name> "<label>")
+//
+// before their first use, where the label is used when displaying the tag and defaults to the name. Declaring a tag
+// registers it with RegisterSpanTag once all patterns have been parsed successfully.
+//
+// States are named in lower case, with words separated by dashes, for example blocked-sync-once for
+// StateBlockedSyncOnce. Comments start with a semicolon and extend to the end of the line. All rules whose
+// conditions match a span apply to it, in the order in which they were defined.
+type Patterns struct {
+	byState [256][]pattern
+}
 
-	// relFns looks for runs of functions in the stack, at no particular offsets
-	relFns [][]string
+type pattern struct {
+	cond patternCond
 
 	newState SchedulingState
 	at       uint8
 	tags     SpanTags
 }
 
-// TODO(dh): implement a pattern language similar to that used in Staticcheck so that we can express ANDs and ORs
-// without having to write a bunch of Go.
-var patterns = [256][]pattern{
-	// XXX add a pattern for "GC incremental sweep" range, to skip some amount of frames
-	StateBlocked: {
-		{
-			state: StateBlocked,
-			fns: []string{
-				0: "runtime.ReadTrace",
-			},
-			newState: StateInactive,
-		},
-	},
-
-	StateBlockedRecv: {
-		{
-			state: StateBlockedRecv,
-			fns: []string{
-				0: "runtime.chanrecv1",
-			},
-			at: 1,
-		},
-		{
-			state: StateBlockedRecv,
-			fns: []string{
-				0: "runtime.chanrecv2",
-			},
-			at: 1,
-		},
-	},
-
-	StateBlockedSend: {
-		{
-			state: StateBlockedSend,
-			fns: []string{
-				0: "runtime.chansend1",
-			},
-			at: 1,
-		},
-	},
-
-	StateBlockedSync: {
-		{
-			state: StateBlockedSync,
-			fns: []string{
-				0: "runtime.gcStart",
-			},
-			newState: StateBlockedSyncTriggeringGC,
-		},
-		{
-			state: StateBlockedSync,
-			fns: []string{
-				0: "sync.(*Mutex).Lock",
-				1: "sync.(*Once).doSlow",
-				2: "sync.(*Once).Do",
-			},
-			newState: StateBlockedSyncOnce,
-			at:       3,
-		},
-	},
-
-	StateBlockedCond: {
-		{
-			state: StateBlockedCond,
-			fns: []string{
-				0: "sync.(*Cond).Wait",
-			},
-			at: 1,
-		},
-	},
-
-	StateBlockedNet: {
-		{
-			state: StateBlockedNet,
-			fns: []string{
-				0: "internal/poll.(*FD).Read",
-			},
-			tags: SpanTagRead,
-			at:   1,
-		},
-		{
-			state: StateBlockedNet,
-			fns: []string{
-				0: "internal/poll.(*FD).Read",
-				1: "net.(*netFD).Read",
-			},
-			tags: SpanTagNetwork,
-			at:   2,
-		},
-		{
-			state: StateBlockedNet,
-			fns: []string{
-				0: "internal/poll.(*FD).Accept",
-			},
-			tags: SpanTagAccept,
-			at:   1,
-		},
-		{
-			state: StateBlockedNet,
-			fns: []string{
-				0: "internal/poll.(*FD).Accept",
-				1: "net.(*netFD).accept",
-			},
-			at:   2,
-			tags: SpanTagNetwork,
-		},
-		{
-			state: StateBlockedNet,
-			fns: []string{
-				0: "internal/poll.(*FD).Accept",
-				2: "net.(*TCPListener).accept",
-				3: "net.(*TCPListener).Accept",
-			},
-			at:   4,
-			tags: SpanTagTCP,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net.(*sysDialer).dialSingle"},
-			},
-			tags: SpanTagDial,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net.(*sysDialer).dialTCP"},
-			},
-			tags: SpanTagTCP,
-		},
-
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"crypto/tls.(*Conn).readFromUntil"},
-			},
-			tags: SpanTagTLS,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"crypto/tls.(*listener).Accept"},
-			},
-			tags: SpanTagTLS,
-		},
-
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net/http.(*connReader).Read"},
-			},
-			tags: SpanTagHTTP,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net/http.(*persistConn).Read"},
-			},
-			tags: SpanTagHTTP,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net/http.(*http2clientConnReadLoop).run"},
-			},
-			tags: SpanTagHTTP,
-		},
-		{
-			state: StateBlockedNet,
-			relFns: [][]string{
-				{"net/http.(*Server).Serve"},
-			},
-			tags: SpanTagHTTP,
-		},
-	},
-
-	StateBlockedSelect: {
-		{
-			state: StateBlockedSelect,
-			at:    1,
-		},
-	},
+type patternCond interface {
+	match(tr *Trace, pcs []uint64) bool
 }
 
-func applyPatterns(tr *Trace, s Span, pcs []uint64) Span {
-	// OPT(dh): be better than O(n)
+type (
+	// patternFrame matches a function at an absolute offset in the stack.
+	patternFrame struct {
+		offset int
+		fn     string
+	}
+	// patternFrames matches a run of functions at no particular offset. Empty strings match any function.
+	patternFrames struct {
+		fns []string
+	}
+	patternAnd []patternCond
+	patternOr  []patternCond
+	patternNot struct {
+		cond patternCond
+	}
+	patternAny struct{}
+)
 
-patternLoop:
-	for _, p := range patterns[s.State] {
-		if len(pcs) < len(p.fns) {
-			continue
+func (c patternFrame) match(tr *Trace, pcs []uint64) bool {
+	return c.offset < len(pcs) && tr.PCs[pcs[c.offset]].Func == c.fn
+}
+
+func (c patternFrames) match(tr *Trace, pcs []uint64) bool {
+	// OPT(dh): be better than O(n²)
+offsetLoop:
+	for start := range pcs {
+		if len(pcs)-start < len(c.fns) {
+			break
 		}
 
-		for i, fn := range p.fns {
+		for i, fn := range c.fns {
 			if fn == "" {
 				continue
 			}
-			if tr.PCs[pcs[i]].Func != fn {
-				continue patternLoop
+			if tr.PCs[pcs[i+start]].Func != fn {
+				continue offsetLoop
 			}
 		}
 
-		for _, relFns := range p.relFns {
-			matched := false
+		return true
+	}
+	return false
+}
 
-			// OPT(dh): be better than O(n²)
-		offsetLoop:
-			for start := range pcs {
-				if len(pcs)-start < len(relFns) {
-					break
-				}
+func (c patternAnd) match(tr *Trace, pcs []uint64) bool {
+	for _, cc := range c {
+		if !cc.match(tr, pcs) {
+			return false
+		}
+	}
+	return true
+}
 
-				for i, fn := range relFns {
-					if fn == "" {
-						continue
-					}
-					if tr.PCs[pcs[i+start]].Func != fn {
-						continue offsetLoop
-					}
-				}
+func (c patternOr) match(tr *Trace, pcs []uint64) bool {
+	for _, cc := range c {
+		if cc.match(tr, pcs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c patternNot) match(tr *Trace, pcs []uint64) bool { return !c.cond.match(tr, pcs) }
+func (patternAny) match(tr *Trace, pcs []uint64) bool   { return true }
+
+// patternStates maps the names used by the pattern language to states. Only goroutine states can be used.
+var patternStates = map[string]SchedulingState{
+	"undetermined":               StateUndetermined,
+	"inactive":                   StateInactive,
+	"active":                     StateActive,
+	"gc-idle":                    StateGCIdle,
+	"gc-dedicated":               StateGCDedicated,
+	"gc-fractional":              StateGCFractional,
+	"blocked":                    StateBlocked,
+	"blocked-send":               StateBlockedSend,
+	"blocked-recv":               StateBlockedRecv,
+	"blocked-select":             StateBlockedSelect,
+	"blocked-sync":               StateBlockedSync,
+	"blocked-sync-once":          StateBlockedSyncOnce,
+	"blocked-sync-triggering-gc": StateBlockedSyncTriggeringGC,
+	"blocked-cond":               StateBlockedCond,
+	"blocked-net":                StateBlockedNet,
+	"blocked-gc":                 StateBlockedGC,
+	"blocked-syscall":            StateBlockedSyscall,
+	"waiting-preempted":          StateWaitingPreempted,
+	"stuck":                      StateStuck,
+	"ready":                      StateReady,
+	"created":                    StateCreated,
+	"gc-mark-assist":             StateGCMarkAssist,
+	"gc-sweep":                   StateGCSweep,
+}
 
-				matched = true
+// XXX add a pattern for "GC incremental sweep" range, to skip some amount of frames
+const builtinPatternsSource = `
+; The goroutine reading the trace isn't interesting.
+(Rule blocked (Frame 0 "runtime.ReadTrace") (State inactive))
+
+(Rule blocked-recv (Or (Frame 0 "runtime.chanrecv1") (Frame 0 "runtime.chanrecv2")) (At 1))
+(Rule blocked-send (Frame 0 "runtime.chansend1") (At 1))
+(Rule blocked-select _ (At 1))
+
+(Rule blocked-sync (Frame 0 "runtime.gcStart") (State blocked-sync-triggering-gc))
+(Rule blocked-sync
+	(Or
+		(Frame 0 "sync.(*Mutex).Lock")
+		(Frame 0 "sync.(*RWMutex).Lock")
+		(Frame 0 "sync.(*RWMutex).RLock")
+		(Frame 0 "sync.(*WaitGroup).Wait"))
+	(At 1))
+(Rule blocked-sync
+	(And
+		(Frame 0 "sync.(*Mutex).Lock")
+		(Frame 1 "sync.(*Once).doSlow")
+		(Frame 2 "sync.(*Once).Do"))
+	(State blocked-sync-once)
+	(At 3))
+(Rule blocked-cond (Frame 0 "sync.(*Cond).Wait") (At 1))
+
+(Rule blocked-net (Frame 0 "internal/poll.(*FD).Read") (Tags read) (At 1))
+(Rule blocked-net
+	(And (Frame 0 "internal/poll.(*FD).Read") (Frame 1 "net.(*netFD).Read"))
+	(Tags network)
+	(At 2))
+(Rule blocked-net (Frame 0 "internal/poll.(*FD).Accept") (Tags accept) (At 1))
+(Rule blocked-net
+	(And (Frame 0 "internal/poll.(*FD).Accept") (Frame 1 "net.(*netFD).accept"))
+	(Tags network)
+	(At 2))
+(Rule blocked-net
+	(And
+		(Frame 0 "internal/poll.(*FD).Accept")
+		(Frame 2 "net.(*TCPListener).accept")
+		(Frame 3 "net.(*TCPListener).Accept"))
+	(Tags tcp)
+	(At 4))
+(Rule blocked-net (Frames "net.(*sysDialer).dialSingle") (Tags dial))
+(Rule blocked-net (Frames "net.(*sysDialer).dialTCP") (Tags tcp))
+(Rule blocked-net
+	(Or
+		(Frames "crypto/tls.(*Conn).readFromUntil")
+		(Frames "crypto/tls.(*listener).Accept"))
+	(Tags tls))
+(Rule blocked-net
+	(Or
+		(Frames "net/http.(*connReader).Read")
+		(Frames "net/http.(*persistConn).Read")
+		(Frames "net/http.(*http2clientConnReadLoop).run")
+		(Frames "net/http.(*Server).Serve"))
+	(Tags http))
+`
+
+// patterns are the patterns used by Parse. They consist of the built-in patterns, followed by any patterns added
+// with AddPatterns.
+var patterns = func() *Patterns {
+	ps, err := ParsePatterns("builtin", []byte(builtinPatternsSource))
+	if err != nil {
+		panic(fmt.Sprintf("couldn't parse built-in patterns: %s", err))
+	}
+	return ps
+}()
+
+// AddPatterns adds patterns to be used by all future calls to Parse, after the built-in patterns. It must not be
+// called concurrently with Parse.
+func AddPatterns(ps *Patterns) {
+	for state, sps := range ps.byState {
+		patterns.byState[state] = append(patterns.byState[state], sps...)
+	}
+}
+
+// A PatternError describes a syntax error or other problem in a file of patterns.
+type PatternError struct {
+	Name   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (err *PatternError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", err.Name, err.Line, err.Column, err.Msg)
+}
+
+type patternNodeKind uint8
+
+const (
+	patternNodeList patternNodeKind = iota
+	patternNodeAtom
+	patternNodeString
+)
+
+type patternNode struct {
+	kind  patternNodeKind
+	line  int
+	col   int
+	value string
+	list  []patternNode
+}
+
+type patternParser struct {
+	name string
+	src  []byte
+	off  int
+	line int
+	col  int
+
+	// Tags declared by the patterns. They only get registered once all patterns have been parsed successfully.
+	tags    []SpanTagInfo
+	nextTag SpanTags
+}
+
+func (p *patternParser) errorf(line, col int, format string, args ...any) error {
+	return &PatternError{
+		Name:   p.name,
+		Line:   line,
+		Column: col,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *patternParser) advance() byte {
+	b := p.src[p.off]
+	p.off++
+	if b == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	return b
+}
+
+func (p *patternParser) skipSpace() {
+	for p.off < len(p.src) {
+		switch b := p.src[p.off]; {
+		case b == ';':
+			for p.off < len(p.src) && p.src[p.off] != '\n' {
+				p.advance()
+			}
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			p.advance()
+		default:
+			return
+		}
+	}
+}
+
+func (p *patternParser) node() (patternNode, error) {
+	line, col := p.line, p.col
+	switch b := p.src[p.off]; b {
+	case '(':
+		p.advance()
+		n := patternNode{kind: patternNodeList, line: line, col: col}
+		for {
+			p.skipSpace()
+			if p.off == len(p.src) {
+				return patternNode{}, p.errorf(line, col, "unterminated list")
+			}
+			if p.src[p.off] == ')' {
+				p.advance()
+				return n, nil
+			}
+			child, err := p.node()
+			if err != nil {
+				return patternNode{}, err
+			}
+			n.list = append(n.list, child)
+		}
+	case ')':
+		return patternNode{}, p.errorf(line, col, "unexpected )")
+	case '"':
+		start := p.off
+		p.advance()
+		for {
+			if p.off == len(p.src) || p.src[p.off] == '\n' {
+				return patternNode{}, p.errorf(line, col, "unterminated string")
+			}
+			b := p.advance()
+			if b == '\\' && p.off < len(p.src) {
+				p.advance()
+			} else if b == '"' {
+				break
+			}
+		}
+		s, err := strconv.Unquote(string(p.src[start:p.off]))
+		if err != nil {
+			return patternNode{}, p.errorf(line, col, "invalid string: %s", err)
+		}
+		return patternNode{kind: patternNodeString, line: line, col: col, value: s}, nil
+	default:
+		start := p.off
+		for p.off < len(p.src) {
+			b := p.src[p.off]
+			if b == '(' || b == ')' || b == '"' || b == ';' || b == ' ' || b == '\t' || b == '\n' || b == '\r' {
 				break
 			}
+			p.advance()
+		}
+		return patternNode{kind: patternNodeAtom, line: line, col: col, value: string(p.src[start:p.off])}, nil
+	}
+}
+
+// ParsePatterns parses patterns written in the pattern language described in the documentation of Patterns. name is
+// used in error messages and is usually the name of the file that src was read from.
+func ParsePatterns(name string, src []byte) (*Patterns, error) {
+	p := &patternParser{
+		name: name,
+		src:  src,
+		line: 1,
+		col:  1,
+
+		nextTag: nextSpanTag,
+	}
+	ps := &Patterns{}
+	for {
+		p.skipSpace()
+		if p.off == len(p.src) {
+			break
+		}
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		if head, ok := p.listHead(n); ok && head == "Tag" {
+			if err := p.declareTag(n); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		state, pat, err := p.compileRule(n)
+		if err != nil {
+			return nil, err
+		}
+		ps.byState[state] = append(ps.byState[state], pat)
+	}
+	for _, info := range p.tags {
+		if _, err := RegisterSpanTag(info.Name, info.Label); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+func (p *patternParser) listHead(n patternNode) (string, bool) {
+	if n.kind != patternNodeList || len(n.list) == 0 || n.list[0].kind != patternNodeAtom {
+		return "", false
+	}
+	return n.list[0].value, true
+}
+
+func (p *patternParser) declareTag(n patternNode) error {
+	args := n.list[1:]
+	if len(args) < 1 || len(args) > 2 || args[0].kind != patternNodeAtom || (len(args) == 2 && args[1].kind != patternNodeString) {
+		return p.errorf(n.line, n.col, `expected (Tag <name> "<label>")`)
+	}
+	name := args[0].value
+	label := name
+	if len(args) == 2 {
+		label = args[1].value
+	}
+	if _, ok := p.lookupTag(name); ok {
+		return nil
+	}
+	// Assign tags the same way RegisterSpanTag will, so that rules can refer to them before they're registered.
+	if p.nextTag == 0 {
+		return p.errorf(n.line, n.col, "couldn't register span tag %q: at most %d span tags are supported", name, MaxSpanTags)
+	}
+	p.tags = append(p.tags, SpanTagInfo{Tag: p.nextTag, Name: name, Label: label})
+	p.nextTag <<= 1
+	return nil
+}
+
+// lookupTag is like LookupSpanTag, but also returns tags that have been declared but not yet registered.
+func (p *patternParser) lookupTag(name string) (SpanTags, bool) {
+	for _, info := range p.tags {
+		if info.Name == name {
+			return info.Tag, true
+		}
+	}
+	return LookupSpanTag(name)
+}
+
+func (p *patternParser) compileRule(n patternNode) (SchedulingState, pattern, error) {
+	if head, ok := p.listHead(n); !ok || head != "Rule" {
+		return 0, pattern{}, p.errorf(n.line, n.col, "expected (Rule ...) or (Tag ...)")
+	}
+	if len(n.list) < 3 {
+		return 0, pattern{}, p.errorf(n.line, n.col, "Rule needs a state and a condition")
+	}
+	state, err := p.compileState(n.list[1])
+	if err != nil {
+		return 0, pattern{}, err
+	}
+	cond, err := p.compileCond(n.list[2])
+	if err != nil {
+		return 0, pattern{}, err
+	}
+	pat := pattern{cond: cond}
+	for _, action := range n.list[3:] {
+		head, ok := p.listHead(action)
+		if !ok {
+			return 0, pattern{}, p.errorf(action.line, action.col, "expected action")
+		}
+		args := action.list[1:]
+		switch head {
+		case "State":
+			if len(args) != 1 {
+				return 0, pattern{}, p.errorf(action.line, action.col, "State needs exactly one state")
+			}
+			pat.newState, err = p.compileState(args[0])
+			if err != nil {
+				return 0, pattern{}, err
+			}
+		case "At":
+			if len(args) != 1 {
+				return 0, pattern{}, p.errorf(action.line, action.col, "At needs exactly one offset")
+			}
+			at, err := p.compileOffset(args[0], 255)
+			if err != nil {
+				return 0, pattern{}, err
+			}
+			pat.at = uint8(at)
+		case "Tags":
+			for _, arg := range args {
+				if arg.kind != patternNodeAtom {
+					return 0, pattern{}, p.errorf(arg.line, arg.col, "expected tag")
+				}
+				tag, ok := p.lookupTag(arg.value)
+				if !ok {
+					return 0, pattern{}, p.errorf(arg.line, arg.col, "unknown tag %q", arg.value)
+				}
+				pat.tags |= tag
+			}
+		default:
+			return 0, pattern{}, p.errorf(action.line, action.col, "unknown action %q", head)
+		}
+	}
+	return state, pat, nil
+}
+
+func (p *patternParser) compileState(n patternNode) (SchedulingState, error) {
+	if n.kind != patternNodeAtom {
+		return 0, p.errorf(n.line, n.col, "expected state")
+	}
+	state, ok := patternStates[n.value]
+	if !ok {
+		return 0, p.errorf(n.line, n.col, "unknown state %q", n.value)
+	}
+	return state, nil
+}
+
+func (p *patternParser) compileOffset(n patternNode, max int) (int, error) {
+	if n.kind != patternNodeAtom {
+		return 0, p.errorf(n.line, n.col, "expected offset")
+	}
+	off, err := strconv.Atoi(n.value)
+	if err != nil || off < 0 || off > max {
+		return 0, p.errorf(n.line, n.col, "invalid offset %q", n.value)
+	}
+	return off, nil
+}
 
-			if !matched {
-				continue patternLoop
+func (p *patternParser) compileCond(n patternNode) (patternCond, error) {
+	if n.kind == patternNodeAtom && n.value == "_" {
+		return patternAny{}, nil
+	}
+	head, ok := p.listHead(n)
+	if !ok {
+		return nil, p.errorf(n.line, n.col, "expected condition")
+	}
+	args := n.list[1:]
+	switch head {
+	case "Frame":
+		if len(args) != 2 || args[1].kind != patternNodeString {
+			return nil, p.errorf(n.line, n.col, `expected (Frame <offset> "<function>")`)
+		}
+		off, err := p.compileOffset(args[0], math.MaxInt)
+		if err != nil {
+			return nil, err
+		}
+		return patternFrame{offset: off, fn: args[1].value}, nil
+	case "Frames":
+		if len(args) == 0 {
+			return nil, p.errorf(n.line, n.col, "Frames needs at least one function")
+		}
+		fns := make([]string, len(args))
+		for i, arg := range args {
+			switch {
+			case arg.kind == patternNodeString:
+				fns[i] = arg.value
+			case arg.kind == patternNodeAtom && arg.value == "_":
+				fns[i] = ""
+			default:
+				return nil, p.errorf(arg.line, arg.col, "expected function or _")
 			}
 		}
+		return patternFrames{fns: fns}, nil
+	case "And", "Or":
+		if len(args) == 0 {
+			return nil, p.errorf(n.line, n.col, "%s needs at least one condition", head)
+		}
+		conds := make([]patternCond, len(args))
+		for i, arg := range args {
+			cond, err := p.compileCond(arg)
+			if err != nil {
+				return nil, err
+			}
+			conds[i] = cond
+		}
+		if head == "And" {
+			return patternAnd(conds), nil
+		}
+		return patternOr(conds), nil
+	case "Not":
+		if len(args) != 1 {
+			return nil, p.errorf(n.line, n.col, "Not needs exactly one condition")
+		}
+		cond, err := p.compileCond(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return patternNot{cond}, nil
+	default:
+		return nil, p.errorf(n.line, n.col, "unknown condition %q", head)
+	}
+}
 
-		if p.at != 0 && int(p.at) >= len(pcs) {
+func applyPatterns(tr *Trace, s Span, pcs []uint64) Span {
+	// OPT(dh): be better than O(n)
+	for _, p := range patterns.byState[s.State] {
+		if !p.cond.match(tr, pcs) {
 			continue
 		}
 
@@ -0,0 +1,196 @@
+package ptrace
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+func TestParsePatterns(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[SchedulingState][]pattern
+	}{
+		{"empty", "", nil},
+		{"only comments", "; nothing\n  ; to see here\n", nil},
+		{
+			"frame",
+			`(Rule blocked-recv (Frame 0 "runtime.chanrecv1") (At 1))`,
+			map[SchedulingState][]pattern{
+				StateBlockedRecv: {{cond: patternFrame{0, "runtime.chanrecv1"}, at: 1}},
+			},
+		},
+		{
+			"frames with wildcard",
+			`(Rule blocked-net (Frames "a" _ "b") (Tags read network))`,
+			map[SchedulingState][]pattern{
+				StateBlockedNet: {{cond: patternFrames{[]string{"a", "", "b"}}, tags: SpanTagRead | SpanTagNetwork}},
+			},
+		},
+		{
+			"nested conditions",
+			`(Rule blocked-sync (And (Frame 0 "a") (Or (Frame 1 "b") (Not (Frame 1 "c")))) (State blocked-sync-once) (At 2))`,
+			map[SchedulingState][]pattern{
+				StateBlockedSync: {{
+					cond: patternAnd{
+						patternFrame{0, "a"},
+						patternOr{patternFrame{1, "b"}, patternNot{patternFrame{1, "c"}}},
+					},
+					newState: StateBlockedSyncOnce,
+					at:       2,
+				}},
+			},
+		},
+		{
+			"rules keep their order",
+			"(Rule blocked-select _ (At 1))\n(Rule blocked-select _ (At 2))\n(Rule blocked _)",
+			map[SchedulingState][]pattern{
+				StateBlockedSelect: {{cond: patternAny{}, at: 1}, {cond: patternAny{}, at: 2}},
+				StateBlocked:       {{cond: patternAny{}}},
+			},
+		},
+		{
+			"escaped string",
+			`(Rule blocked (Frame 0 "a\"b") (State inactive))`,
+			map[SchedulingState][]pattern{
+				StateBlocked: {{cond: patternFrame{0, `a"b`}, newState: StateInactive}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := ParsePatterns("test", []byte(tt.src))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for state, got := range ps.byState {
+				want := tt.want[SchedulingState(state)]
+				if len(got) == 0 && len(want) == 0 {
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("state %d: got %#v, want %#v", state, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePatternsTag(t *testing.T) {
+	ps, err := ParsePatterns("test", []byte(`
+(Tag pattern-test "Pattern test")
+(Rule blocked _ (Tags pattern-test))`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, ok := LookupSpanTag("pattern-test")
+	if !ok {
+		t.Fatal("tag wasn't registered")
+	}
+	if got := ps.byState[StateBlocked][0].tags; got != tag {
+		t.Errorf("got tags %b, want %b", got, tag)
+	}
+}
+
+func TestParsePatternsTagAfterError(t *testing.T) {
+	_, err := ParsePatterns("test", []byte(`
+(Tag pattern-test-error "Pattern test")
+(Rule blocked _ (Tags pattern-test-error))
+(Rule nope _)`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, ok := LookupSpanTag("pattern-test-error"); ok {
+		t.Error("tag was registered despite the error")
+	}
+}
+
+func TestParsePatternsErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		line, col int
+		msg       string
+	}{
+		{"unterminated list", `(Rule blocked _`, 1, 1, "unterminated list"},
+		{"unexpected paren", `)`, 1, 1, "unexpected )"},
+		{"unterminated string", "(Rule blocked (Frame 0 \"a\n\"))", 1, 24, "unterminated string"},
+		{"not a rule", `(Foo)`, 1, 1, "expected (Rule ...) or (Tag ...)"},
+		{"atom at top level", `Rule`, 1, 1, "expected (Rule ...) or (Tag ...)"},
+		{"missing condition", `(Rule blocked)`, 1, 1, "Rule needs a state and a condition"},
+		{"unknown state", `(Rule blocked-forever _)`, 1, 7, `unknown state "blocked-forever"`},
+		{"unknown condition", `(Rule blocked (Frame2 0 "a"))`, 1, 15, `unknown condition "Frame2"`},
+		{"bad frame", `(Rule blocked (Frame "a"))`, 1, 15, `expected (Frame <offset> "<function>")`},
+		{"negative offset", `(Rule blocked (Frame -1 "a"))`, 1, 22, `invalid offset "-1"`},
+		{"offset too large", `(Rule blocked _ (At 256))`, 1, 21, `invalid offset "256"`},
+		{"empty and", `(Rule blocked (And))`, 1, 15, "And needs at least one condition"},
+		{"empty frames", `(Rule blocked (Frames))`, 1, 15, "Frames needs at least one function"},
+		{"bad frames", `(Rule blocked (Frames 1))`, 1, 23, "expected function or _"},
+		{"not with two conditions", `(Rule blocked (Not _ _))`, 1, 15, "Not needs exactly one condition"},
+		{"unknown action", `(Rule blocked _ (Color red))`, 1, 17, `unknown action "Color"`},
+		{"two states", `(Rule blocked _ (State inactive active))`, 1, 17, "State needs exactly one state"},
+		{"unknown tag", `(Rule blocked _ (Tags no-such-tag))`, 1, 23, `unknown tag "no-such-tag"`},
+		{"bad tag declaration", `(Tag "name")`, 1, 1, `expected (Tag <name> "<label>")`},
+		{"position after newlines", "; comment\n\n  (Rule nope _)", 3, 9, `unknown state "nope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePatterns("test", []byte(tt.src))
+			var perr *PatternError
+			if !errors.As(err, &perr) {
+				t.Fatalf("got error %v, want a *PatternError", err)
+			}
+			want := PatternError{Name: "test", Line: tt.line, Column: tt.col, Msg: tt.msg}
+			if *perr != want {
+				t.Errorf("got %q, want %q", perr, &want)
+			}
+		})
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tr := &Trace{
+		PCs: map[uint64]exptrace.StackFrame{
+			1: {Func: "runtime.gopark"},
+			2: {Func: "sync.(*Mutex).Lock"},
+			3: {Func: "sync.(*Once).doSlow"},
+			4: {Func: "sync.(*Once).Do"},
+			5: {Func: "main.main"},
+		},
+	}
+	pcs := []uint64{2, 3, 4, 5}
+
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{`_`, true},
+		{`(Frame 0 "sync.(*Mutex).Lock")`, true},
+		{`(Frame 1 "sync.(*Mutex).Lock")`, false},
+		{`(Frame 4 "main.main")`, false},
+		{`(Frames "sync.(*Once).Do" "main.main")`, true},
+		{`(Frames "sync.(*Once).doSlow" _ "main.main")`, true},
+		{`(Frames "main.main" "sync.(*Once).Do")`, false},
+		{`(Frames "main.main" _)`, false},
+		{`(And (Frame 0 "sync.(*Mutex).Lock") (Frame 3 "main.main"))`, true},
+		{`(And (Frame 0 "sync.(*Mutex).Lock") (Frame 3 "runtime.gopark"))`, false},
+		{`(Or (Frame 0 "runtime.gopark") (Frame 3 "main.main"))`, true},
+		{`(Not (Frames "runtime.gopark"))`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			ps, err := ParsePatterns("test", []byte("(Rule blocked "+tt.cond+")"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ps.byState[StateBlocked][0].cond.match(tr, pcs); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -75,6 +75,26 @@ type Metric struct {
 	Values     []uint64
 }
 
+// Peak returns the highest value of the metric and when it first occurred.
+func (m *Metric) Peak() (v uint64, ts exptrace.Time) {
+	for i, vv := range m.Values {
+		if vv > v {
+			v = vv
+			ts = m.Timestamps[i]
+		}
+	}
+	return v, ts
+}
+
+// At returns the last value of the metric recorded at or before ts.
+func (m *Metric) At(ts exptrace.Time) (uint64, bool) {
+	n, _ := slices.BinarySearch(m.Timestamps, ts+1)
+	if n == 0 {
+		return 0, false
+	}
+	return m.Values[n-1], true
+}
+
 type Trace struct {
 	// OPT(dh): can we get rid of all these pointers?
 	Goroutines    []*Goroutine
@@ -91,6 +111,10 @@ type Trace struct {
 	Events        mem.LargeBucketSlice[exptrace.Event]
 	PCs           map[uint64]exptrace.StackFrame
 	Stacks        map[exptrace.Stack][]uint64
+	// Ranges that aren't scoped to a goroutine, processor, or thread, keyed by their names.
+	Ranges map[string][]Span
+	// Allocations recorded by the AllocFree tracing experiment, if it was enabled.
+	Allocations Allocations
 
 	gsByID map[exptrace.GoID]*Goroutine
 	// psByID and msById will be unset after parsing finishes
@@ -261,8 +285,13 @@ type Machine struct {
 	// OPT(dh): using Span for Ms is wasteful. We don't need tags, stacktrace offsets etc. We only care about what
 	// processor is running at what time. The only benefit of reusing Span is that we can use the same code for
 	// rendering Gs and Ms, but that doesn't seem worth the added cost.
-	Spans      []Span
+
+	// The processors held by the machine. The spans' start events are the processors' state transitions.
+	Spans []Span
+	// The goroutines running on the machine, with the states StateActive and StateBlockedSyscall. The spans' start
+	// events are the goroutines' state transitions.
 	Goroutines []Span
+	Ranges     map[string][]Span
 }
 
 type Processor struct {
@@ -369,9 +398,10 @@ type Span struct {
 	// matching to stack traces that may result in more accurate states. For example, we can determine
 	// stateBlockedSyncOnce from the stack trace, and we would otherwise use stateBlockedSync.
 	State SchedulingState
-	Tags  SpanTags
 	// The kind of span. This primarily affects the kinds of events you can find in StartEvent and EndEvent.
 	Kind SpanKind
+	// Tags has to come last so that it fits into the padding after the single-byte fields.
+	Tags SpanTags
 }
 
 type SpanKind uint8
@@ -389,19 +419,8 @@ type EventID int32
 const NoEvent EventID = -1
 
 func Parse(r *exptrace.Reader, progress func(float64)) (*Trace, error) {
-	tr := &Trace{
-		Functions:     map[string]*Function{},
-		gsByID:        map[exptrace.GoID]*Goroutine{},
-		psByID:        map[exptrace.ProcID]*Processor{},
-		msByID:        map[exptrace.ThreadID]*Machine{},
-		CPUSamplesByG: map[exptrace.GoID][]EventID{},
-		CPUSamplesByP: map[exptrace.ProcID][]EventID{},
-		Metrics:       map[string]Metric{},
-		GC:            make(spansSlice, 0),
-		STW:           make(spansSlice, 0),
-		PCs:           make(map[uint64]exptrace.StackFrame),
-		Stacks:        make(map[exptrace.Stack][]uint64),
-	}
+	p := NewParser()
+	tr := p.tr
 
 	makeProgresser := func(stage int, numStages int) func(float64) {
 		return func(p float64) {
@@ -412,12 +431,10 @@ func Parse(r *exptrace.Reader, progress func(float64)) (*Trace, error) {
 		}
 	}
 
-	if err := processEvents(r, tr, makeProgresser(1, 4)); err != nil {
+	if err := p.readEvents(r, makeProgresser(1, 4)); err != nil {
 		return nil, err
 	}
-
-	populateObjects(tr, makeProgresser(2, 4))
-	postProcessSpans(tr, makeProgresser(3, 4))
+	p.finish(makeProgresser(2, 4), makeProgresser(3, 4))
 
 	tr.psByID = nil
 	tr.msByID = nil
@@ -450,7 +467,9 @@ func rangeActualScope(r exptrace.Range) rangeScope {
 	case "GC concurrent mark phase":
 		return rangeScopeGC
 	case "GC incremental sweep":
-		// XXX
+		// Sweeping is done by whatever goroutine is running on the P, for example as part of allocating. The range
+		// is scoped to the P, which is also what the tracer reports.
+		return rangeScopeProc
 	}
 
 	switch r.Scope.Kind {
@@ -486,69 +505,117 @@ func (rg *runningGauge) add(t exptrace.Time, v int64) {
 	}
 }
 
+// spanGauge counts the number of overlapping spans over time.
+type spanGauge struct {
+	deltas []spanGaugeDelta
+}
+
+type spanGaugeDelta struct {
+	t exptrace.Time
+	v int64
+}
+
+// add adds a span to the gauge. Spans can be added in any order.
+func (sg *spanGauge) add(s *Span) {
+	sg.deltas = append(sg.deltas, spanGaugeDelta{s.Start, 1}, spanGaugeDelta{s.End, -1})
+}
+
+func (sg *spanGauge) metric() Metric {
+	// Process ends before starts at the same timestamp, so that back to back spans don't count twice.
+	slices.SortFunc(sg.deltas, func(a, b spanGaugeDelta) int {
+		if c := cmp.Compare(a.t, b.t); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.v, b.v)
+	})
+	var rg runningGauge
+	for _, d := range sg.deltas {
+		rg.add(d.t, d.v)
+	}
+	return Metric{Timestamps: rg.timestamps, Values: rg.values}
+}
+
 type goroutineMetrics struct {
 	runnableGoroutines runningGauge
 	runningGoroutines  runningGauge
 	blockedGoroutines  runningGauge
 }
 
-func processEvents(r *exptrace.Reader, tr *Trace, progress func(float64)) error {
-	// OPT(dh): evaluate reading all events in one pass, then preallocating []Span slices based on the number
-	// of events we saw for Ps and Gs.
-	getG := func(gid exptrace.GoID) *Goroutine {
-		g, ok := tr.gsByID[gid]
-		if ok {
-			return g
-		}
-		g = &Goroutine{
-			ID: gid,
-			// XXX only allocate map once we have a range to store
-			Ranges: map[string][]Span{},
-		}
-		tr.gsByID[gid] = g
-		return g
-	}
-	getP := func(pid exptrace.ProcID) *Processor {
-		p, ok := tr.psByID[pid]
-		if ok {
-			return p
-		}
-		p = &Processor{
-			ID: pid,
-			// XXX only allocate map once we have a range to store
-			Ranges: map[string][]Span{},
-			// spanLabels: []string{local.Sprintf("p%d", pid)},
-		}
-		tr.psByID[pid] = p
-		return p
-	}
-	addEventToCurrentSpan := func(gid exptrace.GoID, ev EventID) {
-		g := getG(gid)
-		g.Events = append(g.Events, ev)
+// A Parser processes events and builds a Trace from them. Parse uses a Parser to process complete traces, but
+// Parsers can also process traces incrementally, such as traces that are still being written to by a flight recorder,
+// by calling Update whenever new events become available.
+//
+// Update modifies the trace in place. The caller has to ensure that the trace isn't accessed concurrently with calls
+// to Update.
+type Parser struct {
+	tr *Trace
+
+	synced           bool
+	userRegionDepths map[exptrace.GoID]int
+	traceStart       exptrace.Time
+	gm               goroutineMetrics
+	allocs           allocState
+	// The machines currently holding Ps and running (or blocking in syscalls on behalf of) goroutines.
+	pToM map[exptrace.ProcID]*Machine
+	gToM map[exptrace.GoID]*Machine
+
+	// The number of spans per goroutine that have already been post-processed.
+	postProcessed map[*Goroutine]int
+	// Spans that hadn't ended yet and whose ends were set to the end of the trace. They get reopened before
+	// processing more events.
+	openSpans []*Span
+}
+
+func NewParser() *Parser {
+	return &Parser{
+		tr: &Trace{
+			Functions:     map[string]*Function{},
+			gsByID:        map[exptrace.GoID]*Goroutine{},
+			psByID:        map[exptrace.ProcID]*Processor{},
+			msByID:        map[exptrace.ThreadID]*Machine{},
+			CPUSamplesByG: map[exptrace.GoID][]EventID{},
+			CPUSamplesByP: map[exptrace.ProcID][]EventID{},
+			Metrics:       map[string]Metric{},
+			GC:            make(spansSlice, 0),
+			STW:           make(spansSlice, 0),
+			Ranges:        map[string][]Span{},
+			PCs:           make(map[uint64]exptrace.StackFrame),
+			Stacks:        make(map[exptrace.Stack][]uint64),
+			Allocations: Allocations{
+				ByG: map[exptrace.GoID][]int{},
+			},
+		},
+		userRegionDepths: map[exptrace.GoID]int{},
+		allocs:           newAllocState(),
+		pToM:             map[exptrace.ProcID]*Machine{},
+		gToM:             map[exptrace.GoID]*Machine{},
+		postProcessed:    map[*Goroutine]int{},
 	}
-	getTask := func(taskID exptrace.TaskID) *Task {
-		idx, ok := tr.task(taskID)
-		if ok {
-			return tr.Tasks[idx]
-		}
+}
 
-		// The task with the given ID doesn't exist. This can happen in well-formed traces when the task
-		// was created before tracing began.
-		task := &Task{
-			ID: taskID,
+// Trace returns the trace built by the parser. The same trace is returned by all calls and it is updated in place
+// by Update.
+func (p *Parser) Trace() *Trace {
+	return p.tr
+}
+
+// Update processes events, which must directly follow the events of previous calls to Update, and updates the trace
+// accordingly. The trace's end will be the time of the last event processed so far.
+func (p *Parser) Update(events []exptrace.Event) error {
+	p.reopenSpans()
+	for _, ev := range events {
+		if err := p.processEvent(ev); err != nil {
+			return err
 		}
-		tr.Tasks = slices.Insert(tr.Tasks, idx, task)
-		return task
 	}
-	addEventToTask := func(taskID exptrace.TaskID, ev EventID) {
-		t := getTask(taskID)
-		t.Events = append(t.Events, ev)
+	if p.tr.Events.Len() == 0 {
+		return nil
 	}
+	p.finish(func(float64) {}, func(float64) {})
+	return nil
+}
 
-	synced := false
-	userRegionDepths := map[exptrace.GoID]int{}
-	var traceStart exptrace.Time
-	var gm goroutineMetrics
+func (p *Parser) readEvents(r *exptrace.Reader, progress func(float64)) error {
 	for {
 		ev, err := r.ReadEvent()
 		if err != nil {
@@ -561,439 +628,612 @@ func processEvents(r *exptrace.Reader, tr *Trace, progress func(float64)) error
 
 		// TODO(dh): call progress
 
-		if tr.Events.Len() == 0 {
-			traceStart = ev.Time()
-			gm.runningGoroutines.add(traceStart, 0)
-			gm.runnableGoroutines.add(traceStart, 0)
-			gm.blockedGoroutines.add(traceStart, 0)
+		if err := p.processEvent(ev); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		evID := EventID(tr.Events.Len())
-		tr.Events.Append(ev)
+func (p *Parser) finish(populateProgress, postProcessProgress func(float64)) {
+	p.finishEvents()
+	populateObjects(p.tr, populateProgress)
+	p.postProcessSpans(postProcessProgress)
+}
 
-		// Cache all stacks
-		tr.addStack(ev.Stack())
-		if ev.Kind() == exptrace.EventStateTransition {
-			tr.addStack(ev.StateTransition().Stack)
-		}
+// reopenSpans undoes the effect of fixEnds, so that processing further events can end the spans. It has to be called
+// before processing any events, as the pointers in openSpans are invalidated by appending to the spans.
+func (p *Parser) reopenSpans() {
+	for _, s := range p.openSpans {
+		s.End = 0
+	}
+	p.openSpans = p.openSpans[:0]
+}
 
-		switch ev.Kind() {
-		case exptrace.EventSync:
-			synced = true
-		case exptrace.EventLabel:
-			l := ev.Label()
-			switch l.Resource.Kind {
-			case exptrace.ResourceGoroutine:
-				g := getG(l.Resource.Goroutine())
-				if len(g.Spans) == 0 {
-					return fmt.Errorf("got label for goroutine %d but it has no spans", g.ID)
-				}
-				span := &g.Spans[len(g.Spans)-1]
-				if tr.Events.Ptr(int(span.StartEvent)).Kind() != exptrace.EventStateTransition {
-					return fmt.Errorf("got label for goroutine %d but last span isn't a state transition", g.ID)
-				}
-				switch l.Label {
-				case "GC (dedicated)":
-					span.State = StateGCDedicated
-				case "GC (idle)":
-					span.State = StateGCIdle
-				case "GC (fractional)":
-					span.State = StateGCFractional
-				default:
-					log.Printf("unhandled label %q", l.Label)
-				}
-			default:
-				panic(fmt.Sprintf("unhandled kind %s", l.Resource.Kind))
+func (p *Parser) getG(gid exptrace.GoID) *Goroutine {
+	tr := p.tr
+	g, ok := tr.gsByID[gid]
+	if ok {
+		return g
+	}
+	g = &Goroutine{
+		ID: gid,
+		// XXX only allocate map once we have a range to store
+		Ranges: map[string][]Span{},
+	}
+	tr.gsByID[gid] = g
+	return g
+}
+
+func (p *Parser) getP(pid exptrace.ProcID) *Processor {
+	tr := p.tr
+	proc, ok := tr.psByID[pid]
+	if ok {
+		return proc
+	}
+	proc = &Processor{
+		ID: pid,
+		// XXX only allocate map once we have a range to store
+		Ranges: map[string][]Span{},
+		// spanLabels: []string{local.Sprintf("p%d", pid)},
+	}
+	tr.psByID[pid] = proc
+	return proc
+}
+
+func (p *Parser) getM(mid exptrace.ThreadID) *Machine {
+	tr := p.tr
+	m, ok := tr.msByID[mid]
+	if ok {
+		return m
+	}
+	m = &Machine{
+		ID: mid,
+		// XXX only allocate map once we have a range to store
+		Ranges: map[string][]Span{},
+	}
+	tr.msByID[mid] = m
+	return m
+}
+
+// updateMachineProc tracks which machine holds a processor, based on a state transition of the processor that
+// happened on thread mid.
+func (p *Parser) updateMachineProc(mid exptrace.ThreadID, pid exptrace.ProcID, to exptrace.ProcState, start, ts exptrace.Time, evID EventID) {
+	if m, ok := p.pToM[pid]; ok {
+		last := &m.Spans[len(m.Spans)-1]
+		last.End = ts
+		last.EndEvent = evID
+		delete(p.pToM, pid)
+	}
+	if to != exptrace.ProcRunning || mid == exptrace.NoThread {
+		return
+	}
+	m := p.getM(mid)
+	m.Spans = append(m.Spans, Span{
+		Start:      start,
+		StartEvent: evID,
+		Kind:       SpanKindStateTransition,
+		State:      StateActive,
+		EndEvent:   -1,
+	})
+	p.pToM[pid] = m
+}
+
+// updateMachineGoroutine tracks which machine is running a goroutine, or is blocked in a syscall on behalf of it,
+// based on a state transition of the goroutine that happened on thread mid.
+func (p *Parser) updateMachineGoroutine(mid exptrace.ThreadID, gid exptrace.GoID, to exptrace.GoState, start, ts exptrace.Time, evID EventID) {
+	if m, ok := p.gToM[gid]; ok {
+		last := &m.Goroutines[len(m.Goroutines)-1]
+		last.End = ts
+		last.EndEvent = evID
+		delete(p.gToM, gid)
+	}
+	var state SchedulingState
+	switch to {
+	case exptrace.GoRunning:
+		state = StateActive
+	case exptrace.GoSyscall:
+		state = StateBlockedSyscall
+	default:
+		return
+	}
+	if mid == exptrace.NoThread {
+		return
+	}
+	m := p.getM(mid)
+	m.Goroutines = append(m.Goroutines, Span{
+		Start:      start,
+		StartEvent: evID,
+		Kind:       SpanKindStateTransition,
+		State:      state,
+		EndEvent:   -1,
+	})
+	p.gToM[gid] = m
+}
+
+func (p *Parser) addEventToCurrentSpan(gid exptrace.GoID, ev EventID) {
+	g := p.getG(gid)
+	g.Events = append(g.Events, ev)
+}
+
+func (p *Parser) getTask(taskID exptrace.TaskID) *Task {
+	tr := p.tr
+	idx, ok := tr.task(taskID)
+	if ok {
+		return tr.Tasks[idx]
+	}
+
+	// The task with the given ID doesn't exist. This can happen in well-formed traces when the task
+	// was created before tracing began.
+	task := &Task{
+		ID: taskID,
+	}
+	tr.Tasks = slices.Insert(tr.Tasks, idx, task)
+	return task
+}
+
+func (p *Parser) addEventToTask(taskID exptrace.TaskID, ev EventID) {
+	t := p.getTask(taskID)
+	t.Events = append(t.Events, ev)
+}
+
+func (p *Parser) processEvent(ev exptrace.Event) error {
+	// OPT(dh): evaluate reading all events in one pass, then preallocating []Span slices based on the number
+	// of events we saw for Ps and Gs.
+	tr := p.tr
+
+	if tr.Events.Len() == 0 {
+		p.traceStart = ev.Time()
+		p.gm.runningGoroutines.add(p.traceStart, 0)
+		p.gm.runnableGoroutines.add(p.traceStart, 0)
+		p.gm.blockedGoroutines.add(p.traceStart, 0)
+	}
+
+	evID := EventID(tr.Events.Len())
+	tr.Events.Append(ev)
+
+	// Cache all stacks
+	tr.addStack(ev.Stack())
+	if ev.Kind() == exptrace.EventStateTransition {
+		tr.addStack(ev.StateTransition().Stack)
+	}
+
+	switch ev.Kind() {
+	case exptrace.EventSync:
+		p.synced = true
+		if err := p.readAllocFreeBatches(ev.Sync().ExperimentalBatches[allocFreeExperiment]); err != nil {
+			return err
+		}
+	case exptrace.EventLabel:
+		l := ev.Label()
+		switch l.Resource.Kind {
+		case exptrace.ResourceGoroutine:
+			g := p.getG(l.Resource.Goroutine())
+			if len(g.Spans) == 0 {
+				return fmt.Errorf("got label for goroutine %d but it has no spans", g.ID)
 			}
-		case exptrace.EventLog:
-			addEventToCurrentSpan(ev.Goroutine(), evID)
-			l := ev.Log()
-			if l.Task != exptrace.NoTask {
-				addEventToTask(l.Task, evID)
+			span := &g.Spans[len(g.Spans)-1]
+			if tr.Events.Ptr(int(span.StartEvent)).Kind() != exptrace.EventStateTransition {
+				return fmt.Errorf("got label for goroutine %d but last span isn't a state transition", g.ID)
 			}
-		case exptrace.EventMetric:
-			m := ev.Metric()
-			mm := tr.Metrics[m.Name]
-			mm.Timestamps = append(mm.Timestamps, ev.Time())
-			mm.Values = append(mm.Values, m.Value.Uint64())
-			tr.Metrics[m.Name] = mm
-		case exptrace.EventRangeActive:
-			if synced {
-				// We're being told about a range we must've already seen a RangeBegin for
-				continue
+			switch l.Label {
+			case "GC (dedicated)":
+				span.State = StateGCDedicated
+			case "GC (idle)":
+				span.State = StateGCIdle
+			case "GC (fractional)":
+				span.State = StateGCFractional
+			default:
+				log.Printf("unhandled label %q", l.Label)
+			}
+		default:
+			panic(fmt.Sprintf("unhandled kind %s", l.Resource.Kind))
+		}
+	case exptrace.EventLog:
+		p.addEventToCurrentSpan(ev.Goroutine(), evID)
+		l := ev.Log()
+		if l.Task != exptrace.NoTask {
+			p.addEventToTask(l.Task, evID)
+		}
+	case exptrace.EventMetric:
+		m := ev.Metric()
+		mm := tr.Metrics[m.Name]
+		mm.Timestamps = append(mm.Timestamps, ev.Time())
+		mm.Values = append(mm.Values, m.Value.Uint64())
+		tr.Metrics[m.Name] = mm
+	case exptrace.EventRangeActive:
+		if p.synced {
+			// We're being told about a range we must've already seen a RangeBegin for
+			return nil
+		}
+		fallthrough
+	case exptrace.EventRangeBegin:
+		r := ev.Range()
+		var s Span
+		if ev.Kind() == exptrace.EventRangeActive {
+			s = Span{StartEvent: evID, Start: tr.Events.Ptr(0).Time(), EndEvent: -1}
+		} else {
+			s = Span{Start: ev.Time(), StartEvent: evID, EndEvent: -1}
+		}
+
+		switch scope := rangeActualScope(r); scope {
+		case rangeScopeUnknown:
+		case rangeScopeGC:
+			tr.GC = append(tr.GC, s)
+		case rangeScopeSTW:
+			tr.STW = append(tr.STW, s)
+		case rangeScopeGoroutine:
+			g := p.getG(r.Scope.Goroutine())
+			g.Ranges[r.Name] = append(g.Ranges[r.Name], s)
+		case rangeScopeProc:
+			proc := p.getP(r.Scope.Proc())
+			proc.Ranges[r.Name] = append(proc.Ranges[r.Name], s)
+		case rangeScopeThread:
+			m := p.getM(r.Scope.Thread())
+			m.Ranges[r.Name] = append(m.Ranges[r.Name], s)
+		case rangeScopeGlobal:
+			tr.Ranges[r.Name] = append(tr.Ranges[r.Name], s)
+		default:
+			panic(fmt.Sprintf("unhandled range scope %d for range %q", scope, r.Name))
+		}
+	case exptrace.EventRangeEnd:
+		r := ev.Range()
+		var prev *Span
+		switch scope := rangeActualScope(r); scope {
+		case rangeScopeUnknown:
+			return nil
+		case rangeScopeGC:
+			prev = &tr.GC[len(tr.GC)-1]
+		case rangeScopeSTW:
+			prev = &tr.STW[len(tr.STW)-1]
+		case rangeScopeGoroutine:
+			g := p.getG(r.Scope.Goroutine())
+			prev = &g.Ranges[r.Name][len(g.Ranges[r.Name])-1]
+		case rangeScopeProc:
+			proc := p.getP(r.Scope.Proc())
+			prev = &proc.Ranges[r.Name][len(proc.Ranges[r.Name])-1]
+		case rangeScopeThread:
+			m := p.getM(r.Scope.Thread())
+			prev = &m.Ranges[r.Name][len(m.Ranges[r.Name])-1]
+		case rangeScopeGlobal:
+			prev = &tr.Ranges[r.Name][len(tr.Ranges[r.Name])-1]
+		default:
+			panic(fmt.Sprintf("unhandled range scope %d", scope))
+		}
+		prev.End = ev.Time()
+		prev.EndEvent = evID
+	case exptrace.EventRegionBegin:
+		s := Span{
+			Start:      ev.Time(),
+			StartEvent: evID,
+			State:      StateUserRegion,
+			EndEvent:   -1,
+		}
+		gid := ev.Goroutine()
+		g := p.getG(gid)
+		depth := p.userRegionDepths[gid]
+		if depth >= len(g.UserRegions) {
+			if depth < cap(g.UserRegions) {
+				g.UserRegions = g.UserRegions[:depth+1]
+			} else {
+				s := make([][]Span, depth+1)
+				copy(s, g.UserRegions)
+				g.UserRegions = s
 			}
-			fallthrough
-		case exptrace.EventRangeBegin:
-			r := ev.Range()
-			var s Span
-			if ev.Kind() == exptrace.EventRangeActive {
-				s = Span{StartEvent: evID, Start: tr.Events.Ptr(0).Time(), EndEvent: -1}
+		}
+		if g.UserRegions[depth] == nil {
+			g.UserRegions[depth] = make([]Span, 0)
+		}
+		g.UserRegions[depth] = append(g.UserRegions[depth], s)
+		p.userRegionDepths[gid]++
+
+		r := ev.Region()
+		// XXX add a default background task with ID 0
+		if r.Task != 0 && r.Task != exptrace.NoTask {
+			// ensure the task exists
+			p.getTask(r.Task)
+		}
+		return nil
+	case exptrace.EventRegionEnd:
+		gid := ev.Goroutine()
+		g := p.getG(gid)
+		d := p.userRegionDepths[gid] - 1
+		// We can see a region end without a region start if the two occured in different traces.
+		if d >= 0 {
+			ss := g.UserRegions[d]
+			ss[len(ss)-1].EndEvent = evID
+			ss[len(ss)-1].End = ev.Time()
+			if d > 0 {
+				p.userRegionDepths[gid] = d
 			} else {
-				s = Span{Start: ev.Time(), StartEvent: evID, EndEvent: -1}
+				delete(p.userRegionDepths, gid)
+			}
+		}
+	case exptrace.EventStackSample:
+		tr.CPUSamples = append(tr.CPUSamples, evID)
+		if gid := ev.Goroutine(); gid != exptrace.NoGoroutine {
+			tr.CPUSamplesByG[gid] = append(tr.CPUSamplesByG[gid], evID)
+		}
+		if pid := ev.Proc(); pid != exptrace.NoProc {
+			tr.CPUSamplesByP[pid] = append(tr.CPUSamplesByP[pid], evID)
+		}
+	case exptrace.EventStateTransition:
+		trans := ev.StateTransition()
+		res := trans.Resource
+		switch res.Kind {
+		case exptrace.ResourceThread:
+			// TODO(dh): support threads. We derive what threads are doing from the state transitions of Ps and Gs
+			// instead.
+		case exptrace.ResourceProc:
+			from, to := trans.Proc()
+			if from == to {
+				// Enumeration during a generation
+				return nil
+			}
+			proc := p.getP(trans.Resource.Proc())
+			s := Span{
+				Start:      ev.Time(),
+				StartEvent: evID,
+				Kind:       SpanKindStateTransition,
+				EndEvent:   -1,
 			}
 
-			switch scope := rangeActualScope(r); scope {
-			case rangeScopeUnknown:
-			case rangeScopeGC:
-				tr.GC = append(tr.GC, s)
-			case rangeScopeSTW:
-				tr.STW = append(tr.STW, s)
-			case rangeScopeGoroutine:
-				g := getG(r.Scope.Goroutine())
-				g.Ranges[r.Name] = append(g.Ranges[r.Name], s)
-			case rangeScopeProc:
-				p := getP(r.Scope.Proc())
-				p.Ranges[r.Name] = append(p.Ranges[r.Name], s)
-			case rangeScopeThread:
-				// XXX implement
-			case rangeScopeGlobal:
-				// XXX implement
-			default:
-				panic(fmt.Sprintf("unhandled range scope %d for range %q", scope, r.Name))
+			if from == exptrace.ProcUndetermined {
+				s.Start = p.traceStart
 			}
-		case exptrace.EventRangeEnd:
-			r := ev.Range()
-			var prev *Span
-			switch scope := rangeActualScope(r); scope {
-			case rangeScopeUnknown:
-				continue
-			case rangeScopeGC:
-				prev = &tr.GC[len(tr.GC)-1]
-			case rangeScopeSTW:
-				prev = &tr.STW[len(tr.STW)-1]
-			case rangeScopeGoroutine:
-				g := getG(r.Scope.Goroutine())
-				prev = &g.Ranges[r.Name][len(g.Ranges[r.Name])-1]
-			case rangeScopeProc:
-				p := getP(r.Scope.Proc())
-				prev = &p.Ranges[r.Name][len(p.Ranges[r.Name])-1]
-			case rangeScopeThread:
-				// XXX implement
-			case rangeScopeGlobal:
-				// XXX implement
+
+			if from != exptrace.ProcUndetermined && from != exptrace.ProcNotExist && from != exptrace.ProcIdle {
+				prevSpan := &proc.Spans[len(proc.Spans)-1]
+				prevSpan.End = ev.Time()
+				prevSpan.EndEvent = evID
+			}
+			p.updateMachineProc(ev.Thread(), proc.ID, to, s.Start, ev.Time(), evID)
+			switch to {
+			case exptrace.ProcRunning:
+				s.State = StateProcRunningNoG
+			case exptrace.ProcIdle:
+				return nil
 			default:
-				panic(fmt.Sprintf("unhandled range scope %d", scope))
+				panic(fmt.Sprintf("unhandled state %s", to))
+			}
+			proc.Spans = append(proc.Spans, s)
+		case exptrace.ResourceGoroutine:
+			from, to := trans.Goroutine()
+			if from == to {
+				// Enumeration during a generation
+				return nil
 			}
-			prev.End = ev.Time()
-			prev.EndEvent = evID
-		case exptrace.EventRegionBegin:
+			g := p.getG(trans.Resource.Goroutine())
 			s := Span{
 				Start:      ev.Time(),
 				StartEvent: evID,
-				State:      StateUserRegion,
+				Kind:       SpanKindStateTransition,
 				EndEvent:   -1,
 			}
-			gid := ev.Goroutine()
-			g := getG(gid)
-			depth := userRegionDepths[gid]
-			if depth >= len(g.UserRegions) {
-				if depth < cap(g.UserRegions) {
-					g.UserRegions = g.UserRegions[:depth+1]
-				} else {
-					s := make([][]Span, depth+1)
-					copy(s, g.UserRegions)
-					g.UserRegions = s
-				}
-			}
-			if g.UserRegions[depth] == nil {
-				g.UserRegions[depth] = make([]Span, 0)
-			}
-			g.UserRegions[depth] = append(g.UserRegions[depth], s)
-			userRegionDepths[gid]++
-
-			r := ev.Region()
-			// XXX add a default background task with ID 0
-			if r.Task != 0 && r.Task != exptrace.NoTask {
-				// ensure the task exists
-				getTask(r.Task)
+
+			if from == exptrace.GoUndetermined {
+				s.Start = p.traceStart
 			}
-			continue
-		case exptrace.EventRegionEnd:
-			gid := ev.Goroutine()
-			g := getG(gid)
-			d := userRegionDepths[gid] - 1
-			// We can see a region end without a region start if the two occured in different traces.
-			if d >= 0 {
-				ss := g.UserRegions[d]
-				ss[len(ss)-1].EndEvent = evID
-				ss[len(ss)-1].End = ev.Time()
-				if d > 0 {
-					userRegionDepths[gid] = d
-				} else {
-					delete(userRegionDepths, gid)
-				}
+			switch {
+			case from == exptrace.GoRunnable:
+				p.gm.runnableGoroutines.add(ev.Time(), -1)
+			case to == exptrace.GoRunnable:
+				p.gm.runnableGoroutines.add(ev.Time(), 1)
 			}
-		case exptrace.EventStackSample:
-			tr.CPUSamples = append(tr.CPUSamples, evID)
-			if gid := ev.Goroutine(); gid != exptrace.NoGoroutine {
-				tr.CPUSamplesByG[gid] = append(tr.CPUSamplesByG[gid], evID)
+			switch {
+			case from == exptrace.GoRunning:
+				p.gm.runningGoroutines.add(ev.Time(), -1)
+			case to == exptrace.GoRunning:
+				p.gm.runningGoroutines.add(ev.Time(), 1)
 			}
-			if pid := ev.Proc(); pid != exptrace.NoProc {
-				tr.CPUSamplesByP[pid] = append(tr.CPUSamplesByP[pid], evID)
+			fromIsBlocked := from == exptrace.GoSyscall || from == exptrace.GoWaiting
+			toIsBlocked := to == exptrace.GoSyscall || to == exptrace.GoWaiting
+			switch {
+			case fromIsBlocked && !toIsBlocked:
+				p.gm.blockedGoroutines.add(ev.Time(), -1)
+			case !fromIsBlocked && toIsBlocked:
+				p.gm.blockedGoroutines.add(ev.Time(), 1)
 			}
-		case exptrace.EventStateTransition:
-			trans := ev.StateTransition()
-			res := trans.Resource
-			switch res.Kind {
-			case exptrace.ResourceThread:
-				// TODO(dh): support threads
-			case exptrace.ResourceProc:
-				from, to := trans.Proc()
-				if from == to {
-					// Enumeration during a generation
-					continue
-				}
-				p := getP(trans.Resource.Proc())
-				s := Span{
-					Start:      ev.Time(),
-					StartEvent: evID,
-					Kind:       SpanKindStateTransition,
-					EndEvent:   -1,
-				}
-
-				if from == exptrace.ProcUndetermined {
-					s.Start = traceStart
-				}
-
-				if from != exptrace.ProcUndetermined && from != exptrace.ProcNotExist && from != exptrace.ProcIdle {
-					prevSpan := &p.Spans[len(p.Spans)-1]
-					prevSpan.End = ev.Time()
-					prevSpan.EndEvent = evID
-				}
-				switch to {
-				case exptrace.ProcRunning:
-					s.State = StateProcRunningNoG
-				case exptrace.ProcIdle:
-					continue
-				default:
-					panic(fmt.Sprintf("unhandled state %s", to))
-				}
-				p.Spans = append(p.Spans, s)
-			case exptrace.ResourceGoroutine:
-				from, to := trans.Goroutine()
-				if from == to {
-					// Enumeration during a generation
-					continue
-				}
-				g := getG(trans.Resource.Goroutine())
-				s := Span{
-					Start:      ev.Time(),
-					StartEvent: evID,
-					Kind:       SpanKindStateTransition,
-					EndEvent:   -1,
-				}
-
-				if from == exptrace.GoUndetermined {
-					s.Start = traceStart
-				}
-				switch {
-				case from == exptrace.GoRunnable:
-					gm.runnableGoroutines.add(ev.Time(), -1)
-				case to == exptrace.GoRunnable:
-					gm.runnableGoroutines.add(ev.Time(), 1)
-				}
-				switch {
-				case from == exptrace.GoRunning:
-					gm.runningGoroutines.add(ev.Time(), -1)
-				case to == exptrace.GoRunning:
-					gm.runningGoroutines.add(ev.Time(), 1)
-				}
-				fromIsBlocked := from == exptrace.GoSyscall || from == exptrace.GoWaiting
-				toIsBlocked := to == exptrace.GoSyscall || to == exptrace.GoWaiting
-				switch {
-				case fromIsBlocked && !toIsBlocked:
-					gm.blockedGoroutines.add(ev.Time(), -1)
-				case !fromIsBlocked && toIsBlocked:
-					gm.blockedGoroutines.add(ev.Time(), 1)
-				}
-
-				// XXX actually, for from == exptrace.GoUndetermined, we still need to do omst of the work to update P
-				// spans. a proc may start, followed by a goroutine going from undetermined->running on that proc, and
-				// we need to update the "running without G" span in the P.
-				if from != exptrace.GoUndetermined && from != exptrace.GoNotExist {
-					prevSpan := &g.Spans[len(g.Spans)-1]
-					prevSpan.End = ev.Time()
-					prevSpan.EndEvent = evID
-
-					if ev.Proc() != exptrace.NoProc {
-						switch to {
-						case exptrace.GoNotExist:
-							p := getP(ev.Proc())
-							if len(p.Spans) == 0 {
-								return errors.New("transitioning to GoNotExist but don't have existing span")
-							}
-							last := &p.Spans[len(p.Spans)-1]
-							last.End = ev.Time()
-							s := Span{
-								Start:      ev.Time(),
-								StartEvent: evID,
-								State:      StateProcRunningNoG,
-								EndEvent:   -1,
-							}
-							p.Spans = append(p.Spans, s)
-						case exptrace.GoSyscall, exptrace.GoWaiting:
-							p := getP(ev.Proc())
-							last := &p.Spans[len(p.Spans)-1]
-							last.End = ev.Time()
-							s := Span{
-								Start:      ev.Time(),
-								StartEvent: evID,
-								State:      StateProcRunningBlocked,
-								EndEvent:   -1,
-							}
-							p.Spans = append(p.Spans, s)
-						case exptrace.GoRunning:
-							p := getP(ev.Proc())
-							last := &p.Spans[len(p.Spans)-1]
-							last.End = ev.Time()
-							s := Span{
-								Start:      ev.Time(),
-								StartEvent: evID,
-								State:      StateProcRunningG,
-								EndEvent:   -1,
-							}
-							p.Spans = append(p.Spans, s)
-						case exptrace.GoRunnable:
-							// Nothing to do
-						default:
-							panic(fmt.Sprintf("unhandled state %s", to))
+			p.updateMachineGoroutine(ev.Thread(), g.ID, to, s.Start, ev.Time(), evID)
+
+			// XXX actually, for from == exptrace.GoUndetermined, we still need to do omst of the work to update P
+			// spans. a proc may start, followed by a goroutine going from undetermined->running on that proc, and
+			// we need to update the "running without G" span in the P.
+			if from != exptrace.GoUndetermined && from != exptrace.GoNotExist {
+				prevSpan := &g.Spans[len(g.Spans)-1]
+				prevSpan.End = ev.Time()
+				prevSpan.EndEvent = evID
+
+				if ev.Proc() != exptrace.NoProc {
+					switch to {
+					case exptrace.GoNotExist:
+						proc := p.getP(ev.Proc())
+						if len(proc.Spans) == 0 {
+							return errors.New("transitioning to GoNotExist but don't have existing span")
 						}
-					}
-				}
-				switch to {
-				case exptrace.GoNotExist:
-					g.End = maybe.Some(ev.Time())
-					continue
-				case exptrace.GoRunnable:
-					if from == exptrace.GoNotExist {
-						// Goroutine creation
-						s.State = StateCreated
-						g.Start = maybe.Some(ev.Time())
-						g.Parent = ev.Goroutine()
-						// ev.Goroutine is the goroutine that's creating us, versus g, which is the
-						// created goroutine.
-						addEventToCurrentSpan(ev.Goroutine(), evID)
-					} else {
-						if trans.Reason == "runtime.GoSched" || trans.Reason == "runtime.Gosched" {
-							s.State = StateInactive
-						} else {
-							s.State = StateReady
+						last := &proc.Spans[len(proc.Spans)-1]
+						last.End = ev.Time()
+						s := Span{
+							Start:      ev.Time(),
+							StartEvent: evID,
+							State:      StateProcRunningNoG,
+							EndEvent:   -1,
 						}
-						if from == exptrace.GoWaiting {
-							// ev.Goroutine is the goroutine that's unblocking us, versus g, which is the
-							// unblocked goroutine.
-							if ev.Goroutine() != exptrace.NoGoroutine {
-								addEventToCurrentSpan(ev.Goroutine(), evID)
-							}
+						proc.Spans = append(proc.Spans, s)
+					case exptrace.GoSyscall, exptrace.GoWaiting:
+						proc := p.getP(ev.Proc())
+						last := &proc.Spans[len(proc.Spans)-1]
+						last.End = ev.Time()
+						s := Span{
+							Start:      ev.Time(),
+							StartEvent: evID,
+							State:      StateProcRunningBlocked,
+							EndEvent:   -1,
+						}
+						proc.Spans = append(proc.Spans, s)
+					case exptrace.GoRunning:
+						proc := p.getP(ev.Proc())
+						last := &proc.Spans[len(proc.Spans)-1]
+						last.End = ev.Time()
+						s := Span{
+							Start:      ev.Time(),
+							StartEvent: evID,
+							State:      StateProcRunningG,
+							EndEvent:   -1,
 						}
+						proc.Spans = append(proc.Spans, s)
+					case exptrace.GoRunnable:
+						// Nothing to do
+					default:
+						panic(fmt.Sprintf("unhandled state %s", to))
 					}
-				case exptrace.GoRunning:
-					s.State = StateActive
-				case exptrace.GoSyscall:
-					s.State = StateBlockedSyscall
-				case exptrace.GoWaiting:
-					switch trans.Reason {
-					case "chan send":
-						s.State = StateBlockedSend
-					case "chan receive":
-						s.State = StateBlockedRecv
-					case "network":
-						s.State = StateBlockedNet
-					case "runtime.GoSched", "runtime.Gosched":
-						s.State = StateInactive
-					case "select":
-						s.State = StateBlockedSelect
-					case "sleep":
-						s.State = StateInactive
-					case "sync":
-						s.State = StateBlockedSync
-					case "sync.(*Cond).Wait":
-						s.State = StateBlockedCond
-					case "system goroutine wait":
-						s.State = StateInactive
-					case "GC mark assist wait for work":
-						s.State = StateInactive
-					case "GC background sweeper wait":
+				}
+			}
+			switch to {
+			case exptrace.GoNotExist:
+				g.End = maybe.Some(ev.Time())
+				return nil
+			case exptrace.GoRunnable:
+				if from == exptrace.GoNotExist {
+					// Goroutine creation
+					s.State = StateCreated
+					g.Start = maybe.Some(ev.Time())
+					g.Parent = ev.Goroutine()
+					// ev.Goroutine is the goroutine that's creating us, versus g, which is the
+					// created goroutine.
+					p.addEventToCurrentSpan(ev.Goroutine(), evID)
+				} else {
+					if trans.Reason == "runtime.GoSched" || trans.Reason == "runtime.Gosched" {
 						s.State = StateInactive
-					case "preempted":
-						s.State = StateWaitingPreempted
-					case "forever":
-						s.State = StateStuck
-					case "wait for debug call":
-						s.State = StateBlocked
-					case "wait until GC ends":
-						s.State = StateBlockedGC
-					case "GC weak to strong wait":
-						s.State = StateBlocked
-					case "synctest":
-						s.State = StateBlocked
-					case "":
-						s.State = StateBlocked
-					default:
-						log.Printf("unhandled reason %q", trans.Reason)
+					} else {
+						s.State = StateReady
+					}
+					if from == exptrace.GoWaiting {
+						// ev.Goroutine is the goroutine that's unblocking us, versus g, which is the
+						// unblocked goroutine.
+						if ev.Goroutine() != exptrace.NoGoroutine {
+							p.addEventToCurrentSpan(ev.Goroutine(), evID)
+						}
 					}
+				}
+			case exptrace.GoRunning:
+				s.State = StateActive
+			case exptrace.GoSyscall:
+				s.State = StateBlockedSyscall
+			case exptrace.GoWaiting:
+				switch trans.Reason {
+				case "chan send":
+					s.State = StateBlockedSend
+				case "chan receive":
+					s.State = StateBlockedRecv
+				case "network":
+					s.State = StateBlockedNet
+				case "runtime.GoSched", "runtime.Gosched":
+					s.State = StateInactive
+				case "select":
+					s.State = StateBlockedSelect
+				case "sleep":
+					s.State = StateInactive
+				case "sync":
+					s.State = StateBlockedSync
+				case "sync.(*Cond).Wait":
+					s.State = StateBlockedCond
+				case "system goroutine wait":
+					s.State = StateInactive
+				case "GC mark assist wait for work":
+					s.State = StateInactive
+				case "GC background sweeper wait":
+					s.State = StateInactive
+				case "preempted":
+					s.State = StateWaitingPreempted
+				case "forever":
+					s.State = StateStuck
+				case "wait for debug call":
+					s.State = StateBlocked
+				case "wait until GC ends":
+					s.State = StateBlockedGC
+				case "GC weak to strong wait":
+					s.State = StateBlocked
+				case "synctest":
+					s.State = StateBlocked
+				case "":
+					s.State = StateBlocked
 				default:
-					panic(fmt.Sprintf("unhandled state %s", to))
+					log.Printf("unhandled reason %q", trans.Reason)
 				}
-				g.Spans = append(g.Spans, s)
 			default:
-				return fmt.Errorf("invalid resource kind %s", res.Kind)
-			}
-		case exptrace.EventTaskBegin:
-			t := ev.Task()
-			idx, ok := tr.task(t.ID)
-			if ok {
-				panic("task already exists")
+				panic(fmt.Sprintf("unhandled state %s", to))
 			}
+			g.Spans = append(g.Spans, s)
+		default:
+			return fmt.Errorf("invalid resource kind %s", res.Kind)
+		}
+	case exptrace.EventTaskBegin:
+		t := ev.Task()
+		idx, ok := tr.task(t.ID)
+		if ok {
+			panic("task already exists")
+		}
+		task := &Task{
+			ID:         t.ID,
+			Parent:     t.Parent,
+			Start:      maybe.Some(ev.Time()),
+			StartEvent: evID,
+			Name:       t.Type,
+		}
+		// Tasks may not be sorted in the trace, so we need to insert them at the correct position.
+		// This will translate to an append in most cases.
+		tr.Tasks = slices.Insert(tr.Tasks, idx, task)
+		p.addEventToCurrentSpan(ev.Goroutine(), evID)
+		if t.Parent != exptrace.NoTask {
+			p.addEventToTask(t.Parent, evID)
+		}
+	case exptrace.EventTaskEnd:
+		t := ev.Task()
+		idx, ok := tr.task(t.ID)
+		if !ok {
+			// The task with the given ID doesn't exist. This can happen in well-formed traces when the task
+			// was created before tracing began.
 			task := &Task{
-				ID:         t.ID,
-				Parent:     t.Parent,
-				Start:      maybe.Some(ev.Time()),
-				StartEvent: evID,
-				Name:       t.Type,
+				ID:       t.ID,
+				Parent:   t.Parent,
+				End:      maybe.Some(ev.Time()),
+				EndEvent: evID,
+				Name:     t.Type,
 			}
-			// Tasks may not be sorted in the trace, so we need to insert them at the correct position.
-			// This will translate to an append in most cases.
 			tr.Tasks = slices.Insert(tr.Tasks, idx, task)
-			addEventToCurrentSpan(ev.Goroutine(), evID)
-			if t.Parent != exptrace.NoTask {
-				addEventToTask(t.Parent, evID)
-			}
-		case exptrace.EventTaskEnd:
-			t := ev.Task()
-			idx, ok := tr.task(t.ID)
-			if !ok {
-				// The task with the given ID doesn't exist. This can happen in well-formed traces when the task
-				// was created before tracing began.
-				task := &Task{
-					ID:       t.ID,
-					Parent:   t.Parent,
-					End:      maybe.Some(ev.Time()),
-					EndEvent: evID,
-					Name:     t.Type,
-				}
-				tr.Tasks = slices.Insert(tr.Tasks, idx, task)
-			} else {
-				if tr.Tasks[idx].Stub() {
-					// Fill the missing information
-					tr.Tasks[idx].Parent = t.Parent
-					tr.Tasks[idx].Name = t.Type
-				}
-				tr.Tasks[idx].End = maybe.Some(ev.Time())
-				tr.Tasks[idx].EndEvent = evID
-			}
-			if t.Parent != exptrace.NoTask {
-				addEventToTask(t.Parent, evID)
+		} else {
+			if tr.Tasks[idx].Stub() {
+				// Fill the missing information
+				tr.Tasks[idx].Parent = t.Parent
+				tr.Tasks[idx].Name = t.Type
 			}
-		case exptrace.EventExperimental:
-			// TODO(dh): do something useful with these
-		default:
-			panic(fmt.Sprintf("unhandled kind %s", ev.Kind()))
+			tr.Tasks[idx].End = maybe.Some(ev.Time())
+			tr.Tasks[idx].EndEvent = evID
 		}
+		if t.Parent != exptrace.NoTask {
+			p.addEventToTask(t.Parent, evID)
+		}
+	case exptrace.EventExperimental:
+		if err := p.processAllocEvent(ev, evID); err != nil {
+			return err
+		}
+	default:
+		panic(fmt.Sprintf("unhandled kind %s", ev.Kind()))
 	}
+	return nil
+}
+
+// finishEvents computes information that depends on all events processed so far.
+func (p *Parser) finishEvents() {
+	tr := p.tr
 
 	// TODO(dh): try harder to figure out goroutines' functions
 	for _, g := range tr.gsByID {
+		if g.Function != nil {
+			// We've already determined the function in a previous call to Update.
+			continue
+		}
 		for i := range g.Spans {
 			s := &g.Spans[i]
 			if s.StartEvent != 0 {
@@ -1011,25 +1251,30 @@ func processEvents(r *exptrace.Reader, tr *Trace, progress func(float64)) error
 	}
 
 	tr.Metrics["/gotraceui/sched/goroutines/runnable:goroutines"] = Metric{
-		Timestamps: gm.runnableGoroutines.timestamps,
-		Values:     gm.runnableGoroutines.values,
+		Timestamps: p.gm.runnableGoroutines.timestamps,
+		Values:     p.gm.runnableGoroutines.values,
 	}
 	tr.Metrics["/gotraceui/sched/goroutines/running:goroutines"] = Metric{
-		Timestamps: gm.runningGoroutines.timestamps,
-		Values:     gm.runningGoroutines.values,
+		Timestamps: p.gm.runningGoroutines.timestamps,
+		Values:     p.gm.runningGoroutines.values,
 	}
 	tr.Metrics["/gotraceui/sched/goroutines/waiting:goroutines"] = Metric{
-		Timestamps: gm.blockedGoroutines.timestamps,
-		Values:     gm.blockedGoroutines.values,
+		Timestamps: p.gm.blockedGoroutines.timestamps,
+		Values:     p.gm.blockedGoroutines.values,
+	}
+	if len(tr.Allocations.Events) != 0 {
+		tr.Metrics["/gotraceui/heap/objects/allocated:bytes-per-second"] = p.allocs.allocated.metric()
+		tr.Metrics["/gotraceui/heap/objects/freed:bytes-per-second"] = p.allocs.freed.metric()
 	}
-
-	return nil
 }
 
-func postProcessSpans(tr *Trace, progress func(float64)) {
+// postProcessSpans post-processes all spans that haven't been post-processed by a previous call yet.
+func (p *Parser) postProcessSpans(progress func(float64)) {
+	tr := p.tr
 	var wg sync.WaitGroup
 	doG := func(g *Goroutine) {
-		for i := range len(g.Spans) {
+		// postProcessed is only written to after all goroutines are done, so reading it concurrently is safe.
+		for i := p.postProcessed[g]; i < len(g.Spans); i++ {
 			s := g.Spans[i]
 			pcs := tr.Stacks[tr.Events.Ptr(int(s.StartEvent)).Stack()]
 			s = applyPatterns(tr, s, pcs)
@@ -1082,6 +1327,7 @@ func postProcessSpans(tr *Trace, progress func(float64)) {
 		s := &spans[len(spans)-1]
 		if s.End == 0 {
 			s.End = tr.Events.Ptr(tr.Events.Len() - 1).Time()
+			p.openSpans = append(p.openSpans, s)
 		}
 	}
 
@@ -1109,6 +1355,10 @@ func postProcessSpans(tr *Trace, progress func(float64)) {
 	}
 	wg.Wait()
 
+	for _, g := range tr.Goroutines {
+		p.postProcessed[g] = len(g.Spans)
+	}
+
 	for _, g := range tr.Goroutines {
 		fixEnds(g.Spans)
 		for _, ranges := range g.Ranges {
@@ -1118,12 +1368,22 @@ func postProcessSpans(tr *Trace, progress func(float64)) {
 			fixEnds(u)
 		}
 	}
-	for _, p := range tr.Processors {
-		fixEnds(p.Spans)
-		for _, ranges := range p.Ranges {
+	for _, proc := range tr.Processors {
+		fixEnds(proc.Spans)
+		for _, ranges := range proc.Ranges {
+			fixEnds(ranges)
+		}
+	}
+	for _, m := range tr.Machines {
+		fixEnds(m.Spans)
+		fixEnds(m.Goroutines)
+		for _, ranges := range m.Ranges {
 			fixEnds(ranges)
 		}
 	}
+	for _, ranges := range tr.Ranges {
+		fixEnds(ranges)
+	}
 	for i := range tr.Tasks {
 		tr.Tasks[i].Spans = []Span{
 			{
@@ -1141,7 +1401,6 @@ func postProcessSpans(tr *Trace, progress func(float64)) {
 	}
 	fixEnds(tr.GC)
 	fixEnds(tr.STW)
-
 }
 
 func populateObjects(tr *Trace, progress func(float64)) {
@@ -1154,6 +1413,9 @@ func populateObjects(tr *Trace, progress func(float64)) {
 	}
 	progress(1.0 / 5.0)
 
+	// populateObjects may run more than once when processing traces incrementally.
+	tr.Processors = tr.Processors[:0]
+	tr.Machines = tr.Machines[:0]
 	for _, p := range tr.psByID {
 		// OPT(dh): preallocate ps
 		if len(p.Spans) != 0 {
@@ -1164,11 +1426,8 @@ func populateObjects(tr *Trace, progress func(float64)) {
 
 	for _, m := range tr.msByID {
 		// OPT(dh): preallocate ms
-		tr.Machines = append(tr.Machines, m)
-		if len(m.Spans) > 0 {
-			if last := &m.Spans[len(m.Spans)-1]; last.End == -1 {
-				last.End = tr.Events.Ptr(tr.Events.Len() - 1).Time()
-			}
+		if len(m.Spans) != 0 || len(m.Goroutines) != 0 || len(m.Ranges) != 0 {
+			tr.Machines = append(tr.Machines, m)
 		}
 	}
 	progress(3.0 / 5.0)
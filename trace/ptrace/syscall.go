@@ -0,0 +1,159 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SyscallOutcome describes what happened to the processor of a goroutine that made a blocking syscall.
+type SyscallOutcome uint8
+
+const (
+	// The syscall didn't return before the end of the trace.
+	SyscallUnfinished SyscallOutcome = iota
+	// The goroutine kept its processor and continued running right after the syscall.
+	SyscallRetainedP
+	// The processor was handed off to another thread while the goroutine was blocked, and the goroutine had to wait
+	// to be scheduled again.
+	SyscallHandedOffP
+)
+
+// Syscall is a single time a goroutine blocked in a syscall.
+type Syscall struct {
+	Goroutine *Goroutine
+	// The index of the span in Goroutine.Spans.
+	Span    int
+	Outcome SyscallOutcome
+}
+
+// SyscallSite aggregates the syscalls made by the same function from the same place.
+type SyscallSite struct {
+	// The function that made the syscall, which is the innermost frame of the stack, such as syscall.read. It is
+	// empty if the trace didn't record stacks.
+	Syscall string
+	// The PC of the frame the syscalls are attributed to, or 0 if the trace didn't record stacks. See
+	// ComputeSyscalls.
+	PC    uint64
+	Calls []Syscall
+	// The durations of the syscalls, in ascending order.
+	Durations []time.Duration
+	Total     time.Duration
+	// The number of syscalls per outcome.
+	Outcomes [3]int
+}
+
+// Syscalls describes the time goroutines and threads spent blocked in syscalls.
+type Syscalls struct {
+	// The sites, sorted by their total time, in descending order.
+	Sites    []*SyscallSite
+	Total    time.Duration
+	Outcomes [3]int
+	// The number of threads blocked in syscalls over time.
+	Threads Metric
+}
+
+// syscallLayers are the prefixes of functions that wrap syscalls, and which we skip when looking for the frame to
+// attribute a syscall to.
+var syscallLayers = []string{
+	"syscall.",
+	"internal/syscall/",
+	"golang.org/x/sys/",
+	"internal/poll.",
+	"internal/runtime/",
+	"runtime.",
+}
+
+func isSyscallLayer(fn string) bool {
+	for _, prefix := range syscallLayers {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeSyscalls groups the spans of goroutines blocked in syscalls by the function that made the syscall and the
+// place it was called from. A span is attributed to the frame at its At offset, if a pattern set one, or else to the
+// first frame outside of the syscall, internal/poll, and runtime packages.
+func ComputeSyscalls(tr *Trace) *Syscalls {
+	type siteKey struct {
+		syscall string
+		pc      uint64
+	}
+
+	var out Syscalls
+	sites := map[siteKey]*SyscallSite{}
+	for _, g := range tr.Goroutines {
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			if s.State != StateBlockedSyscall {
+				continue
+			}
+
+			var k siteKey
+			if s.StartEvent != NoEvent {
+				if pcs := tr.Stacks[tr.Event(s.StartEvent).Stack()]; len(pcs) > 0 {
+					k.syscall = tr.PCs[pcs[0]].Func
+					k.pc = pcs[0]
+					if s.At != 0 && int(s.At) < len(pcs) {
+						k.pc = pcs[s.At]
+					} else {
+						for _, pc := range pcs {
+							if !isSyscallLayer(tr.PCs[pc].Func) {
+								k.pc = pc
+								break
+							}
+						}
+					}
+				}
+			}
+			site, ok := sites[k]
+			if !ok {
+				site = &SyscallSite{Syscall: k.syscall, PC: k.pc}
+				sites[k] = site
+			}
+
+			c := Syscall{Goroutine: g, Span: i, Outcome: SyscallUnfinished}
+			if i+1 < len(g.Spans) {
+				if g.Spans[i+1].State == StateActive {
+					c.Outcome = SyscallRetainedP
+				} else {
+					c.Outcome = SyscallHandedOffP
+				}
+			}
+			site.Calls = append(site.Calls, c)
+			site.Durations = append(site.Durations, s.Duration())
+			site.Total += s.Duration()
+			site.Outcomes[c.Outcome]++
+			out.Total += s.Duration()
+			out.Outcomes[c.Outcome]++
+		}
+	}
+
+	for _, site := range sites {
+		slices.Sort(site.Durations)
+		out.Sites = append(out.Sites, site)
+	}
+	slices.SortFunc(out.Sites, func(a, b *SyscallSite) int {
+		if c := cmp.Compare(b.Total, a.Total); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Syscall, b.Syscall); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PC, b.PC)
+	})
+
+	var gauge spanGauge
+	for _, m := range tr.Machines {
+		for i := range m.Goroutines {
+			if s := &m.Goroutines[i]; s.State == StateBlockedSyscall {
+				gauge.add(s)
+			}
+		}
+	}
+	out.Threads = gauge.metric()
+	return &out
+}
@@ -0,0 +1,82 @@
+package ptrace
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SpanTags is a bitmap of tags that patterns attached to a span. The built-in tags occupy the lowest bits, the
+// remaining bits are assigned to tags registered with RegisterSpanTag.
+type SpanTags uint32
+
+const (
+	SpanTagNetwork SpanTags = 1 << iota
+	SpanTagTCP
+	SpanTagTLS
+	SpanTagRead
+	SpanTagAccept
+	SpanTagDial
+	SpanTagHTTP
+
+	// Used for spans of GC goroutines, used when choosing span colors for processor timelines.
+	SpanTagGC
+
+	spanTagFirstUser
+)
+
+// MaxSpanTags is the maximum number of span tags, including the built-in ones.
+const MaxSpanTags = 32
+
+type SpanTagInfo struct {
+	Tag SpanTags
+	// The name used to refer to the tag in patterns.
+	Name string
+	// The name used when displaying the tag. Tags without labels are only used internally and aren't displayed.
+	Label string
+}
+
+// spanTags contains all known tags, in the order in which they should be displayed.
+var spanTags = []SpanTagInfo{
+	{SpanTagRead, "read", "read"},
+	{SpanTagAccept, "accept", "accept"},
+	{SpanTagDial, "dial", "dial"},
+	{SpanTagNetwork, "network", "network"},
+	{SpanTagTCP, "tcp", "TCP"},
+	{SpanTagTLS, "tls", "TLS"},
+	{SpanTagHTTP, "http", "HTTP"},
+	{SpanTagGC, "gc", ""},
+}
+
+var nextSpanTag = spanTagFirstUser
+
+// RegisterSpanTag registers a new span tag that can be attached to spans by patterns. If a tag with the same name has
+// already been registered, that tag is returned instead. RegisterSpanTag must not be called concurrently with Parse or
+// other functions accessing span tags.
+func RegisterSpanTag(name, label string) (SpanTags, error) {
+	if tag, ok := LookupSpanTag(name); ok {
+		return tag, nil
+	}
+	if nextSpanTag == 0 {
+		return 0, fmt.Errorf("couldn't register span tag %q: at most %d span tags are supported", name, MaxSpanTags)
+	}
+	tag := nextSpanTag
+	nextSpanTag <<= 1
+	spanTags = append(spanTags, SpanTagInfo{Tag: tag, Name: name, Label: label})
+	return tag, nil
+}
+
+// LookupSpanTag returns the tag with the given name.
+func LookupSpanTag(name string) (SpanTags, bool) {
+	for _, info := range spanTags {
+		if info.Name == name {
+			return info.Tag, true
+		}
+	}
+	return 0, false
+}
+
+// SpanTagInfos returns all known span tags, built-in and registered ones, in the order in which they should be
+// displayed.
+func SpanTagInfos() []SpanTagInfo {
+	return slices.Clone(spanTags)
+}
@@ -0,0 +1,82 @@
+package ptrace
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Unblocking is a single time that a goroutine unblocked another goroutine.
+type Unblocking struct {
+	// The goroutine that was unblocked, and the index of the span in Goroutine.Spans during which it was blocked.
+	Goroutine *Goroutine
+	Span      int
+	By        *Goroutine
+}
+
+// UnblockEdge aggregates all the times that goroutines running one function unblocked goroutines running another
+// function.
+type UnblockEdge struct {
+	// The functions of the unblocking and the unblocked goroutines. Either may be nil for goroutines whose function
+	// isn't known.
+	From, To *Function
+	// How long the unblocked goroutines had been blocked for, in total.
+	Blocked     time.Duration
+	Unblockings []Unblocking
+}
+
+func (e *UnblockEdge) Count() int {
+	return len(e.Unblockings)
+}
+
+// ComputeUnblockGraph aggregates which goroutines unblocked which other goroutines by the functions of the
+// goroutines. The edges are sorted by the time goroutines spent blocked, in descending order.
+func ComputeUnblockGraph(tr *Trace) []*UnblockEdge {
+	type key struct {
+		from, to *Function
+	}
+	edges := map[key]*UnblockEdge{}
+	for _, g := range tr.Goroutines {
+		for i := range g.Spans {
+			s := &g.Spans[i]
+			gid, ok := UnblockedBy(tr, s)
+			if !ok || gid == g.ID {
+				continue
+			}
+			by, ok := tr.gsByID[gid]
+			if !ok {
+				continue
+			}
+			k := key{by.Function, g.Function}
+			e, ok := edges[k]
+			if !ok {
+				e = &UnblockEdge{From: by.Function, To: g.Function}
+				edges[k] = e
+			}
+			e.Blocked += s.Duration()
+			e.Unblockings = append(e.Unblockings, Unblocking{Goroutine: g, Span: i, By: by})
+		}
+	}
+
+	out := make([]*UnblockEdge, 0, len(edges))
+	for _, e := range edges {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b *UnblockEdge) int {
+		if c := cmp.Compare(b.Blocked, a.Blocked); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Count(), a.Count()); c != 0 {
+			return c
+		}
+		return cmp.Compare(functionName(a.From)+"\x00"+functionName(a.To), functionName(b.From)+"\x00"+functionName(b.To))
+	})
+	return out
+}
+
+func functionName(fn *Function) string {
+	if fn == nil {
+		return ""
+	}
+	return fn.Func
+}
@@ -1,5 +1,15 @@
 package ptrace
 
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
 var legalStateTransitions = [256][StateLast]bool{
 	StateInactive: {
 		StateActive:         true,
@@ -24,6 +34,7 @@ var legalStateTransitions = [256][StateLast]bool{
 		StateBlockedNet:              true,
 		StateBlockedGC:               true,
 		StateBlockedSyscall:          true,
+		StateWaitingPreempted:        true,
 		StateStuck:                   true,
 		StateDone:                    true,
 		StateGCMarkAssist:            true,
@@ -76,7 +87,10 @@ var legalStateTransitions = [256][StateLast]bool{
 	StateBlockedGC:               {StateReady: true},
 	StateBlockedSyscall: {
 		StateReady: true,
+		// Goroutines returning from a syscall can continue running immediately if they can reacquire a processor
+		StateActive: true,
 	},
+	StateWaitingPreempted: {StateReady: true},
 
 	StateGCMarkAssist: {
 		// active -> ready occurs on preemption
@@ -92,3 +106,175 @@ var legalStateTransitions = [256][StateLast]bool{
 		StateActive: true, // back to the goroutine's previous state
 	},
 }
+
+// ViolationKind is the kind of inconsistency found by Validate.
+type ViolationKind uint8
+
+const (
+	// A goroutine transitioned between two states that can't follow each other.
+	ViolationStateTransition ViolationKind = iota + 1
+	// A span ends before it starts, or starts before the previous span on the same track ends.
+	ViolationSpanOrder
+	// A task's end doesn't match its beginning.
+	ViolationTaskPairing
+	// A goroutine's spans don't agree with its start or end.
+	ViolationGoroutineLifetime
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case ViolationStateTransition:
+		return "state transition"
+	case ViolationSpanOrder:
+		return "span order"
+	case ViolationTaskPairing:
+		return "task pairing"
+	case ViolationGoroutineLifetime:
+		return "goroutine lifetime"
+	default:
+		return fmt.Sprintf("ViolationKind(%d)", k)
+	}
+}
+
+// A Violation is an inconsistency in a processed trace. Traces written by the Go runtime shouldn't have any, so
+// violations usually mean that we misinterpreted the trace, for example because it was produced by a newer version of
+// Go.
+type Violation struct {
+	Kind ViolationKind
+	// The object containing the inconsistency, such as "goroutine 12" or "processor 3".
+	Object string
+	// The event at which the inconsistency occurred, or NoEvent.
+	Event EventID
+	Time  exptrace.Time
+	// A description of the inconsistency.
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Object, v.Kind, v.Message)
+}
+
+// stateName returns the name of a goroutine state, as used by the pattern language.
+func stateName(state SchedulingState) string {
+	for name, s := range patternStates {
+		if s == state {
+			return name
+		}
+	}
+	return fmt.Sprintf("state %d", state)
+}
+
+// Validate checks a processed trace for inconsistencies, such as illegal state transitions, overlapping spans, tasks
+// whose beginnings and ends don't match, and goroutines whose spans don't agree with their starts and ends. It returns
+// the violations sorted by time.
+func Validate(tr *Trace) []Violation {
+	var out []Violation
+	report := func(kind ViolationKind, obj string, ev EventID, t exptrace.Time, format string, args ...any) {
+		out = append(out, Violation{
+			Kind:    kind,
+			Object:  obj,
+			Event:   ev,
+			Time:    t,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	checkSpans := func(obj string, spans []Span) {
+		for i := range spans {
+			s := &spans[i]
+			if s.End < s.Start {
+				report(ViolationSpanOrder, obj, s.StartEvent, s.Start, "span ends %s before it starts", time.Duration(s.Start-s.End))
+			}
+			if i > 0 {
+				if prev := &spans[i-1]; s.Start < prev.End {
+					report(ViolationSpanOrder, obj, s.StartEvent, s.Start, "span starts %s before the previous span ends", time.Duration(prev.End-s.Start))
+				}
+			}
+		}
+	}
+	checkRanges := func(obj string, ranges map[string][]Span) {
+		for _, name := range slices.Sorted(maps.Keys(ranges)) {
+			checkSpans(fmt.Sprintf("%s, range %q", obj, name), ranges[name])
+		}
+	}
+
+	for _, g := range tr.Goroutines {
+		obj := fmt.Sprintf("goroutine %d", g.ID)
+		checkSpans(obj, g.Spans)
+		for i := 1; i < len(g.Spans); i++ {
+			prev, s := &g.Spans[i-1], &g.Spans[i]
+			if !legalStateTransitions[prev.State][s.State] {
+				report(ViolationStateTransition, obj, s.StartEvent, s.Start, "illegal transition from %s to %s", stateName(prev.State), stateName(s.State))
+			}
+		}
+		for depth, spans := range g.UserRegions {
+			checkSpans(fmt.Sprintf("%s, user regions at depth %d", obj, depth), spans)
+		}
+		checkRanges(obj, g.Ranges)
+
+		start, hasStart := g.Start.Get()
+		end, hasEnd := g.End.Get()
+		if hasStart && hasEnd && end < start {
+			report(ViolationGoroutineLifetime, obj, NoEvent, end, "goroutine ends %s before it starts", time.Duration(start-end))
+		}
+		if len(g.Spans) == 0 {
+			continue
+		}
+		first, last := &g.Spans[0], &g.Spans[len(g.Spans)-1]
+		if hasStart {
+			if first.Start != start {
+				report(ViolationGoroutineLifetime, obj, first.StartEvent, first.Start, "first span starts at %d, but goroutine starts at %d", first.Start, start)
+			}
+			if first.State != StateCreated {
+				report(ViolationGoroutineLifetime, obj, first.StartEvent, first.Start, "goroutine was created, but its first span is in state %s", stateName(first.State))
+			}
+		} else if first.State == StateCreated {
+			report(ViolationGoroutineLifetime, obj, first.StartEvent, first.Start, "goroutine was created, but has no start")
+		}
+		if hasEnd && last.End != end {
+			report(ViolationGoroutineLifetime, obj, last.EndEvent, last.End, "last span ends at %d, but goroutine ends at %d", last.End, end)
+		}
+	}
+
+	for _, p := range tr.Processors {
+		obj := fmt.Sprintf("processor %d", p.ID)
+		checkSpans(obj, p.Spans)
+		checkRanges(obj, p.Ranges)
+	}
+
+	for _, m := range tr.Machines {
+		obj := fmt.Sprintf("machine %d", m.ID)
+		checkSpans(obj, m.Spans)
+		checkSpans(obj+", goroutines", m.Goroutines)
+		checkRanges(obj, m.Ranges)
+	}
+
+	checkSpans("GC", tr.GC)
+	checkSpans("STW", tr.STW)
+	checkRanges("trace", tr.Ranges)
+
+	for _, t := range tr.Tasks {
+		obj := fmt.Sprintf("task %d", t.ID)
+		start, hasStart := t.Start.Get()
+		end, hasEnd := t.End.Get()
+		if !hasStart || !hasEnd {
+			// Tasks may begin before the trace starts and end after it ends.
+			continue
+		}
+		if end < start {
+			report(ViolationTaskPairing, obj, t.EndEvent, end, "task ends %s before it begins", time.Duration(start-end))
+		}
+		begin, finish := tr.Event(t.StartEvent).Task(), tr.Event(t.EndEvent).Task()
+		if begin.Type != finish.Type {
+			report(ViolationTaskPairing, obj, t.EndEvent, end, "task begins with type %q but ends with type %q", begin.Type, finish.Type)
+		}
+		if begin.Parent != finish.Parent {
+			report(ViolationTaskPairing, obj, t.EndEvent, end, "task begins with parent %d but ends with parent %d", begin.Parent, finish.Parent)
+		}
+	}
+
+	slices.SortStableFunc(out, func(a, b Violation) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return out
+}